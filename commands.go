@@ -0,0 +1,422 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+
+	"openvpn3-tui/internal/autoconnect"
+	"openvpn3-tui/internal/bundle"
+	"openvpn3-tui/internal/config"
+	"openvpn3-tui/internal/importer"
+	"openvpn3-tui/internal/killswitch"
+	"openvpn3-tui/internal/ovpn"
+)
+
+// runCommand runs a command-line subcommand and returns the exit code
+func runCommand(cfg *config.Config, name string, args []string) int {
+	switch name {
+	case "lint":
+		return runLint(cfg, args)
+	case "inline":
+		return runInline(cfg, args)
+	case "import":
+		return runImport(cfg, "import", args, "")
+	case "import-nm":
+		return runImport(cfg, "import-nm", args, importer.NMConnectionsDir)
+	case "export":
+		return runExport(cfg, args)
+	case "rules":
+		return runRules(cfg, args)
+	case "kill-switch":
+		return runKillSwitch(cfg, args)
+	case "help", "-h", "--help":
+		printUsage()
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", name)
+		printUsage()
+		return 2
+	}
+}
+
+// printUsage lists the available subcommands
+func printUsage() {
+	fmt.Fprintln(os.Stderr, `Usage: openvpn3-tui [--connect profile]... [command]
+
+Without a command the interactive interface is started. --connect connects
+profiles on start, like setting autoconnect on them.
+
+Commands:
+  lint [profile|file...]   Check profiles for errors and weak settings
+  inline <profile...>      Inline certificates and keys into a single file
+  export [-o file] [--keep-keys] <profile...>
+                           Export profiles into a bundle to share
+  import [--dry-run] [--copy] <dir|archive|bundle>
+                           Import profiles from a directory or archive
+  import-nm [--dry-run] [file|dir]
+                           Import OpenVPN connections from NetworkManager
+                           (default `+importer.NMConnectionsDir+`)
+  rules [--ssid name] [--gateway ip|mac] [--interface name] [--address ip]
+                           Show which auto-connect rule fires on the current
+                           network, or on one described by the flags
+  kill-switch [--device name] [--off] [profile...]
+                           Print the nftables rules of profiles' kill switches,
+                           or remove the rules with --off`)
+}
+
+// resolveProfilePaths maps profile names or file paths to config files.
+// Without arguments every saved profile is returned.
+func resolveProfilePaths(cfg *config.Config, args []string) []string {
+	var paths []string
+	if len(args) == 0 {
+		for _, p := range cfg.Profiles {
+			paths = append(paths, p.Path)
+		}
+		return paths
+	}
+	for _, arg := range args {
+		if i, ok := cfg.FindProfile(arg); ok {
+			paths = append(paths, cfg.Profiles[i].Path)
+			continue
+		}
+		paths = append(paths, arg)
+	}
+	return paths
+}
+
+// runLint prints lint findings as path:line: severity: message and fails
+// when any profile has errors
+func runLint(cfg *config.Config, args []string) int {
+	paths := resolveProfilePaths(cfg, args)
+	if len(paths) == 0 {
+		fmt.Fprintln(os.Stderr, "No profiles to lint")
+		return 0
+	}
+
+	failed := false
+	for _, path := range paths {
+		findings, err := ovpn.LintFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: error: %v\n", path, err)
+			failed = true
+			continue
+		}
+		for _, f := range findings {
+			if f.Line > 0 {
+				fmt.Printf("%s:%d: %s: %s\n", path, f.Line, f.Severity, f.Message)
+			} else {
+				fmt.Printf("%s: %s: %s\n", path, f.Severity, f.Message)
+			}
+		}
+		if errors, _ := ovpn.Count(findings); errors > 0 {
+			failed = true
+		}
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// runImport adds the profiles found in a directory, archive, bundle or
+// NetworkManager keyfile. With --dry-run only the report is printed.
+func runImport(cfg *config.Config, name string, args []string, defaultPath string) int {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "report what would be imported without writing anything")
+	copyFiles := fs.Bool("copy", false, "copy profiles from a directory into the managed profiles directory")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	path := defaultPath
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+	if path == "" {
+		fmt.Fprintf(os.Stderr, "Usage: openvpn3-tui %s [--dry-run] [--copy] <path>\n", name)
+		return 2
+	}
+
+	src, err := importer.Scan(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	src.MarkDuplicates(cfg)
+
+	destDir, err := config.ProfilesDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	imported, failed := 0, false
+	for _, c := range src.Candidates {
+		fmt.Printf("%s (%s)\n", c.Name, c.Path)
+		if c.Username != "" {
+			fmt.Printf("  username %s is asked for when connecting\n", c.Username)
+		}
+		for _, note := range c.Notes {
+			fmt.Printf("  note: %s\n", note)
+		}
+
+		switch {
+		case c.Duplicate != "":
+			fmt.Printf("  skipped: already imported as %s\n", c.Duplicate)
+			continue
+		case !c.Selected:
+			failed = true
+			continue
+		case *dryRun:
+			continue
+		}
+
+		profilePath, err := src.Install(c, destDir, *copyFiles)
+		if err == nil {
+			c.Name = cfg.UniqueName(c.Name)
+			err = cfg.AddProfileEntry(c.Profile(profilePath))
+		}
+		if err != nil {
+			fmt.Printf("  error: %v\n", err)
+			failed = true
+			continue
+		}
+		fmt.Printf("  imported as %s\n", profilePath)
+		imported++
+	}
+
+	if imported > 0 {
+		if err := cfg.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to save config: %v\n", err)
+			return 1
+		}
+	}
+	if *dryRun {
+		fmt.Println(strings.Repeat("-", 40))
+		fmt.Println("Dry run: nothing was written")
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// runInline makes profiles self-contained by inlining the files they reference.
+// The result is saved in the managed profiles directory and the profile is
+// updated to use it.
+func runInline(cfg *config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: openvpn3-tui inline <profile...>")
+		return 2
+	}
+
+	failed, changed := false, false
+	for _, name := range args {
+		index, ok := cfg.FindProfile(name)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "%s: no such profile\n", name)
+			failed = true
+			continue
+		}
+		profile := cfg.Profiles[index]
+		dst, err := config.ManagedProfilePath(profile.Name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+
+		inlined, err := ovpn.InlineFile(profile.Path, dst)
+		if err == nil {
+			err = cfg.UpdateProfile(index, profile.Name, dst)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			failed = true
+			continue
+		}
+		changed = true
+		fmt.Printf("%s: inlined %s into %s\n", name, strings.Join(inlined, ", "), dst)
+	}
+
+	if changed {
+		if err := cfg.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to save config: %v\n", err)
+			return 1
+		}
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// runExport writes profiles into a bundle that can be shared and imported
+// again. Private keys and credential files are stripped unless --keep-keys is given.
+func runExport(cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	output := fs.String("o", "openvpn3-tui-bundle.tar.gz", "bundle file to write")
+	keepKeys := fs.Bool("keep-keys", false, "include private keys and credential files")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: openvpn3-tui export [-o file] [--keep-keys] <profile...>")
+		return 2
+	}
+
+	var profiles []config.Profile
+	for _, name := range fs.Args() {
+		index, ok := cfg.FindProfile(name)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "%s: no such profile\n", name)
+			return 1
+		}
+		profiles = append(profiles, cfg.Profiles[index])
+	}
+
+	if err := bundle.ExportFile(*output, profiles, !*keepKeys); err != nil {
+		fmt.Fprintf(os.Stderr, "Export failed: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Exported %d profile(s) to %s\n", len(profiles), *output)
+	return 0
+}
+
+// runRules is a dry run of the auto-connect rules. Flags replace parts of the
+// current network so that rules for other places can be tried.
+func runRules(cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("rules", flag.ContinueOnError)
+	ssid := fs.String("ssid", "", "Wi-Fi network name")
+	gateway := fs.String("gateway", "", "default gateway IP or MAC address")
+	iface := fs.String("interface", "", "interface of the default route")
+	address := fs.String("address", "", "address of this machine")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if cfg.AutoConnect == nil || len(cfg.AutoConnect.Rules) == 0 {
+		fmt.Println("No auto-connect rules configured")
+		return 0
+	}
+
+	st, err := autoconnect.NewSource(cfg.AutoConnect).State()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if *ssid != "" {
+		st.SSID = *ssid
+	}
+	if *gateway != "" {
+		if _, err := netip.ParseAddr(*gateway); err == nil {
+			st.Gateway, st.GatewayMAC = *gateway, ""
+		} else {
+			st.GatewayMAC = *gateway
+		}
+	}
+	if *iface != "" {
+		st.Interface = *iface
+	}
+	if *address != "" {
+		addr, err := netip.ParseAddr(*address)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid address: %s\n", *address)
+			return 2
+		}
+		st.Addresses = []netip.Prefix{netip.PrefixFrom(addr, addr.BitLen())}
+	}
+
+	fmt.Printf("Network: %s\n", st)
+	if st.GatewayMAC != "" {
+		fmt.Printf("Gateway MAC: %s\n", st.GatewayMAC)
+	}
+	for _, p := range st.Addresses {
+		fmt.Printf("Address: %s\n", p)
+	}
+	fmt.Println()
+
+	fired := -1
+	if i, ok := autoconnect.Evaluate(cfg.AutoConnect.Rules, st); ok {
+		fired = i
+	}
+	for i, r := range autoconnect.Explain(cfg.AutoConnect.Rules, st) {
+		name := autoconnect.RuleName(r.Rule, i)
+		switch {
+		case i == fired:
+			fmt.Printf("=> rule %s: fires, would %s\n", name, autoconnect.Actions(r.Rule))
+		case r.Matched:
+			fmt.Printf("   rule %s: matches, but an earlier rule fires\n", name)
+		default:
+			fmt.Printf("   rule %s: %s\n", name, r.Reason)
+		}
+	}
+	if fired < 0 {
+		fmt.Println("No rule fires")
+	}
+
+	problems := autoconnect.Check(cfg)
+	if len(problems) > 0 {
+		fmt.Println()
+		for _, p := range problems {
+			fmt.Printf("warning: %s\n", p)
+		}
+		return 1
+	}
+	return 0
+}
+
+// runKillSwitch prints the ruleset the kill switches of profiles would
+// apply, by default of every profile with one. --off removes the rules, e.g.
+// after a session dropped while the TUI was not running.
+func runKillSwitch(cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("kill-switch", flag.ContinueOnError)
+	device := fs.String("device", "tun0", "tunnel device to allow")
+	off := fs.Bool("off", false, "remove the kill switch rules")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *off {
+		if err := killswitch.NewRunner(cfg.KillSwitchRunner).Apply(killswitch.Ruleset(nil)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Println("Kill switch rules removed")
+		return 0
+	}
+
+	var profiles []config.Profile
+	for _, name := range fs.Args() {
+		index, ok := cfg.FindProfile(name)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "No such profile: %s\n", name)
+			return 2
+		}
+		profiles = append(profiles, cfg.Profiles[index])
+	}
+	if fs.NArg() == 0 {
+		for _, p := range cfg.Profiles {
+			if p.KillSwitch != nil {
+				profiles = append(profiles, p)
+			}
+		}
+	}
+	if len(profiles) == 0 {
+		fmt.Println("No profiles with a kill switch")
+		return 0
+	}
+
+	var guards []killswitch.Guard
+	for _, p := range profiles {
+		g, err := killswitch.ForProfile(context.Background(), p, *device)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", p.Name, err)
+			return 1
+		}
+		guards = append(guards, g)
+	}
+	fmt.Print(killswitch.Ruleset(guards))
+	return 0
+}
@@ -0,0 +1,207 @@
+// Package autoconnect decides which profiles to connect or disconnect on the
+// network the machine is attached to.
+package autoconnect
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"path"
+	"strings"
+
+	"openvpn3-tui/internal/config"
+	"openvpn3-tui/internal/network"
+)
+
+// Result tells whether a rule matches and, if not, which condition failed
+type Result struct {
+	Rule    config.Rule
+	Matched bool
+	Reason  string
+}
+
+// Evaluate returns the index of the first rule matching st. Nothing matches
+// while offline.
+func Evaluate(rules []config.Rule, st network.State) (int, bool) {
+	if !st.Online() {
+		return -1, false
+	}
+	for i, r := range rules {
+		if reason := mismatch(r.When, st); reason == "" {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// Explain checks every rule against st, for dry runs
+func Explain(rules []config.Rule, st network.State) []Result {
+	results := make([]Result, len(rules))
+	for i, r := range rules {
+		results[i] = Result{Rule: r, Reason: "offline"}
+		if st.Online() {
+			results[i].Reason = mismatch(r.When, st)
+		}
+		results[i].Matched = results[i].Reason == ""
+	}
+	return results
+}
+
+// mismatch names the first condition of m that st fails, or "" when it matches
+func mismatch(m config.Match, st network.State) string {
+	conditions := []struct {
+		name     string
+		patterns []string
+		values   []string
+		match    func(pattern, value string) bool
+	}{
+		{"ssid", m.SSID, []string{st.SSID}, matchGlob},
+		{"gateway", m.Gateway, []string{st.Gateway, st.GatewayMAC}, matchGateway},
+		{"interface", m.Interface, []string{st.Interface}, matchGlob},
+		{"address", m.Address, prefixAddrs(st.Addresses), matchCIDR},
+	}
+	for _, c := range conditions {
+		if !matchAny(c.patterns, c.values, c.match) {
+			return fmt.Sprintf("%s does not match", c.name)
+		}
+	}
+	return ""
+}
+
+// matchAny applies include and "!" exclude patterns to values
+func matchAny(patterns, values []string, match func(pattern, value string) bool) bool {
+	included, hasInclude := false, false
+	for _, p := range patterns {
+		exclude := strings.HasPrefix(p, "!")
+		p = strings.TrimPrefix(p, "!")
+		hit := false
+		for _, v := range values {
+			if v != "" && match(p, v) {
+				hit = true
+				break
+			}
+		}
+		if exclude && hit {
+			return false
+		}
+		if !exclude {
+			hasInclude = true
+			included = included || hit
+		}
+	}
+	return included || !hasInclude
+}
+
+// matchGlob compares with * and ? wildcards
+func matchGlob(pattern, value string) bool {
+	ok, err := path.Match(pattern, value)
+	return err == nil && ok
+}
+
+// matchGateway compares a gateway IP or MAC address with an address, CIDR or MAC
+func matchGateway(pattern, value string) bool {
+	if mac, err := net.ParseMAC(pattern); err == nil {
+		return strings.EqualFold(mac.String(), value)
+	}
+	return matchCIDR(pattern, value)
+}
+
+// matchCIDR checks whether an address is pattern or lies within it
+func matchCIDR(pattern, value string) bool {
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return false
+	}
+	if prefix, err := netip.ParsePrefix(pattern); err == nil {
+		return prefix.Contains(addr)
+	}
+	want, err := netip.ParseAddr(pattern)
+	return err == nil && want == addr
+}
+
+// prefixAddrs returns the addresses of interface prefixes
+func prefixAddrs(prefixes []netip.Prefix) []string {
+	addrs := make([]string, len(prefixes))
+	for i, p := range prefixes {
+		addrs[i] = p.Addr().String()
+	}
+	return addrs
+}
+
+// Check reports rules that name unknown profiles or contain invalid patterns
+func Check(cfg *config.Config) []string {
+	if cfg.AutoConnect == nil {
+		return nil
+	}
+	var problems []string
+	for i, r := range cfg.AutoConnect.Rules {
+		name := RuleName(r, i)
+		if len(r.Connect) == 0 && len(r.Disconnect) == 0 {
+			problems = append(problems, fmt.Sprintf("rule %s: connects and disconnects nothing", name))
+		}
+		for _, profile := range append(append([]string{}, r.Connect...), r.Disconnect...) {
+			if _, ok := cfg.FindProfile(profile); !ok {
+				problems = append(problems, fmt.Sprintf("rule %s: unknown profile %q", name, profile))
+			}
+		}
+		for _, p := range append(append([]string{}, r.When.SSID...), r.When.Interface...) {
+			if _, err := path.Match(strings.TrimPrefix(p, "!"), ""); err != nil {
+				problems = append(problems, fmt.Sprintf("rule %s: invalid pattern %q", name, p))
+			}
+		}
+		for _, p := range r.When.Gateway {
+			if !validAddress(strings.TrimPrefix(p, "!"), true) {
+				problems = append(problems, fmt.Sprintf("rule %s: invalid gateway %q", name, p))
+			}
+		}
+		for _, p := range r.When.Address {
+			if !validAddress(strings.TrimPrefix(p, "!"), false) {
+				problems = append(problems, fmt.Sprintf("rule %s: invalid address %q", name, p))
+			}
+		}
+	}
+	return problems
+}
+
+// validAddress accepts an IP address or CIDR, and a MAC address if allowed
+func validAddress(s string, mac bool) bool {
+	if _, err := netip.ParseAddr(s); err == nil {
+		return true
+	}
+	if _, err := netip.ParsePrefix(s); err == nil {
+		return true
+	}
+	_, err := net.ParseMAC(s)
+	return mac && err == nil
+}
+
+// RuleName returns the name of the rule at index, numbering unnamed rules
+func RuleName(r config.Rule, index int) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("#%d", index+1)
+}
+
+// NewSource watches this machine's network, reading the SSID as configured
+func NewSource(ac *config.AutoConnect) *network.System {
+	if ac != nil && ac.SSIDCommand != "" {
+		return network.NewSystem(network.CommandSSID(ac.SSIDCommand))
+	}
+	return network.NewSystem(nil)
+}
+
+// Actions describes what a rule does, e.g. "disconnect Home; connect Work"
+func Actions(r config.Rule) string {
+	var parts []string
+	if len(r.Disconnect) > 0 {
+		parts = append(parts, "disconnect "+strings.Join(r.Disconnect, ", "))
+	}
+	if len(r.Connect) > 0 {
+		parts = append(parts, "connect "+strings.Join(r.Connect, ", "))
+	}
+	if len(parts) == 0 {
+		return "nothing"
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,151 @@
+package autoconnect
+
+import (
+	"net/netip"
+	"testing"
+
+	"openvpn3-tui/internal/config"
+	"openvpn3-tui/internal/network"
+)
+
+// Networks the rules are evaluated against
+var (
+	home = network.State{
+		Interface:  "wlan0",
+		Gateway:    "192.168.1.1",
+		GatewayMAC: "a4:2b:b0:11:22:33",
+		SSID:       "HomeNet",
+		Addresses:  []netip.Prefix{netip.MustParsePrefix("192.168.1.23/24")},
+	}
+	guest = network.State{
+		Interface: "wlan0",
+		Gateway:   "10.20.0.1",
+		SSID:      "Acme-Guest",
+		Addresses: []netip.Prefix{netip.MustParsePrefix("10.20.3.4/16")},
+	}
+	office = network.State{
+		Interface: "enp0s31f6",
+		Gateway:   "10.10.0.1",
+		Addresses: []netip.Prefix{netip.MustParsePrefix("10.10.4.2/16")},
+	}
+	cafe = network.State{
+		Interface: "wlan0",
+		Gateway:   "172.16.0.1",
+		SSID:      "Free WiFi",
+	}
+	offline = network.State{Interface: "wlan0"}
+)
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name  string
+		match config.Match
+		state network.State
+		want  bool
+	}{
+		{"empty match", config.Match{}, cafe, true},
+		{"ssid", config.Match{SSID: []string{"HomeNet"}}, home, true},
+		{"ssid glob", config.Match{SSID: []string{"Acme-*"}}, guest, true},
+		{"ssid mismatch", config.Match{SSID: []string{"HomeNet"}}, cafe, false},
+		{"ssid without wifi", config.Match{SSID: []string{"*"}}, office, false},
+		{"excluded ssid", config.Match{SSID: []string{"Acme-*", "!Acme-Guest"}}, guest, false},
+		{"only excludes", config.Match{SSID: []string{"!HomeNet", "!Acme-*"}}, cafe, true},
+		{"only excludes hit", config.Match{SSID: []string{"!HomeNet", "!Acme-*"}}, home, false},
+		{"gateway ip", config.Match{Gateway: []string{"192.168.1.1"}}, home, true},
+		{"gateway cidr", config.Match{Gateway: []string{"10.10.0.0/16"}}, office, true},
+		{"gateway cidr mismatch", config.Match{Gateway: []string{"10.10.0.0/16"}}, guest, false},
+		{"gateway mac", config.Match{Gateway: []string{"A4:2B:B0:11:22:33"}}, home, true},
+		{"gateway mac unknown", config.Match{Gateway: []string{"a4:2b:b0:11:22:33"}}, cafe, false},
+		{"excluded gateway mac", config.Match{Gateway: []string{"!a4:2b:b0:11:22:33"}}, home, false},
+		{"interface", config.Match{Interface: []string{"enp*"}}, office, true},
+		{"address", config.Match{Address: []string{"10.10.0.0/16"}}, office, true},
+		{"excluded address", config.Match{Interface: []string{"wlan*"}, Address: []string{"!10.0.0.0/8"}}, guest, false},
+		{"all conditions", config.Match{SSID: []string{"HomeNet"}, Gateway: []string{"192.168.1.0/24"}, Interface: []string{"wlan0"}}, home, true},
+		{"one condition fails", config.Match{SSID: []string{"HomeNet"}, Interface: []string{"eth*"}}, home, false},
+		{"offline", config.Match{}, offline, false},
+	}
+	for _, tt := range tests {
+		_, got := Evaluate([]config.Rule{{When: tt.match}}, tt.state)
+		if got != tt.want {
+			t.Errorf("%s: Evaluate() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluateOrder(t *testing.T) {
+	rules := []config.Rule{
+		{Name: "home", When: config.Match{SSID: []string{"HomeNet"}}},
+		{Name: "acme", When: config.Match{Address: []string{"10.0.0.0/8"}}},
+		{Name: "office", When: config.Match{Interface: []string{"enp*"}}},
+		{Name: "untrusted"},
+	}
+	tests := []struct {
+		state network.State
+		want  int
+	}{
+		{home, 0},
+		{guest, 1},
+		{office, 1}, // acme comes before office
+		{cafe, 3},
+	}
+	for _, tt := range tests {
+		index, ok := Evaluate(rules, tt.state)
+		if !ok || index != tt.want {
+			t.Errorf("Evaluate() on %s = %d, %v, want rule %d", tt.state, index, ok, tt.want)
+		}
+	}
+	if index, ok := Evaluate(rules, offline); ok {
+		t.Errorf("Evaluate() offline = rule %d, want none", index)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	rules := []config.Rule{
+		{When: config.Match{SSID: []string{"HomeNet"}}},
+		{When: config.Match{SSID: []string{"HomeNet"}, Gateway: []string{"10.0.0.0/8"}}},
+		{},
+	}
+	results := Explain(rules, home)
+	want := []string{"", "gateway does not match", ""}
+	for i, r := range results {
+		if r.Reason != want[i] || r.Matched != (want[i] == "") {
+			t.Errorf("rule %d: matched %v with reason %q, want %q", i, r.Matched, r.Reason, want[i])
+		}
+	}
+	for i, r := range Explain(rules, offline) {
+		if r.Matched || r.Reason != "offline" {
+			t.Errorf("rule %d offline: matched %v with reason %q", i, r.Matched, r.Reason)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	cfg := &config.Config{
+		Profiles: []config.Profile{{Name: "Work"}},
+		AutoConnect: &config.AutoConnect{Rules: []config.Rule{
+			{Name: "ok", When: config.Match{Gateway: []string{"!a4:2b:b0:11:22:33"}}, Connect: []string{"Work"}},
+			{Name: "bad", When: config.Match{
+				SSID:    []string{"[Home"},
+				Gateway: []string{"router"},
+				Address: []string{"a4:2b:b0:11:22:33"},
+			}, Connect: []string{"Missing"}},
+			{When: config.Match{SSID: []string{"HomeNet"}}},
+		}},
+	}
+	want := []string{
+		`rule bad: unknown profile "Missing"`,
+		`rule bad: invalid pattern "[Home"`,
+		`rule bad: invalid gateway "router"`,
+		`rule bad: invalid address "a4:2b:b0:11:22:33"`,
+		"rule #3: connects and disconnects nothing",
+	}
+	got := Check(cfg)
+	if len(got) != len(want) {
+		t.Fatalf("Check() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Check()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
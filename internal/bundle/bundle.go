@@ -0,0 +1,192 @@
+// Package bundle exports profiles into a portable .tar.gz that can be shared
+// with teammates and imported again.
+//
+// A bundle holds a manifest with the config.json entries of the exported
+// profiles and one self-contained .ovpn file per profile.
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"openvpn3-tui/internal/config"
+	"openvpn3-tui/internal/ovpn"
+)
+
+// ManifestName is the path of the manifest inside a bundle
+const ManifestName = "openvpn3-tui-bundle.json"
+
+// Version is the bundle format version written by Export
+const Version = 1
+
+// Manifest describes the contents of a bundle
+type Manifest struct {
+	Version  int       `json:"version"`
+	Created  time.Time `json:"created"`
+	Redacted bool      `json:"redacted"`
+	// Profiles are config.json entries whose Path is the .ovpn file inside the bundle
+	Profiles []config.Profile `json:"profiles"`
+}
+
+// secretBlocks hold private keys that are removed when redacting, either
+// inline or as a file directive
+var secretBlocks = map[string]bool{
+	"key":          true,
+	"pkcs12":       true,
+	"tls-crypt-v2": true,
+	"secret":       true, // Static key of point-to-point tunnels
+}
+
+// credentialDirectives point at files with usernames and passwords
+var credentialDirectives = map[string]bool{
+	"auth-user-pass":       true,
+	"http-proxy-user-pass": true,
+	"askpass":              true,
+}
+
+// ExportFile writes a bundle of profiles to path
+func ExportFile(path string, profiles []config.Profile, redact bool) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if err := Export(f, profiles, redact); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// Export writes a bundle of profiles to w. Referenced files are inlined so
+// every profile is self-contained. With redact, private keys and credential
+// files are stripped.
+func Export(w io.Writer, profiles []config.Profile, redact bool) error {
+	if len(profiles) == 0 {
+		return errors.New("no profiles to export")
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	manifest := Manifest{Version: Version, Created: time.Now().UTC(), Redacted: redact}
+	used := make(map[string]bool)
+
+	for _, p := range profiles {
+		cfg, err := ovpn.ParseFile(p.Path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p.Name, err)
+		}
+		if _, err := cfg.Inline(); err != nil && !errors.Is(err, ovpn.ErrNothingToInline) {
+			return fmt.Errorf("%s: %w", p.Name, err)
+		}
+		if redact {
+			Redact(cfg)
+		}
+
+		name := config.SafeFileName(p.Name)
+		file := path.Join("profiles", name+".ovpn")
+		for n := 2; used[file]; n++ {
+			file = path.Join("profiles", fmt.Sprintf("%s-%d.ovpn", name, n))
+		}
+		used[file] = true
+
+		if err := writeEntry(tw, file, cfg.Bytes()); err != nil {
+			return err
+		}
+		// Hooks run local commands and are never shared
+		entry := p
+		entry.Path = file
+		entry.Hooks = nil
+		manifest.Profiles = append(manifest.Profiles, entry)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeEntry(tw, ManifestName, data); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// writeEntry adds a private file to the archive
+func writeEntry(tw *tar.Writer, name string, data []byte) error {
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0600,
+		Size:     int64(len(data)),
+		ModTime:  time.Now(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// Redact removes private keys, inline credentials and references to
+// credential files from a config and returns what was removed. Shared keys
+// such as tls-auth and tls-crypt are kept since every client of a server
+// needs the same one.
+func Redact(cfg *ovpn.Config) []string {
+	var removed []string
+	var nodes []ovpn.Node
+	for _, n := range cfg.Nodes {
+		switch {
+		case n.Block != nil && credentialDirectives[n.Block.Name]:
+			// Inline usernames and passwords, kept as a prompt like files
+			removed = append(removed, n.Block.Name)
+			nodes = append(nodes, ovpn.Node{Directive: &ovpn.Directive{Name: n.Block.Name}})
+		case n.Block != nil && secretBlocks[n.Block.Name]:
+			removed = append(removed, n.Block.Name)
+			nodes = append(nodes, ovpn.Node{Raw: fmt.Sprintf("# <%s> removed on export", n.Block.Name)})
+		case n.Directive != nil && secretBlocks[n.Directive.Name]:
+			removed = append(removed, n.Directive.Name)
+			nodes = append(nodes, ovpn.Node{Raw: fmt.Sprintf("# %s removed on export", n.Directive.Name)})
+		case n.Directive != nil && credentialDirectives[n.Directive.Name] && n.Directive.Arg(0) != "":
+			// Keep the directive so the credentials are still asked for
+			removed = append(removed, n.Directive.Name+" file")
+			nodes = append(nodes, ovpn.Node{Directive: &ovpn.Directive{Name: n.Directive.Name}})
+		default:
+			nodes = append(nodes, n)
+		}
+	}
+	cfg.Nodes = nodes
+	return removed
+}
+
+// ParseManifest reads and validates a bundle manifest. files holds the other
+// members of the bundle so that missing profile files are detected.
+func ParseManifest(data []byte, files map[string][]byte) (*Manifest, error) {
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid bundle manifest: %w", err)
+	}
+	if m.Version < 1 || m.Version > Version {
+		return nil, fmt.Errorf("unsupported bundle version %d", m.Version)
+	}
+	if len(m.Profiles) == 0 {
+		return nil, errors.New("bundle contains no profiles")
+	}
+	for _, p := range m.Profiles {
+		if p.Name == "" {
+			return nil, errors.New("bundle contains a profile without a name")
+		}
+		if _, ok := files[p.Path]; !ok {
+			return nil, fmt.Errorf("%s: %s is missing from the bundle", p.Name, p.Path)
+		}
+	}
+	return &m, nil
+}
@@ -0,0 +1,59 @@
+package bundle
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"openvpn3-tui/internal/ovpn"
+)
+
+func TestRedact(t *testing.T) {
+	in := `client
+remote vpn.example.com 1194
+<ca>
+CA
+</ca>
+<key>
+PRIVATE
+</key>
+<tls-auth>
+SHARED
+</tls-auth>
+secret static.key
+<secret>
+STATIC
+</secret>
+auth-user-pass creds.txt
+<auth-user-pass>
+alice
+hunter2
+</auth-user-pass>
+http-proxy proxy.example.com 3128
+<http-proxy-user-pass>
+bob
+swordfish
+</http-proxy-user-pass>
+`
+	cfg, err := ovpn.Parse(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed := Redact(cfg)
+
+	want := []string{"key", "secret", "secret", "auth-user-pass file", "auth-user-pass", "http-proxy-user-pass"}
+	if !slices.Equal(removed, want) {
+		t.Errorf("Redact() removed %q, want %q", removed, want)
+	}
+	out := string(cfg.Bytes())
+	for _, secret := range []string{"PRIVATE", "STATIC", "static.key", "creds.txt", "alice", "hunter2", "bob", "swordfish"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("redacted config still contains %q:\n%s", secret, out)
+		}
+	}
+	for _, kept := range []string{"CA", "SHARED", "\nauth-user-pass\n", "\nhttp-proxy-user-pass\n", "http-proxy proxy.example.com 3128"} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("redacted config lost %q:\n%s", kept, out)
+		}
+	}
+}
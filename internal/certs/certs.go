@@ -0,0 +1,228 @@
+// Package certs reads the X.509 certificates a profile carries, inline or in
+// referenced files, so that ones about to expire can be flagged. PKCS#12
+// bundles are read with the openssl command.
+package certs
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"openvpn3-tui/internal/ovpn"
+)
+
+// certDirectives are the directives whose file or inline block holds PEM certificates
+var certDirectives = []string{"ca", "cert", "extra-certs"}
+
+// Certificate is an X.509 certificate found in a profile
+type Certificate struct {
+	Source    string // Where it was found, e.g. "cert (inline)" or "ca /etc/vpn/ca.crt"
+	Subject   string
+	Issuer    string
+	SANs      []string
+	NotBefore time.Time
+	NotAfter  time.Time
+	IsCA      bool
+}
+
+// Expired reports whether the certificate is no longer valid at now
+func (c Certificate) Expired(now time.Time) bool {
+	return now.After(c.NotAfter)
+}
+
+// ExpiresWithin reports whether the certificate expires within window of now
+func (c Certificate) ExpiresWithin(window time.Duration, now time.Time) bool {
+	return c.NotAfter.Before(now.Add(window))
+}
+
+// DaysLeft returns the number of whole days until the certificate expires
+func (c Certificate) DaysLeft(now time.Time) int {
+	return int(c.NotAfter.Sub(now).Hours() / 24)
+}
+
+// LoadFile parses the config at path and returns its certificates
+func LoadFile(path string) ([]Certificate, error) {
+	cfg, err := ovpn.ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Load(cfg)
+}
+
+// Load returns the certificates of a config, read from inline blocks, referenced
+// files and PKCS#12 bundles. Certificates that can be read are returned even
+// when others fail; the first failure is returned as the error.
+func Load(cfg *ovpn.Config) ([]Certificate, error) {
+	var certs []Certificate
+	var errs []error
+	collect := func(found []Certificate, err error) {
+		certs = append(certs, found...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, name := range []string{"ca", "cert", "extra-certs", "pkcs12"} {
+		if b, ok := cfg.Block(name); ok {
+			source := name + " (inline)"
+			if name == "pkcs12" {
+				collect(parseInlinePKCS12(b.Content, source))
+			} else {
+				collect(ParsePEM([]byte(b.Content), source))
+			}
+		}
+	}
+
+	for _, ref := range cfg.ExternalFiles() {
+		name := ref.Directive.Name
+		if name != "pkcs12" && !isCertDirective(name) {
+			continue
+		}
+		data, err := os.ReadFile(ref.Path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		source := name + " " + ref.Path
+		if name == "pkcs12" {
+			collect(ParsePKCS12(data, source))
+		} else {
+			collect(ParsePEM(data, source))
+		}
+	}
+
+	if len(errs) > 0 {
+		return certs, errs[0]
+	}
+	return certs, nil
+}
+
+// isCertDirective reports whether a directive refers to PEM certificates
+func isCertDirective(name string) bool {
+	for _, d := range certDirectives {
+		if d == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePEM parses every CERTIFICATE block in data
+func ParsePEM(data []byte, source string) ([]Certificate, error) {
+	var certs []Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return certs, fmt.Errorf("%s: %w", source, err)
+		}
+		certs = append(certs, newCertificate(c, source))
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%s: no certificates found", source)
+	}
+	return certs, nil
+}
+
+// parseInlinePKCS12 decodes a base64 encoded inline <pkcs12> block
+func parseInlinePKCS12(content, source string) ([]Certificate, error) {
+	der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(content), ""))
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid base64: %w", source, err)
+	}
+	return ParsePKCS12(der, source)
+}
+
+// ParsePKCS12 extracts the certificates of a PKCS#12 bundle. The standard
+// library cannot read PKCS#12, so this uses the openssl CLI and only works for
+// bundles without a password.
+func ParsePKCS12(der []byte, source string) ([]Certificate, error) {
+	pemData, err := runOpenSSL(der)
+	if err != nil {
+		// OpenSSL 3 needs the legacy provider for bundles using RC2 or 3DES
+		var legacyErr error
+		if pemData, legacyErr = runOpenSSL(der, "-legacy"); legacyErr != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+	}
+	return ParsePEM(pemData, source)
+}
+
+// runOpenSSL converts a PKCS#12 bundle read from stdin to PEM certificates
+func runOpenSSL(der []byte, extra ...string) ([]byte, error) {
+	args := append([]string{"pkcs12", "-nokeys", "-passin", "pass:"}, extra...)
+	cmd := exec.Command("openssl", args...)
+	cmd.Stdin = bytes.NewReader(der)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("openssl is required to read PKCS#12 files")
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("openssl: %s", firstLine(msg))
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// firstLine returns the first line of s
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i != -1 {
+		return s[:i]
+	}
+	return s
+}
+
+// newCertificate summarizes a parsed certificate
+func newCertificate(c *x509.Certificate, source string) Certificate {
+	sans := append([]string{}, c.DNSNames...)
+	for _, ip := range c.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, c.EmailAddresses...)
+	for _, uri := range c.URIs {
+		sans = append(sans, uri.String())
+	}
+
+	return Certificate{
+		Source:    source,
+		Subject:   c.Subject.String(),
+		Issuer:    c.Issuer.String(),
+		SANs:      sans,
+		NotBefore: c.NotBefore,
+		NotAfter:  c.NotAfter,
+		IsCA:      c.IsCA,
+	}
+}
+
+// Soonest returns the certificate that expires first
+func Soonest(certs []Certificate) (Certificate, bool) {
+	if len(certs) == 0 {
+		return Certificate{}, false
+	}
+	soonest := certs[0]
+	for _, c := range certs[1:] {
+		if c.NotAfter.Before(soonest.NotAfter) {
+			soonest = c
+		}
+	}
+	return soonest, true
+}
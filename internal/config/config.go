@@ -2,19 +2,369 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 )
 
+// ErrEmptyName is returned when a profile name is blank
+var ErrEmptyName = errors.New("profile name cannot be empty")
+
+// ErrDuplicateName is returned when a profile name is already in use
+var ErrDuplicateName = errors.New("a profile with that name already exists")
+
+// ErrDependencyCycle is returned when profiles depend on each other
+var ErrDependencyCycle = errors.New("dependency cycle")
+
 // Profile represents a saved VPN configuration
 type Profile struct {
-	Name string `json:"name"`
-	Path string `json:"path"`
+	Name   string   `json:"name"`
+	Path   string   `json:"path"`
+	Folder string   `json:"folder,omitempty"` // Slash separated, e.g. "Acme/Prod"
+	Tags   []string `json:"tags,omitempty"`
+	Hooks  *Hooks   `json:"hooks,omitempty"`
+	// ConnectOnStart connects the profile whenever the TUI starts
+	ConnectOnStart bool `json:"autoconnect,omitempty"`
+	// Schedule connects the profile when one of its windows opens and
+	// disconnects it when the window closes
+	Schedule []Window `json:"schedule,omitempty"`
+	// IdleMinutes disconnects the session after this long without traffic
+	IdleMinutes int `json:"idle_minutes,omitempty"`
+	// DependsOn names profiles that must be connected first, e.g. a jump VPN
+	DependsOn []string `json:"depends_on,omitempty"`
+	// Health checks that traffic flows while the profile is connected
+	Health *Health `json:"health,omitempty"`
+	// Server is the remote picked last, as host:port/proto, used instead of
+	// trying the remotes in order
+	Server string `json:"server,omitempty"`
+	// KillSwitch blocks traffic outside the tunnel while the profile is connected
+	KillSwitch *KillSwitch `json:"kill_switch,omitempty"`
+}
+
+// Clone returns a copy of the profile that shares no slices or settings
+// with it, so either can be edited on its own
+func (p Profile) Clone() Profile {
+	p.Tags = slices.Clone(p.Tags)
+	p.DependsOn = slices.Clone(p.DependsOn)
+	if p.Schedule != nil {
+		windows := make([]Window, len(p.Schedule))
+		for i, w := range p.Schedule {
+			w.Days = slices.Clone(w.Days)
+			windows[i] = w
+		}
+		p.Schedule = windows
+	}
+	if p.Hooks != nil {
+		hooks := *p.Hooks
+		p.Hooks = &hooks
+	}
+	if p.Health != nil {
+		health := *p.Health
+		health.Checks = slices.Clone(health.Checks)
+		p.Health = &health
+	}
+	if p.KillSwitch != nil {
+		ks := KillSwitch{Allow: slices.Clone(p.KillSwitch.Allow)}
+		p.KillSwitch = &ks
+	}
+	return p
+}
+
+// KillSwitch lets only the tunnel, its servers and the listed networks
+// through while a profile is connected
+type KillSwitch struct {
+	// Allow lists LAN exceptions as CIDR prefixes, e.g. "192.168.1.0/24"
+	Allow []string `json:"allow,omitempty"`
+}
+
+// Window is a weekly time window in local time, e.g. Saturdays 02:00 to 04:00
+type Window struct {
+	Days  []string `json:"days,omitempty"` // mon to sun, every day when empty
+	Start string   `json:"start"`          // HH:MM
+	End   string   `json:"end"`            // HH:MM, before Start to end on the next day
+}
+
+// defaultHookTimeout is how long a hook may run unless configured otherwise
+const defaultHookTimeout = 30 * time.Second
+
+// Hooks are shell commands run around connecting and disconnecting a profile
+type Hooks struct {
+	PreConnect     string `json:"pre_connect,omitempty"`
+	PostConnect    string `json:"post_connect,omitempty"`
+	PreDisconnect  string `json:"pre_disconnect,omitempty"`
+	PostDisconnect string `json:"post_disconnect,omitempty"`
+	HealthFailed   string `json:"health_failed,omitempty"`
+	// Timeout in seconds after which a hook is killed
+	Timeout int `json:"timeout,omitempty"`
+	// AbortOnFailure cancels the connection when the pre-connect hook fails
+	AbortOnFailure bool `json:"abort_on_failure,omitempty"`
+}
+
+// TimeoutDuration returns how long a hook may run
+func (h *Hooks) TimeoutDuration() time.Duration {
+	if h == nil || h.Timeout <= 0 {
+		return defaultHookTimeout
+	}
+	return time.Duration(h.Timeout) * time.Second
+}
+
+// defaultHealthInterval and defaultHealthTimeout apply unless configured otherwise
+const (
+	defaultHealthInterval = 30 * time.Second
+	defaultHealthTimeout  = 5 * time.Second
+)
+
+// Health lists the checks run periodically while a profile is connected
+type Health struct {
+	// Interval in seconds between rounds of checks
+	Interval int `json:"interval,omitempty"`
+	// Timeout in seconds after which a check fails
+	Timeout int           `json:"timeout,omitempty"`
+	Checks  []HealthCheck `json:"checks"`
+}
+
+// HealthCheck probes one service behind the VPN. Exactly one of TCP, HTTP
+// and DNS is set.
+type HealthCheck struct {
+	Name   string `json:"name,omitempty"`
+	TCP    string `json:"tcp,omitempty"`    // host:port to connect to
+	HTTP   string `json:"http,omitempty"`   // URL to GET
+	Status int    `json:"status,omitempty"` // Expected HTTP status, 200 when unset
+	DNS    string `json:"dns,omitempty"`    // Name to look up
+	Server string `json:"server,omitempty"` // DNS server, the system resolver when unset
+}
+
+// IntervalDuration returns how long to wait between rounds of checks
+func (h *Health) IntervalDuration() time.Duration {
+	if h == nil || h.Interval <= 0 {
+		return defaultHealthInterval
+	}
+	return time.Duration(h.Interval) * time.Second
+}
+
+// TimeoutDuration returns how long a check may take
+func (h *Health) TimeoutDuration() time.Duration {
+	if h == nil || h.Timeout <= 0 {
+		return defaultHealthTimeout
+	}
+	return time.Duration(h.Timeout) * time.Second
+}
+
+// HasTag reports whether the profile carries tag, ignoring case
+func (p Profile) HasTag(tag string) bool {
+	for _, t := range p.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// InFolder reports whether the profile is in folder or one of its subfolders
+func (p Profile) InFolder(folder string) bool {
+	if folder == "" {
+		return true
+	}
+	return p.Folder == folder || strings.HasPrefix(p.Folder, folder+"/")
+}
+
+// NormalizeFolder cleans up a slash separated folder path
+func NormalizeFolder(folder string) string {
+	var parts []string
+	for _, part := range strings.Split(folder, "/") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// ParseTags splits a comma or space separated list of tags, dropping duplicates
+func ParseTags(s string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		tag = strings.TrimPrefix(tag, "#")
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		tags = append(tags, tag)
+	}
+	return tags
 }
 
+// defaultCertWarningDays is how long before expiry certificates are flagged
+const defaultCertWarningDays = 30
+
 // Config holds the application configuration
 type Config struct {
 	Profiles []Profile `json:"profiles"`
+	Keymap   Keymap    `json:"keymap"`
+	// CertWarningDays flags certificates expiring within this many days
+	CertWarningDays int `json:"cert_warning_days,omitempty"`
+	// CredentialStore selects where passwords are kept: "secret-service",
+	// "file" or empty to use the Secret Service when it is available
+	CredentialStore string `json:"credential_store,omitempty"`
+	// AutoConnect connects and disconnects profiles as the network changes
+	AutoConnect *AutoConnect `json:"auto_connect,omitempty"`
+	// OnQuit decides what happens to running sessions when the TUI exits
+	OnQuit string `json:"on_quit,omitempty"`
+	// QuitTimeout bounds in seconds how long disconnecting on exit may take
+	QuitTimeout int `json:"quit_timeout,omitempty"`
+	// Exclusive lists sets of profiles of which only one may be connected
+	Exclusive []ExclusiveGroup `json:"exclusive,omitempty"`
+	// KillSwitchRunner is the command that runs nft as root, "pkexec" when
+	// empty, e.g. "sudo -n" or "none" when already running as root
+	KillSwitchRunner string `json:"kill_switch_runner,omitempty"`
+}
+
+// ExclusiveGroup is a set of profiles of which only one may be connected at a
+// time, e.g. regional gateways with conflicting routes
+type ExclusiveGroup struct {
+	Name     string   `json:"name"`
+	Profiles []string `json:"profiles"`
+}
+
+// DependencyOrder returns the profiles the named profile depends on, directly
+// or through other dependencies, in the order they must be connected
+func (c *Config) DependencyOrder(name string) ([]Profile, error) {
+	var order []Profile
+	done := make(map[string]bool)
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		if slices.Contains(path, name) {
+			cycle := append(path[slices.Index(path, name):], name)
+			return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(cycle, " -> "))
+		}
+		if done[name] {
+			return nil
+		}
+		index, ok := c.FindProfile(name)
+		if !ok && len(path) == 0 {
+			return fmt.Errorf("unknown profile %q", name)
+		} else if !ok {
+			return fmt.Errorf("%s depends on unknown profile %q", path[len(path)-1], name)
+		}
+		path = append(path, name)
+		for _, dep := range c.Profiles[index].DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		done[name] = true
+		order = append(order, c.Profiles[index])
+		return nil
+	}
+
+	if err := visit(name); err != nil {
+		return nil, err
+	}
+	// The profile itself comes last
+	return order[:len(order)-1], nil
+}
+
+// ExclusiveGroups returns the exclusive groups the named profile belongs to
+func (c *Config) ExclusiveGroups(name string) []ExclusiveGroup {
+	var groups []ExclusiveGroup
+	for _, g := range c.Exclusive {
+		for _, p := range g.Profiles {
+			if p == name {
+				groups = append(groups, g)
+				break
+			}
+		}
+	}
+	return groups
+}
+
+// ExclusiveSiblings returns the profiles that may not be connected together
+// with the named profile
+func (c *Config) ExclusiveSiblings(name string) []Profile {
+	var siblings []Profile
+	seen := map[string]bool{name: true}
+	for _, g := range c.ExclusiveGroups(name) {
+		for _, p := range g.Profiles {
+			if index, ok := c.FindProfile(p); ok && !seen[p] {
+				seen[p] = true
+				siblings = append(siblings, c.Profiles[index])
+			}
+		}
+	}
+	return siblings
+}
+
+// Policies for sessions still running when the TUI exits
+const (
+	QuitLeave         = "leave"          // Keep every session running
+	QuitDisconnectOwn = "disconnect-own" // Disconnect sessions started by this instance
+	QuitDisconnectAll = "disconnect-all" // Disconnect every session
+)
+
+// defaultQuitTimeout is how long disconnecting on exit may take unless configured otherwise
+const defaultQuitTimeout = 15 * time.Second
+
+// QuitPolicy returns the configured on_quit policy, leaving sessions running by default
+func (c *Config) QuitPolicy() string {
+	if c.OnQuit == "" {
+		return QuitLeave
+	}
+	return c.OnQuit
+}
+
+// QuitTimeoutDuration returns how long disconnecting on exit may take
+func (c *Config) QuitTimeoutDuration() time.Duration {
+	if c.QuitTimeout <= 0 {
+		return defaultQuitTimeout
+	}
+	return time.Duration(c.QuitTimeout) * time.Second
+}
+
+// AutoConnect holds the rules applied whenever the machine joins a network
+type AutoConnect struct {
+	// DryRun only reports which rule would fire and what it would do
+	DryRun bool `json:"dry_run,omitempty"`
+	// SSIDCommand prints the connected Wi-Fi network instead of asking
+	// iwgetid or NetworkManager
+	SSIDCommand string `json:"ssid_command,omitempty"`
+	// Rules are tried in order and the first matching rule fires
+	Rules []Rule `json:"rules"`
+}
+
+// Rule connects and disconnects profiles on networks matching When
+type Rule struct {
+	Name       string   `json:"name"`
+	When       Match    `json:"when"`
+	Connect    []string `json:"connect,omitempty"` // Profile names
+	Disconnect []string `json:"disconnect,omitempty"`
+}
+
+// Match describes networks. A field matches when one of its patterns does,
+// and none of its patterns starting with "!" do. Empty fields match any
+// network.
+type Match struct {
+	SSID      []string `json:"ssid,omitempty"`      // Wi-Fi name, * matches any text
+	Gateway   []string `json:"gateway,omitempty"`   // IP, CIDR or MAC address of the default gateway
+	Interface []string `json:"interface,omitempty"` // Interface of the default route, * matches any text
+	Address   []string `json:"address,omitempty"`   // CIDR containing an address of this machine
+}
+
+// CertWarningWindow returns how long before expiry certificates are flagged
+func (c *Config) CertWarningWindow() time.Duration {
+	days := c.CertWarningDays
+	if days <= 0 {
+		days = defaultCertWarningDays
+	}
+	return time.Duration(days) * 24 * time.Hour
 }
 
 // configDir returns the config directory path
@@ -26,6 +376,58 @@ func configDir() (string, error) {
 	return filepath.Join(home, ".config", "openvpn3-tui"), nil
 }
 
+// ProfilesDir returns the directory imported and converted profiles are copied to
+func ProfilesDir() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "profiles"), nil
+}
+
+// CredentialsPath returns the encrypted credential file used when no Secret
+// Service is available
+func CredentialsPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "credentials.enc"), nil
+}
+
+// FootprintsPath returns the file remembering the routes and DNS settings of
+// each profile's last session
+func FootprintsPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "footprints.json"), nil
+}
+
+// ManagedProfilePath returns the path in ProfilesDir for a profile's config file
+func ManagedProfilePath(name string) (string, error) {
+	dir, err := ProfilesDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, SafeFileName(name)+".ovpn"), nil
+}
+
+// SafeFileName turns a profile name into a file name
+func SafeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == 0 {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" || name == "." || name == ".." {
+		name = "profile"
+	}
+	return name
+}
+
 // configPath returns the full path to the config file
 func configPath() (string, error) {
 	dir, err := configDir()
@@ -55,6 +457,21 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
+	// Reject keymaps with unknown actions or conflicting keys up front
+	if _, err := cfg.Keymap.Resolve(); err != nil {
+		return nil, fmt.Errorf("invalid keymap: %w", err)
+	}
+	for _, p := range cfg.Profiles {
+		if _, err := cfg.DependencyOrder(p.Name); errors.Is(err, ErrDependencyCycle) {
+			return nil, err
+		}
+	}
+	switch cfg.QuitPolicy() {
+	case QuitLeave, QuitDisconnectOwn, QuitDisconnectAll:
+	default:
+		return nil, fmt.Errorf("invalid on_quit %q, use %s, %s or %s", cfg.OnQuit, QuitLeave, QuitDisconnectOwn, QuitDisconnectAll)
+	}
+
 	return &cfg, nil
 }
 
@@ -83,8 +500,95 @@ func (c *Config) Save() error {
 }
 
 // AddProfile adds a new profile to the config
-func (c *Config) AddProfile(name, path string) {
-	c.Profiles = append(c.Profiles, Profile{Name: name, Path: path})
+func (c *Config) AddProfile(name, path string) error {
+	return c.AddProfileEntry(Profile{Name: name, Path: path})
+}
+
+// AddProfileEntry adds a profile with all its settings, e.g. from an import
+func (c *Config) AddProfileEntry(p Profile) error {
+	if err := c.validateName(p.Name, -1); err != nil {
+		return err
+	}
+	p.Folder = NormalizeFolder(p.Folder)
+	p.Tags = ParseTags(strings.Join(p.Tags, ","))
+	c.Profiles = append(c.Profiles, p)
+	return nil
+}
+
+// UpdateProfile changes the name and path of the profile at index
+func (c *Config) UpdateProfile(index int, name, path string) error {
+	if index < 0 || index >= len(c.Profiles) {
+		return fmt.Errorf("profile index %d out of range", index)
+	}
+	if err := c.validateName(name, index); err != nil {
+		return err
+	}
+	c.renameReferences(c.Profiles[index].Name, name)
+	c.Profiles[index].Name = name
+	c.Profiles[index].Path = path
+	return nil
+}
+
+// renameReferences updates settings that refer to a profile by name
+func (c *Config) renameReferences(from, to string) {
+	if from == to {
+		return
+	}
+	var lists [][]string
+	if c.AutoConnect != nil {
+		for _, rule := range c.AutoConnect.Rules {
+			lists = append(lists, rule.Connect, rule.Disconnect)
+		}
+	}
+	for _, g := range c.Exclusive {
+		lists = append(lists, g.Profiles)
+	}
+	for _, p := range c.Profiles {
+		lists = append(lists, p.DependsOn)
+	}
+	for _, names := range lists {
+		for j, name := range names {
+			if name == from {
+				names[j] = to
+			}
+		}
+	}
+}
+
+// SetFolder moves the profile at index into folder, or to the top level if empty
+func (c *Config) SetFolder(index int, folder string) error {
+	if index < 0 || index >= len(c.Profiles) {
+		return fmt.Errorf("profile index %d out of range", index)
+	}
+	c.Profiles[index].Folder = NormalizeFolder(folder)
+	return nil
+}
+
+// SetTags replaces the tags of the profile at index
+func (c *Config) SetTags(index int, tags []string) error {
+	if index < 0 || index >= len(c.Profiles) {
+		return fmt.Errorf("profile index %d out of range", index)
+	}
+	c.Profiles[index].Tags = ParseTags(strings.Join(tags, ","))
+	return nil
+}
+
+// Tags returns every tag in use, sorted case-insensitively
+func (c *Config) Tags() []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, p := range c.Profiles {
+		for _, t := range p.Tags {
+			if key := strings.ToLower(t); !seen[key] {
+				seen[key] = true
+				tags = append(tags, t)
+			}
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return strings.ToLower(tags[i]) < strings.ToLower(tags[j])
+	})
+	return tags
 }
 
 // RemoveProfile removes a profile by index
@@ -94,6 +598,72 @@ func (c *Config) RemoveProfile(index int) {
 	}
 }
 
+// MoveProfile moves the profile at index by delta positions and returns its new index
+func (c *Config) MoveProfile(index, delta int) int {
+	if index < 0 || index >= len(c.Profiles) {
+		return index
+	}
+	target := min(max(index+delta, 0), len(c.Profiles)-1)
+	profile := c.Profiles[index]
+	if target < index {
+		copy(c.Profiles[target+1:index+1], c.Profiles[target:index])
+	} else {
+		copy(c.Profiles[index:target], c.Profiles[index+1:target+1])
+	}
+	c.Profiles[target] = profile
+	return target
+}
+
+// DuplicateProfile inserts a copy of the profile at index right after it
+// and returns the index of the copy. The copy uses the same config file, and
+// sessions are matched to profiles by their file, so a session of one counts
+// as a session of both until the path of either is changed.
+func (c *Config) DuplicateProfile(index int) (int, error) {
+	if index < 0 || index >= len(c.Profiles) {
+		return index, fmt.Errorf("profile index %d out of range", index)
+	}
+
+	dup := c.Profiles[index].Clone()
+	dup.Name = c.UniqueName(dup.Name + " (copy)")
+
+	c.Profiles = append(c.Profiles, Profile{})
+	copy(c.Profiles[index+2:], c.Profiles[index+1:])
+	c.Profiles[index+1] = dup
+	return index + 1, nil
+}
+
+// FindProfile returns the index of the profile with the given name
+func (c *Config) FindProfile(name string) (int, bool) {
+	for i, p := range c.Profiles {
+		if p.Name == name {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// validateName checks that name is non-empty and not used by any profile except skip
+func (c *Config) validateName(name string, skip int) error {
+	if strings.TrimSpace(name) == "" {
+		return ErrEmptyName
+	}
+	if i, ok := c.FindProfile(name); ok && i != skip {
+		return ErrDuplicateName
+	}
+	return nil
+}
+
+// UniqueName returns name, or name with a counter appended if it is taken
+func (c *Config) UniqueName(name string) string {
+	candidate := name
+	for n := 2; ; n++ {
+		if _, ok := c.FindProfile(candidate); !ok {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s %d", name, n)
+	}
+}
+
 // ValidateProfiles checks if profile files exist and returns validity status
 func (c *Config) ValidateProfiles() map[int]bool {
 	valid := make(map[int]bool)
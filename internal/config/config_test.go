@@ -0,0 +1,91 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDuplicateProfile(t *testing.T) {
+	original := Profile{
+		Name:      "Acme",
+		Path:      "/vpn/acme.ovpn",
+		Tags:      []string{"prod"},
+		Hooks:     &Hooks{PreConnect: "mount-shares"},
+		Schedule:  []Window{{Days: []string{"sat"}, Start: "02:00", End: "04:00"}},
+		DependsOn: []string{"Jump"},
+		Health: &Health{Checks: []HealthCheck{
+			{TCP: "10.0.0.1:22"},
+		}},
+		KillSwitch: &KillSwitch{Allow: []string{"192.168.1.0/24"}},
+	}
+	c := &Config{Profiles: []Profile{original.Clone(), {Name: "Other", Path: "/vpn/other.ovpn"}}}
+
+	index, err := c.DuplicateProfile(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index != 1 || c.Profiles[1].Name != "Acme (copy)" || c.Profiles[2].Name != "Other" {
+		t.Fatalf("DuplicateProfile() = %d with profiles %+v", index, c.Profiles)
+	}
+	dup := &c.Profiles[1]
+	dup.Name = original.Name
+	if !reflect.DeepEqual(*dup, original) {
+		t.Errorf("copy = %+v, want %+v", *dup, original)
+	}
+
+	// Editing the copy leaves the original alone
+	dup.Tags[0] = "staging"
+	dup.Hooks.PreConnect = ""
+	dup.Schedule[0].Days[0] = "sun"
+	dup.DependsOn[0] = "Bastion"
+	dup.Health.Checks[0].TCP = "10.0.0.2:22"
+	dup.KillSwitch.Allow[0] = "10.0.0.0/8"
+	if got := c.Profiles[0]; !reflect.DeepEqual(got, original) {
+		t.Errorf("original changed with its copy: %+v", got)
+	}
+
+	if _, err := c.DuplicateProfile(5); err == nil {
+		t.Error("DuplicateProfile() of a missing index succeeded")
+	}
+}
+
+func TestCloneKeepsNil(t *testing.T) {
+	p := Profile{Name: "Plain", Path: "/vpn/plain.ovpn"}
+	if got := p.Clone(); !reflect.DeepEqual(got, p) {
+		t.Errorf("Clone() = %+v, want %+v", got, p)
+	}
+}
+
+func TestKeymapResolve(t *testing.T) {
+	for _, preset := range KeymapPresets() {
+		keys, err := Keymap{Preset: preset}.Resolve()
+		if err != nil {
+			t.Errorf("preset %s: %v", preset, err)
+			continue
+		}
+		for _, scope := range keyScopes {
+			for _, action := range scope {
+				if len(keys[action]) == 0 {
+					t.Errorf("preset %s binds no key to %s", preset, action)
+				}
+			}
+		}
+	}
+
+	tests := []struct {
+		name     string
+		bindings map[string][]string
+		wantErr  bool
+	}{
+		{"other scope", map[string][]string{ActionToggle: {"d"}}, false},
+		{"main scope", map[string][]string{ActionRefresh: {"a"}}, true},
+		{"picker scope", map[string][]string{ActionToggle: {"j"}}, true},
+		{"unknown action", map[string][]string{"launch": {"l"}}, true},
+	}
+	for _, tt := range tests {
+		_, err := Keymap{Bindings: tt.bindings}.Resolve()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Resolve() = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
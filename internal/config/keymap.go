@@ -0,0 +1,254 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Actions that can be bound to keys in the keymap section
+const (
+	ActionQuit            = "quit"
+	ActionSwitchView      = "switch_view"
+	ActionUp              = "up"
+	ActionDown            = "down"
+	ActionPageUp          = "page_up"
+	ActionPageDown        = "page_down"
+	ActionHome            = "home"
+	ActionEnd             = "end"
+	ActionSelect          = "select"
+	ActionServers         = "servers"
+	ActionAdd             = "add"
+	ActionImport          = "import"
+	ActionExport          = "export"
+	ActionEdit            = "edit"
+	ActionMoveUp          = "move_up"
+	ActionMoveDown        = "move_down"
+	ActionDuplicate       = "duplicate"
+	ActionInline          = "inline"
+	ActionFolder          = "folder"
+	ActionTags            = "tags"
+	ActionConnectGroup    = "connect_group"
+	ActionDisconnectGroup = "disconnect_group"
+	ActionDelete          = "delete"
+	ActionRefresh         = "refresh"
+	ActionStats           = "stats"
+	ActionCerts           = "certs"
+	ActionCredentials     = "credentials"
+	ActionForget          = "forget_credentials"
+	ActionTOTP            = "totp"
+	ActionHookLog         = "hook_log"
+	ActionHelp            = "help"
+	ActionFilter          = "filter"
+	ActionToggle          = "toggle"
+	ActionToggleAll       = "toggle_all"
+	ActionCopyFiles       = "copy_files"
+	ActionConfigOrder     = "config_order"
+	ActionClose           = "close"
+	ActionConfirm         = "confirm"
+	ActionCancel          = "cancel"
+)
+
+// keyScopes groups actions that are active at the same time.
+// Keys must be unique within a scope but may be reused across scopes.
+var keyScopes = [][]string{
+	{
+		ActionQuit, ActionSwitchView, ActionUp, ActionDown, ActionPageUp,
+		ActionPageDown, ActionHome, ActionEnd, ActionSelect, ActionServers, ActionAdd,
+		ActionImport, ActionExport, ActionEdit, ActionMoveUp, ActionMoveDown, ActionDuplicate,
+		ActionInline, ActionFolder, ActionTags, ActionConnectGroup, ActionDisconnectGroup,
+		ActionDelete, ActionRefresh, ActionStats, ActionCerts, ActionCredentials,
+		ActionForget, ActionTOTP, ActionHookLog, ActionHelp,
+		ActionFilter,
+	},
+	// The import preview and the server picker
+	{
+		ActionUp, ActionDown, ActionPageUp, ActionPageDown, ActionHome, ActionEnd,
+		ActionSelect, ActionRefresh, ActionToggle, ActionToggleAll, ActionCopyFiles,
+		ActionConfigOrder, ActionClose,
+	},
+	{ActionConfirm, ActionCancel},
+}
+
+// keymapPresets holds the built-in key layouts
+var keymapPresets = map[string]map[string][]string{
+	"default": {
+		ActionQuit:            {"q", "ctrl+c"},
+		ActionSwitchView:      {"tab"},
+		ActionUp:              {"up", "k"},
+		ActionDown:            {"down", "j"},
+		ActionPageUp:          {"pgup"},
+		ActionPageDown:        {"pgdown"},
+		ActionHome:            {"home", "g"},
+		ActionEnd:             {"end", "G"},
+		ActionSelect:          {"enter"},
+		ActionServers:         {"S"},
+		ActionAdd:             {"a"},
+		ActionImport:          {"I"},
+		ActionExport:          {"E"},
+		ActionEdit:            {"e"},
+		ActionMoveUp:          {"K", "shift+up"},
+		ActionMoveDown:        {"J", "shift+down"},
+		ActionDuplicate:       {"c"},
+		ActionInline:          {"L"},
+		ActionFolder:          {"m"},
+		ActionTags:            {"t"},
+		ActionConnectGroup:    {"C"},
+		ActionDisconnectGroup: {"X"},
+		ActionDelete:          {"d", "delete"},
+		ActionRefresh:         {"r"},
+		ActionStats:           {"s"},
+		ActionCerts:           {"i"},
+		ActionCredentials:     {"p"},
+		ActionForget:          {"P"},
+		ActionTOTP:            {"T"},
+		ActionHookLog:         {"H"},
+		ActionHelp:            {"?"},
+		ActionFilter:          {"/"},
+		ActionToggle:          {" "},
+		ActionToggleAll:       {"a"},
+		ActionCopyFiles:       {"c"},
+		ActionConfigOrder:     {"o"},
+		ActionClose:           {"esc"},
+		ActionConfirm:         {"y", "Y", "enter"},
+		ActionCancel:          {"n", "N", "esc"},
+	},
+	"vim": {
+		ActionQuit:            {"q", "ctrl+c"},
+		ActionSwitchView:      {"tab", "shift+tab"},
+		ActionUp:              {"k", "up"},
+		ActionDown:            {"j", "down"},
+		ActionPageUp:          {"ctrl+b", "pgup"},
+		ActionPageDown:        {"ctrl+f", "pgdown"},
+		ActionHome:            {"g", "home"},
+		ActionEnd:             {"G", "end"},
+		ActionSelect:          {"enter", "l"},
+		ActionServers:         {"S"},
+		ActionAdd:             {"a", "o"},
+		ActionImport:          {"R"},
+		ActionExport:          {"E"},
+		ActionEdit:            {"e", "i"},
+		ActionMoveUp:          {"K", "shift+up"},
+		ActionMoveDown:        {"J", "shift+down"},
+		ActionDuplicate:       {"y"},
+		ActionInline:          {"L"},
+		ActionFolder:          {"m"},
+		ActionTags:            {"t"},
+		ActionConnectGroup:    {"C"},
+		ActionDisconnectGroup: {"X"},
+		ActionDelete:          {"d", "x"},
+		ActionRefresh:         {"r", "ctrl+r"},
+		ActionStats:           {"s"},
+		ActionCerts:           {"I"},
+		ActionCredentials:     {"p"},
+		ActionForget:          {"P"},
+		ActionTOTP:            {"T"},
+		ActionHookLog:         {"H"},
+		ActionHelp:            {"?"},
+		ActionFilter:          {"/"},
+		ActionToggle:          {" ", "x"},
+		ActionToggleAll:       {"a"},
+		ActionCopyFiles:       {"c"},
+		ActionConfigOrder:     {"o"},
+		ActionClose:           {"esc", "q"},
+		ActionConfirm:         {"y", "Y", "enter"},
+		ActionCancel:          {"n", "N", "esc"},
+	},
+	"emacs": {
+		ActionQuit:            {"ctrl+c", "q"},
+		ActionSwitchView:      {"tab", "ctrl+o"},
+		ActionUp:              {"ctrl+p", "up"},
+		ActionDown:            {"ctrl+n", "down"},
+		ActionPageUp:          {"alt+v", "pgup"},
+		ActionPageDown:        {"ctrl+v", "pgdown"},
+		ActionHome:            {"alt+<", "home"},
+		ActionEnd:             {"alt+>", "end"},
+		ActionSelect:          {"enter", "ctrl+f"},
+		ActionServers:         {"S"},
+		ActionAdd:             {"a"},
+		ActionImport:          {"I"},
+		ActionExport:          {"E"},
+		ActionEdit:            {"e"},
+		ActionMoveUp:          {"alt+p", "shift+up"},
+		ActionMoveDown:        {"alt+n", "shift+down"},
+		ActionDuplicate:       {"c"},
+		ActionInline:          {"L"},
+		ActionFolder:          {"m"},
+		ActionTags:            {"t"},
+		ActionConnectGroup:    {"C"},
+		ActionDisconnectGroup: {"ctrl+x", "X"},
+		ActionDelete:          {"ctrl+d", "delete"},
+		ActionRefresh:         {"g"},
+		ActionStats:           {"s"},
+		ActionCerts:           {"i"},
+		ActionCredentials:     {"p"},
+		ActionForget:          {"P"},
+		ActionTOTP:            {"T"},
+		ActionHookLog:         {"H"},
+		ActionHelp:            {"?"},
+		ActionFilter:          {"ctrl+s", "/"},
+		ActionToggle:          {" ", "ctrl+@"},
+		ActionToggleAll:       {"a"},
+		ActionCopyFiles:       {"c"},
+		ActionConfigOrder:     {"o"},
+		ActionClose:           {"esc", "ctrl+g"},
+		ActionConfirm:         {"y", "enter"},
+		ActionCancel:          {"n", "esc", "ctrl+g"},
+	},
+}
+
+// Keymap holds the user's key binding preferences
+type Keymap struct {
+	// Preset selects a built-in layout: default, vim or emacs
+	Preset string `json:"preset,omitempty"`
+	// Bindings overrides the keys of individual actions
+	Bindings map[string][]string `json:"bindings,omitempty"`
+}
+
+// KeymapPresets returns the names of the built-in presets
+func KeymapPresets() []string {
+	names := make([]string, 0, len(keymapPresets))
+	for name := range keymapPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve merges the preset with the overrides and returns the keys per action.
+// It returns an error for unknown presets or actions and for keys bound twice in a scope.
+func (k Keymap) Resolve() (map[string][]string, error) {
+	presetName := k.Preset
+	if presetName == "" {
+		presetName = "default"
+	}
+	preset, ok := keymapPresets[presetName]
+	if !ok {
+		return nil, fmt.Errorf("unknown keymap preset %q", presetName)
+	}
+
+	resolved := make(map[string][]string, len(preset))
+	for action, keys := range preset {
+		resolved[action] = keys
+	}
+	for action, keys := range k.Bindings {
+		if _, ok := preset[action]; !ok {
+			return nil, fmt.Errorf("unknown keymap action %q", action)
+		}
+		resolved[action] = keys
+	}
+
+	// Detect keys bound to more than one action in the same scope
+	for _, scope := range keyScopes {
+		owner := make(map[string]string)
+		for _, action := range scope {
+			for _, key := range resolved[action] {
+				if other, ok := owner[key]; ok && other != action {
+					return nil, fmt.Errorf("key %q is bound to both %q and %q", key, other, action)
+				}
+				owner[key] = action
+			}
+		}
+	}
+
+	return resolved, nil
+}
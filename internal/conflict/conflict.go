@@ -0,0 +1,284 @@
+// Package conflict finds sessions that fight over routes and DNS, either
+// with each other or with the local network.
+package conflict
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"openvpn3-tui/internal/ovpn"
+)
+
+// Footprint is what a session adds to the routing table and the resolver
+type Footprint struct {
+	Routes     []netip.Prefix `json:"routes,omitempty"`
+	Default    bool           `json:"default,omitempty"` // Routes all traffic
+	DNS        []string       `json:"dns,omitempty"`     // Servers
+	Domains    []string       `json:"domains,omitempty"` // Search and routing domains
+	DefaultDNS bool           `json:"default_dns,omitempty"`
+}
+
+// Empty reports whether the footprint adds nothing
+func (f Footprint) Empty() bool {
+	return len(f.Routes) == 0 && !f.Default && len(f.DNS) == 0 && len(f.Domains) == 0 && !f.DefaultDNS
+}
+
+// Merge combines two footprints, e.g. the routes a profile asks for with those
+// its server pushed last time
+func Merge(a, b Footprint) Footprint {
+	merged := Footprint{
+		Default:    a.Default || b.Default,
+		DefaultDNS: a.DefaultDNS || b.DefaultDNS,
+	}
+	for _, p := range append(slices.Clone(a.Routes), b.Routes...) {
+		if !slices.Contains(merged.Routes, p) {
+			merged.Routes = append(merged.Routes, p)
+		}
+	}
+	for _, s := range append(slices.Clone(a.DNS), b.DNS...) {
+		if !slices.Contains(merged.DNS, s) {
+			merged.DNS = append(merged.DNS, s)
+		}
+	}
+	for _, d := range append(slices.Clone(a.Domains), b.Domains...) {
+		if !slices.Contains(merged.Domains, d) {
+			merged.Domains = append(merged.Domains, d)
+		}
+	}
+	return merged
+}
+
+// addDomain records a search or routing domain. The root routing domain "~."
+// sends all queries to the session.
+func (f *Footprint) addDomain(domain string) {
+	d := normalizeDomain(domain)
+	if d == "" {
+		f.DefaultDNS = true
+		return
+	}
+	if !slices.Contains(f.Domains, d) {
+		f.Domains = append(f.Domains, d)
+	}
+}
+
+// finish applies what follows from the other fields: the two halves that
+// redirect-gateway def1 installs route everything, and DNS servers without
+// domains answer all queries
+func (f *Footprint) finish() {
+	halves := map[string][]string{
+		"v4": {"0.0.0.0/1", "128.0.0.0/1"},
+		"v6": {"::/1", "8000::/1"},
+	}
+	for _, pair := range halves {
+		a, b := netip.MustParsePrefix(pair[0]), netip.MustParsePrefix(pair[1])
+		if slices.Contains(f.Routes, a) && slices.Contains(f.Routes, b) {
+			f.Default = true
+			f.Routes = slices.DeleteFunc(f.Routes, func(p netip.Prefix) bool { return p == a || p == b })
+		}
+	}
+	f.Routes = slices.DeleteFunc(f.Routes, func(p netip.Prefix) bool {
+		if p.Bits() == 0 {
+			f.Default = true
+			return true
+		}
+		return false
+	})
+	if len(f.DNS) > 0 && len(f.Domains) == 0 {
+		f.DefaultDNS = true
+	}
+}
+
+// normalizeDomain lowercases a domain and strips the routing-only "~" and
+// dots around it
+func normalizeDomain(domain string) string {
+	return strings.Trim(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "~"), ".")
+}
+
+// Predict reads the routes and DNS settings a profile asks for itself. Routes
+// and DNS its server pushes are only known once it was connected.
+func Predict(cfg *ovpn.Config) Footprint {
+	var f Footprint
+	for _, d := range cfg.All("route") {
+		addr, err := netip.ParseAddr(d.Arg(0))
+		if err != nil || !addr.Is4() {
+			// Keywords such as vpn_gateway and host names are left out
+			continue
+		}
+		bits := 32
+		if mask, err := netip.ParseAddr(d.Arg(1)); err == nil && mask.Is4() {
+			bits = maskBits(mask)
+		}
+		if p, err := addr.Prefix(bits); err == nil {
+			f.Routes = append(f.Routes, p)
+		}
+	}
+	for _, d := range cfg.All("route-ipv6") {
+		if p, err := netip.ParsePrefix(d.Arg(0)); err == nil {
+			f.Routes = append(f.Routes, p.Masked())
+		}
+	}
+	if cfg.Has("redirect-gateway") {
+		f.Default = true
+	}
+	for _, d := range cfg.All("dhcp-option") {
+		switch strings.ToUpper(d.Arg(0)) {
+		case "DNS", "DNS6":
+			f.DNS = append(f.DNS, d.Arg(1))
+		case "DOMAIN", "DOMAIN-SEARCH", "ADAPTER_DOMAIN_SUFFIX", "DOMAIN-ROUTE":
+			f.addDomain(d.Arg(1))
+		}
+	}
+	f.finish()
+	return f
+}
+
+// maskBits counts the leading ones of a netmask
+func maskBits(mask netip.Addr) int {
+	bits := 0
+	for _, b := range mask.AsSlice() {
+		for i := 7; i >= 0 && b&(1<<i) != 0; i-- {
+			bits++
+		}
+	}
+	return bits
+}
+
+// LAN is the name conflicts with the local network are reported under
+const LAN = "local network"
+
+// Owner is a footprint with the name of the session or network it belongs to
+type Owner struct {
+	Name string
+	Footprint
+}
+
+// Conflict describes two footprints that get in each other's way
+type Conflict struct {
+	With   string // Session or network the footprint conflicts with
+	Detail string
+}
+
+// String formats the conflict for messages
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s with %s", c.Detail, c.With)
+}
+
+// Find checks f against others. Routing everything is only a conflict with
+// another session doing the same, since more specific routes take precedence.
+func Find(f Footprint, others []Owner) []Conflict {
+	var conflicts []Conflict
+	for _, o := range others {
+		add := func(format string, args ...any) {
+			conflicts = append(conflicts, Conflict{With: o.Name, Detail: fmt.Sprintf(format, args...)})
+		}
+		if f.Default && o.Default {
+			add("both route all traffic")
+		}
+		for _, p := range f.Routes {
+			for _, q := range o.Routes {
+				switch {
+				case p == q:
+					add("both route %s", p)
+				case p.Overlaps(q):
+					add("route %s overlaps %s", p, q)
+				}
+			}
+		}
+		if f.DefaultDNS && o.DefaultDNS {
+			add("both answer all DNS queries")
+		}
+		for _, d := range f.Domains {
+			for _, e := range o.Domains {
+				switch {
+				case d == e:
+					add("both resolve %s", d)
+				case strings.HasSuffix(d, "."+e) || strings.HasSuffix(e, "."+d):
+					add("DNS domain %s overlaps %s", d, e)
+				}
+			}
+		}
+	}
+	return conflicts
+}
+
+// Analyze checks every footprint against the others and the local network,
+// returning the conflicts of each in the same order
+func Analyze(sessions []Owner, lan Footprint) [][]Conflict {
+	found := make([][]Conflict, len(sessions))
+	for i, s := range sessions {
+		others := []Owner{{Name: LAN, Footprint: lan}}
+		for j, o := range sessions {
+			if i != j {
+				others = append(others, o)
+			}
+		}
+		found[i] = Find(s.Footprint, others)
+	}
+	return found
+}
+
+// Store remembers the footprint of each profile's last session, so that
+// routes and DNS pushed by its server are known before it connects again.
+// It is safe for concurrent use.
+type Store struct {
+	mu         sync.Mutex
+	path       string
+	footprints map[string]Footprint
+}
+
+// OpenStore loads the footprints saved at path. A missing file is an empty store.
+func OpenStore(path string) (*Store, error) {
+	s := &Store{path: path, footprints: make(map[string]Footprint)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s.footprints); err != nil {
+		return s, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Get returns the footprint remembered for a profile
+func (s *Store) Get(profile string) Footprint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.footprints[profile]
+}
+
+// Update remembers footprints by profile and saves them when any changed
+func (s *Store) Update(footprints map[string]Footprint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := false
+	for profile, f := range footprints {
+		old, _ := json.Marshal(s.footprints[profile])
+		cur, _ := json.Marshal(f)
+		if string(old) != string(cur) {
+			s.footprints[profile] = f
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.footprints, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
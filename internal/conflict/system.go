@@ -0,0 +1,162 @@
+package conflict
+
+import (
+	"bufio"
+	"encoding/hex"
+	"io"
+	"net/netip"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"openvpn3-tui/internal/network"
+)
+
+// route is a routing table entry
+type route struct {
+	device string
+	prefix netip.Prefix
+}
+
+// link holds the DNS settings systemd-resolved has for an interface
+type link struct {
+	dns     []string
+	domains []string
+}
+
+// Snapshot is the routing table and resolver configuration at one moment
+type Snapshot struct {
+	routes []route
+	links  map[string]link
+}
+
+// Read takes a snapshot of this machine's routes and, when systemd-resolved
+// is in use, its per-interface DNS settings
+func Read() (*Snapshot, error) {
+	s := &Snapshot{links: make(map[string]link)}
+	routes, err := network.ReadRoutes()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range routes {
+		s.routes = append(s.routes, route{device: r.Device, prefix: r.Prefix})
+	}
+	if f6, err := os.Open("/proc/net/ipv6_route"); err == nil {
+		defer f6.Close()
+		routes, err := parseRoutes6(f6)
+		if err != nil {
+			return nil, err
+		}
+		s.routes = append(s.routes, routes...)
+	}
+
+	// Without resolvectl nothing is known about DNS per interface
+	if out, err := exec.Command("resolvectl", "dns").Output(); err == nil {
+		for dev, values := range parseResolvectl(string(out)) {
+			l := s.links[dev]
+			l.dns = values
+			s.links[dev] = l
+		}
+	}
+	if out, err := exec.Command("resolvectl", "domain").Output(); err == nil {
+		for dev, values := range parseResolvectl(string(out)) {
+			l := s.links[dev]
+			l.domains = values
+			s.links[dev] = l
+		}
+	}
+	return s, nil
+}
+
+// Device returns the footprint of a tunnel device
+func (s *Snapshot) Device(device string) Footprint {
+	var f Footprint
+	for _, r := range s.routes {
+		if r.device == device {
+			f.Routes = append(f.Routes, r.prefix)
+		}
+	}
+	l := s.links[device]
+	f.DNS = l.dns
+	for _, d := range l.domains {
+		f.addDomain(d)
+	}
+	f.finish()
+	return f
+}
+
+// LAN returns the networks and DNS domains of the interfaces outside of any
+// VPN. Their default route and DNS servers are what a VPN is expected to
+// replace, so they are left out.
+func (s *Snapshot) LAN() Footprint {
+	var f Footprint
+	for _, r := range s.routes {
+		if r.device != "lo" && !network.IsTunnel(r.device) && r.prefix.Bits() > 0 {
+			f.Routes = append(f.Routes, r.prefix)
+		}
+	}
+	for dev, l := range s.links {
+		if dev != "lo" && !network.IsTunnel(dev) {
+			for _, d := range l.domains {
+				if d := normalizeDomain(d); d != "" {
+					f.Domains = append(f.Domains, d)
+				}
+			}
+		}
+	}
+	return f
+}
+
+// rtfLocal marks entries of the local table in /proc/net/ipv6_route
+const rtfLocal = 0x80000000
+
+// parseRoutes6 reads /proc/net/ipv6_route, leaving out local addresses,
+// link-local and multicast networks, which every interface has
+func parseRoutes6(r io.Reader) ([]route, error) {
+	var routes []route
+	linkLocal := netip.MustParsePrefix("fe80::/10")
+	multicast := netip.MustParsePrefix("ff00::/8")
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		raw, err := hex.DecodeString(fields[0])
+		if err != nil || len(raw) != 16 {
+			continue
+		}
+		bits, err1 := strconv.ParseUint(fields[1], 16, 8)
+		flags, err2 := strconv.ParseUint(fields[8], 16, 32)
+		if err1 != nil || err2 != nil || flags&rtfLocal != 0 {
+			continue
+		}
+		addr := netip.AddrFrom16([16]byte(raw))
+		if linkLocal.Contains(addr) || multicast.Contains(addr) {
+			continue
+		}
+		if p, err := addr.Prefix(int(bits)); err == nil {
+			routes = append(routes, route{device: fields[9], prefix: p})
+		}
+	}
+	return routes, scanner.Err()
+}
+
+// parseResolvectl reads the per-link lines of "resolvectl dns" or
+// "resolvectl domain", e.g. "Link 5 (tun0): 10.0.0.53 10.0.0.54"
+func parseResolvectl(out string) map[string][]string {
+	values := make(map[string][]string)
+	for _, line := range strings.Split(out, "\n") {
+		rest, ok := strings.CutPrefix(strings.TrimSpace(line), "Link ")
+		if !ok {
+			continue
+		}
+		start, end := strings.Index(rest, "("), strings.Index(rest, "):")
+		if start < 0 || end < start {
+			continue
+		}
+		values[rest[start+1:end]] = strings.Fields(rest[end+2:])
+	}
+	return values
+}
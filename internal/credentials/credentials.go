@@ -0,0 +1,132 @@
+// Package credentials keeps the usernames and passwords of profiles so that
+// openvpn3 authentication prompts can be answered without typing them.
+//
+// Secrets go to the freedesktop Secret Service (GNOME Keyring, KWallet,
+// KeePassXC) when one is running, and otherwise to a file in the config
+// directory encrypted with a passphrase.
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"openvpn3-tui/internal/config"
+)
+
+// ErrLocked is returned when the credential file has not been unlocked yet
+var ErrLocked = errors.New("credential file is locked")
+
+// ErrWrongPassphrase is returned when the credential file cannot be decrypted
+var ErrWrongPassphrase = errors.New("wrong passphrase")
+
+// Store kinds accepted by Open and the credential_store config setting
+const (
+	KindSecretService = "secret-service"
+	KindFile          = "file"
+)
+
+// Credentials are the secrets saved for a profile
+type Credentials struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	OTP      *TOTP  `json:"otp,omitempty"`
+}
+
+// IsZero reports whether nothing is left to save
+func (c Credentials) IsZero() bool {
+	return c.Username == "" && c.Password == "" && c.OTP == nil
+}
+
+// otpWords identify prompts asking for a one-time password, including
+// challenge texts such as "Enter Authenticator Code"
+var otpWords = []string{"otp", "one-time", "one time", "authenticator", "token", "code", "2fa", "verification"}
+
+// IsOTPPrompt reports whether a prompt asks for a one-time password
+func IsOTPPrompt(prompt string) bool {
+	p := strings.ToLower(prompt)
+	for _, w := range otpWords {
+		if strings.Contains(p, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// Answer returns the reply to an openvpn3 prompt such as "Auth User name",
+// "Auth Password" or an OTP challenge. Prompts it knows nothing about are
+// left unanswered.
+func (c Credentials) Answer(prompt string) (string, bool) {
+	p := strings.ToLower(prompt)
+	switch {
+	case c.OTP != nil && IsOTPPrompt(prompt):
+		return c.OTPCode()
+	case strings.Contains(p, "private key"):
+		// A key passphrase is not the account password
+		return "", false
+	case strings.Contains(p, "password") && c.Password != "":
+		return c.Password, true
+	case strings.Contains(p, "user") && c.Username != "":
+		return c.Username, true
+	}
+	return "", false
+}
+
+// OTPCode returns the current one-time password
+func (c Credentials) OTPCode() (string, bool) {
+	if c.OTP == nil {
+		return "", false
+	}
+	code, err := c.OTP.Code(time.Now())
+	return code, err == nil
+}
+
+// Store saves credentials by profile name
+type Store interface {
+	// Name describes where credentials are kept
+	Name() string
+	// Locked reports whether Unlock must be called before the store is used
+	Locked() bool
+	Unlock(passphrase string) error
+	// Get returns the credentials of a profile and whether any were saved
+	Get(profile string) (Credentials, bool, error)
+	Set(profile string, c Credentials) error
+	Delete(profile string) error
+	// List returns the names of the profiles with saved credentials
+	List() ([]string, error)
+}
+
+// Open returns the store of the given kind. With an empty kind the Secret
+// Service is used when it answers and the encrypted file otherwise.
+func Open(kind string) (Store, error) {
+	path, err := config.CredentialsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case KindSecretService:
+		return NewSecretService(), nil
+	case KindFile:
+		return NewFileStore(path), nil
+	case "":
+		if s := NewSecretService(); s.Available() {
+			return s, nil
+		}
+		return NewFileStore(path), nil
+	}
+	return nil, fmt.Errorf("unknown credential_store %q, use %s or %s", kind, KindSecretService, KindFile)
+}
+
+// Move keeps the credentials of a renamed profile
+func Move(s Store, from, to string) error {
+	c, ok, err := s.Get(from)
+	if err != nil || !ok {
+		return err
+	}
+	if err := s.Set(to, c); err != nil {
+		return err
+	}
+	return s.Delete(from)
+}
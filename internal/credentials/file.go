@@ -0,0 +1,262 @@
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// fileVersion is the format version of the credential file
+const fileVersion = 1
+
+// pbkdf2Iterations is the work factor used for new credential files and the
+// least accepted from existing ones
+const pbkdf2Iterations = 600000
+
+// fileFormat is the JSON layout of the credential file. Data holds the
+// AES-256-GCM encrypted credentials keyed by profile name.
+type fileFormat struct {
+	Version    int    `json:"version"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Data       []byte `json:"data"`
+}
+
+// FileStore keeps credentials in a file encrypted with a key derived from a
+// passphrase. It is locked until Unlock is called with the passphrase.
+type FileStore struct {
+	path string
+
+	mu         sync.Mutex
+	key        []byte
+	salt       []byte
+	iterations int
+	entries    map[string]Credentials
+}
+
+// NewFileStore creates a store for the credential file at path
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Name describes the store
+func (s *FileStore) Name() string {
+	return "encrypted file"
+}
+
+// Path returns the location of the credential file
+func (s *FileStore) Path() string {
+	return s.path
+}
+
+// Exists reports whether the credential file has been created
+func (s *FileStore) Exists() bool {
+	_, err := os.Stat(s.path)
+	return err == nil
+}
+
+// Locked reports whether the passphrase is still needed
+func (s *FileStore) Locked() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.key == nil
+}
+
+// Unlock decrypts the credential file. When there is no file yet the
+// passphrase is used to encrypt the one written by the first Set.
+func (s *FileStore) Unlock(passphrase string) error {
+	if passphrase == "" {
+		return errors.New("passphrase cannot be empty")
+	}
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+		key, err := deriveKey(passphrase, salt, pbkdf2Iterations)
+		if err != nil {
+			return err
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.key, s.salt, s.iterations = key, salt, pbkdf2Iterations
+		s.entries = make(map[string]Credentials)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var f fileFormat
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("invalid credential file: %w", err)
+	}
+	if f.Version != fileVersion {
+		return fmt.Errorf("unsupported credential file version %d", f.Version)
+	}
+	if f.Iterations < pbkdf2Iterations {
+		// A lowered work factor would make the passphrase cheap to guess
+		return fmt.Errorf("credential file uses %d PBKDF2 iterations, fewer than the required %d", f.Iterations, pbkdf2Iterations)
+	}
+	key, err := deriveKey(passphrase, f.Salt, f.Iterations)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	plain, err := gcm.Open(nil, f.Nonce, f.Data, nil)
+	if err != nil {
+		return ErrWrongPassphrase
+	}
+	entries := make(map[string]Credentials)
+	if err := json.Unmarshal(plain, &entries); err != nil {
+		return fmt.Errorf("invalid credential file: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.key, s.salt, s.iterations, s.entries = key, f.Salt, f.Iterations, entries
+	return nil
+}
+
+// Get returns the credentials of a profile
+func (s *FileStore) Get(profile string) (Credentials, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key == nil {
+		return Credentials{}, false, ErrLocked
+	}
+	c, ok := s.entries[profile]
+	return c, ok, nil
+}
+
+// Set saves the credentials of a profile and rewrites the file
+func (s *FileStore) Set(profile string, c Credentials) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key == nil {
+		return ErrLocked
+	}
+	prev, had := s.entries[profile]
+	s.entries[profile] = c
+	if err := s.save(); err != nil {
+		if had {
+			s.entries[profile] = prev
+		} else {
+			delete(s.entries, profile)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes the credentials of a profile and rewrites the file
+func (s *FileStore) Delete(profile string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key == nil {
+		return ErrLocked
+	}
+	prev, had := s.entries[profile]
+	if !had {
+		return nil
+	}
+	delete(s.entries, profile)
+	if err := s.save(); err != nil {
+		s.entries[profile] = prev
+		return err
+	}
+	return nil
+}
+
+// List returns the profiles with saved credentials
+func (s *FileStore) List() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key == nil {
+		return nil, ErrLocked
+	}
+	names := make([]string, 0, len(s.entries))
+	for name := range s.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// save encrypts the entries with a fresh nonce and replaces the file
+// atomically. The caller holds the lock.
+func (s *FileStore) save() error {
+	plain, err := json.Marshal(s.entries)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(s.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(fileFormat{
+		Version:    fileVersion,
+		Iterations: s.iterations,
+		Salt:       s.salt,
+		Nonce:      nonce,
+		Data:       gcm.Seal(nil, nonce, plain, nil),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".credentials-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// deriveKey turns a passphrase into an AES-256 key
+func deriveKey(passphrase string, salt []byte, iterations int) ([]byte, error) {
+	if len(salt) == 0 || iterations <= 0 {
+		return nil, errors.New("invalid credential file: missing key parameters")
+	}
+	return pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+}
+
+// newGCM creates the AES-GCM cipher for a key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
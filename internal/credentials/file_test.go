@@ -0,0 +1,140 @@
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.enc")
+	s := NewFileStore(path)
+	if !s.Locked() || s.Exists() {
+		t.Fatal("new store should be locked and have no file")
+	}
+	if err := s.Set("Work", Credentials{}); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Set() while locked = %v, want ErrLocked", err)
+	}
+	if err := s.Unlock("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	want := Credentials{Username: "alice", Password: "hunter2"}
+	if err := s.Set("Work", want); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("Home", Credentials{Username: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("Home"); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		t.Errorf("credential file mode = %v, want no group or other access", info.Mode().Perm())
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "hunter2") || strings.Contains(string(data), "alice") {
+		t.Error("credential file holds plain text secrets")
+	}
+
+	reopened := NewFileStore(path)
+	if err := reopened.Unlock("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	got, found, err := reopened.Get("Work")
+	if err != nil || !found || got != want {
+		t.Errorf("Get() after reopening = %+v, %v, %v, want %+v", got, found, err, want)
+	}
+	if names, _ := reopened.List(); !slices.Equal(names, []string{"Work"}) {
+		t.Errorf("List() after reopening = %q", names)
+	}
+}
+
+func TestFileStoreWrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.enc")
+	s := NewFileStore(path)
+	if err := s.Unlock("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("Work", Credentials{Password: "hunter2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := NewFileStore(path)
+	if err := reopened.Unlock("battery staple"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("Unlock() with the wrong passphrase = %v, want ErrWrongPassphrase", err)
+	}
+	if !reopened.Locked() {
+		t.Error("store unlocked after a wrong passphrase")
+	}
+	if err := reopened.Unlock(""); err == nil {
+		t.Error("Unlock() accepted an empty passphrase")
+	}
+}
+
+func TestFileStoreCorrupted(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "credentials.enc")
+	s := NewFileStore(path)
+	if err := s.Unlock("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("Work", Credentials{Password: "hunter2"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f fileFormat
+	if err := json.Unmarshal(data, &f); err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(name string, v any) string {
+		p := filepath.Join(dir, name)
+		raw, ok := v.([]byte)
+		if !ok {
+			raw, _ = json.Marshal(v)
+		}
+		if err := os.WriteFile(p, raw, 0600); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	tampered := f
+	tampered.Data = append([]byte(nil), f.Data...)
+	tampered.Data[0] ^= 0xff
+	future := f
+	future.Version = fileVersion + 1
+	noSalt := f
+	noSalt.Salt = nil
+	weak := f
+	weak.Iterations = 1000
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"not json", write("garbage.enc", []byte("{not json")), "invalid credential file"},
+		{"tampered data", write("tampered.enc", tampered), ErrWrongPassphrase.Error()},
+		{"newer version", write("future.enc", future), "unsupported credential file version"},
+		{"missing salt", write("nosalt.enc", noSalt), "missing key parameters"},
+		{"few iterations", write("weak.enc", weak), "fewer than the required"},
+	}
+	for _, tt := range tests {
+		err := NewFileStore(tt.path).Unlock("correct horse")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Unlock() = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package credentials
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// application is the Secret Service attribute every item is stored under
+const application = "openvpn3-tui"
+
+// SecretToolRunner runs secret-tool with args, feeding it stdin, and returns
+// what it printed. A non-zero exit is reported as an *exec.ExitError.
+type SecretToolRunner func(stdin string, args ...string) (stdout, stderr []byte, err error)
+
+// SecretService stores credentials in the freedesktop Secret Service using
+// the secret-tool command from libsecret. Each profile is one item holding
+// its credentials as JSON.
+type SecretService struct {
+	run SecretToolRunner
+}
+
+// NewSecretService creates a Secret Service store
+func NewSecretService() *SecretService {
+	return NewSecretServiceWith(runSecretTool)
+}
+
+// NewSecretServiceWith creates a Secret Service store that runs secret-tool
+// through run, e.g. a stub standing in for the service
+func NewSecretServiceWith(run SecretToolRunner) *SecretService {
+	return &SecretService{run: run}
+}
+
+// Name describes the store
+func (s *SecretService) Name() string {
+	return "Secret Service"
+}
+
+// Locked is always false, the service unlocks its keyring on its own
+func (s *SecretService) Locked() bool {
+	return false
+}
+
+// Unlock does nothing for the Secret Service
+func (s *SecretService) Unlock(string) error {
+	return nil
+}
+
+// Available reports whether secret-tool is installed and a service answers
+// on the session bus
+func (s *SecretService) Available() bool {
+	_, _, err := s.secretTool("", "search", "application", application)
+	return err == nil
+}
+
+// Get returns the credentials of a profile
+func (s *SecretService) Get(profile string) (Credentials, bool, error) {
+	var c Credentials
+	out, found, err := s.secretTool("", "lookup", "application", application, "profile", profile)
+	if err != nil || !found {
+		return c, false, err
+	}
+	if err := json.Unmarshal(out, &c); err != nil {
+		return c, false, fmt.Errorf("invalid credentials for %s: %w", profile, err)
+	}
+	return c, true, nil
+}
+
+// Set saves the credentials of a profile, replacing any saved before
+func (s *SecretService) Set(profile string, c Credentials) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	_, _, err = s.secretTool(string(data), "store", "--label=OpenVPN3 TUI: "+profile,
+		"application", application, "profile", profile)
+	return err
+}
+
+// Delete removes the credentials of a profile
+func (s *SecretService) Delete(profile string) error {
+	_, _, err := s.secretTool("", "clear", "application", application, "profile", profile)
+	return err
+}
+
+// List returns the profiles with saved credentials
+func (s *SecretService) List() ([]string, error) {
+	out, _, err := s.secretTool("", "search", "--all", "application", application)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if name, ok := strings.CutPrefix(scanner.Text(), "attribute.profile = "); ok {
+			names = append(names, name)
+		}
+	}
+	return names, scanner.Err()
+}
+
+// secretTool runs secret-tool with stdin as input. secret-tool exits with an
+// error but prints nothing when no item matches, which is reported as not
+// found rather than as an error.
+func (s *SecretService) secretTool(stdin string, args ...string) ([]byte, bool, error) {
+	out, stderr, err := s.run(stdin, args...)
+	if err != nil {
+		if msg := strings.TrimSpace(string(stderr)); msg != "" {
+			return nil, false, errors.New(msg)
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return out, true, nil
+}
+
+// runSecretTool runs the installed secret-tool
+func runSecretTool(stdin string, args ...string) ([]byte, []byte, error) {
+	cmd := exec.Command("secret-tool", args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	return out, stderr.Bytes(), err
+}
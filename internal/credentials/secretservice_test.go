@@ -0,0 +1,153 @@
+package credentials
+
+import (
+	"fmt"
+	"os/exec"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// stubService stands in for secret-tool and the Secret Service behind it,
+// keeping items in memory
+type stubService struct {
+	items map[string]string // Secrets by profile attribute
+	down  bool              // No service answers on the session bus
+}
+
+func newStubService() *stubService {
+	return &stubService{items: make(map[string]string)}
+}
+
+// exitError is what running secret-tool returns when it exits with 1
+func exitError() error {
+	return &exec.ExitError{}
+}
+
+func (s *stubService) run(stdin string, args ...string) ([]byte, []byte, error) {
+	if s.down {
+		return nil, []byte("Cannot autolaunch D-Bus without X11 $DISPLAY\n"), exitError()
+	}
+	var attrs []string
+	for _, a := range args[1:] {
+		if !strings.HasPrefix(a, "--") {
+			attrs = append(attrs, a)
+		}
+	}
+	lookup := make(map[string]string)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		lookup[attrs[i]] = attrs[i+1]
+	}
+	if lookup["application"] != application {
+		return nil, nil, exitError()
+	}
+	profile, hasProfile := lookup["profile"]
+
+	switch args[0] {
+	case "store":
+		s.items[profile] = stdin
+	case "lookup":
+		secret, ok := s.items[profile]
+		if !ok {
+			return nil, nil, exitError()
+		}
+		return []byte(secret), nil, nil
+	case "clear":
+		delete(s.items, profile)
+	case "search":
+		var names []string
+		for name := range s.items {
+			if !hasProfile || name == profile {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			return nil, nil, exitError()
+		}
+		sort.Strings(names)
+		var out strings.Builder
+		for i, name := range names {
+			fmt.Fprintf(&out, "[/org/freedesktop/secrets/collection/login/%d]\n", i+1)
+			fmt.Fprintf(&out, "label = OpenVPN3 TUI: %s\nsecret = %s\n", name, s.items[name])
+			fmt.Fprintf(&out, "attribute.profile = %s\nattribute.application = %s\n", name, application)
+		}
+		return []byte(out.String()), nil, nil
+	default:
+		return nil, []byte("usage: secret-tool ...\n"), exitError()
+	}
+	return nil, nil, nil
+}
+
+func TestSecretServiceRoundTrip(t *testing.T) {
+	stub := newStubService()
+	s := NewSecretServiceWith(stub.run)
+
+	if _, found, err := s.Get("Work"); err != nil || found {
+		t.Fatalf("Get() of a missing profile = %v, %v, want not found", found, err)
+	}
+	want := Credentials{Username: "alice", Password: "hunter2"}
+	if err := s.Set("Work", want); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("Home VPN", Credentials{Username: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	got, found, err := s.Get("Work")
+	if err != nil || !found || got != want {
+		t.Fatalf("Get() = %+v, %v, %v, want %+v", got, found, err, want)
+	}
+
+	names, err := s.List()
+	if err != nil || !slices.Equal(names, []string{"Home VPN", "Work"}) {
+		t.Fatalf("List() = %q, %v", names, err)
+	}
+
+	if err := Move(s, "Work", "Office"); err != nil {
+		t.Fatal(err)
+	}
+	if got, found, _ := s.Get("Office"); !found || got != want {
+		t.Errorf("Get() after Move() = %+v, %v", got, found)
+	}
+	if _, found, _ := s.Get("Work"); found {
+		t.Error("Move() kept the old item")
+	}
+
+	if err := s.Delete("Office"); err != nil {
+		t.Fatal(err)
+	}
+	if names, _ := s.List(); !slices.Equal(names, []string{"Home VPN"}) {
+		t.Errorf("List() after Delete() = %q", names)
+	}
+}
+
+func TestSecretServiceAvailable(t *testing.T) {
+	stub := newStubService()
+	s := NewSecretServiceWith(stub.run)
+	if !s.Available() {
+		t.Error("Available() = false with an empty keyring")
+	}
+	stub.down = true
+	if s.Available() {
+		t.Error("Available() = true without a service")
+	}
+}
+
+func TestSecretServiceErrors(t *testing.T) {
+	stub := newStubService()
+	s := NewSecretServiceWith(stub.run)
+
+	stub.items["Work"] = "not json"
+	if _, _, err := s.Get("Work"); err == nil || !strings.Contains(err.Error(), "invalid credentials for Work") {
+		t.Errorf("Get() of a broken item = %v", err)
+	}
+
+	stub.down = true
+	err := s.Set("Work", Credentials{Username: "alice"})
+	if err == nil || err.Error() != "Cannot autolaunch D-Bus without X11 $DISPLAY" {
+		t.Errorf("Set() without a service = %v, want the secret-tool message", err)
+	}
+	if _, err := s.List(); err == nil {
+		t.Error("List() without a service succeeded")
+	}
+}
@@ -0,0 +1,69 @@
+package credentials
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestTOTPCode checks the test vectors of RFC 6238 Appendix B
+func TestTOTPCode(t *testing.T) {
+	seed := func(n int) string {
+		key := []byte(strings.Repeat("1234567890", 7)[:n])
+		return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(key)
+	}
+	algorithms := []struct {
+		name   string
+		secret string
+	}{
+		{"SHA1", seed(20)},
+		{"SHA256", seed(32)},
+		{"SHA512", seed(64)},
+	}
+	vectors := []struct {
+		unix  int64
+		codes [3]string // SHA1, SHA256, SHA512
+	}{
+		{59, [3]string{"94287082", "46119246", "90693936"}},
+		{1111111109, [3]string{"07081804", "68084774", "25091201"}},
+		{1111111111, [3]string{"14050471", "67062674", "99943326"}},
+		{1234567890, [3]string{"89005924", "91819424", "93441116"}},
+		{2000000000, [3]string{"69279037", "90698825", "38618901"}},
+		{20000000000, [3]string{"65353130", "77737706", "47863826"}},
+	}
+	for i, alg := range algorithms {
+		totp := TOTP{Secret: alg.secret, Digits: 8, Algorithm: alg.name}
+		for _, v := range vectors {
+			got, err := totp.Code(time.Unix(v.unix, 0))
+			if err != nil {
+				t.Fatalf("%s: %v", alg.name, err)
+			}
+			if got != v.codes[i] {
+				t.Errorf("%s at %d: Code() = %s, want %s", alg.name, v.unix, got, v.codes[i])
+			}
+		}
+	}
+}
+
+func TestParseTOTP(t *testing.T) {
+	got, err := ParseTOTP("otpauth://totp/ACME:alice@example.com?secret=jbsw%20y3dp%20ehpk%203pxp&issuer=ACME&algorithm=sha256&digits=8&period=60")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := TOTP{Secret: "JBSWY3DPEHPK3PXP", Digits: 8, Period: 60, Algorithm: "SHA256"}
+	if got != want {
+		t.Errorf("ParseTOTP() = %+v, want %+v", got, want)
+	}
+
+	for _, uri := range []string{
+		"otpauth://hotp/ACME?secret=JBSWY3DPEHPK3PXP&counter=1",
+		"otpauth://totp/ACME?secret=not-base32",
+		"otpauth://totp/ACME?secret=JBSWY3DPEHPK3PXP&digits=4",
+		"otpauth://totp/ACME?secret=JBSWY3DPEHPK3PXP&algorithm=MD5",
+	} {
+		if _, err := ParseTOTP(uri); err == nil {
+			t.Errorf("ParseTOTP(%q) succeeded, want an error", uri)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+// Package health checks that traffic flows through a VPN session by probing
+// services behind it.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"openvpn3-tui/internal/config"
+	"openvpn3-tui/internal/network"
+)
+
+// Result is the outcome of one check
+type Result struct {
+	Check   string // Description of the check, e.g. "tcp 10.0.0.1:22"
+	Latency time.Duration
+	Err     error
+}
+
+// Report holds the results of one round of checks
+type Report struct {
+	Time    time.Time
+	Results []Result
+}
+
+// Healthy reports whether every check passed
+func (r Report) Healthy() bool {
+	return len(r.Failed()) == 0
+}
+
+// Failed returns the checks that did not pass
+func (r Report) Failed() []Result {
+	var failed []Result
+	for _, res := range r.Results {
+		if res.Err != nil {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// Latency returns the slowest passing check
+func (r Report) Latency() time.Duration {
+	var slowest time.Duration
+	for _, res := range r.Results {
+		if res.Err == nil {
+			slowest = max(slowest, res.Latency)
+		}
+	}
+	return slowest
+}
+
+// Error describes the first failed check, or returns nil when all passed
+func (r Report) Error() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	err := fmt.Errorf("%s: %w", failed[0].Check, failed[0].Err)
+	if len(failed) > 1 {
+		err = fmt.Errorf("%w (and %d more)", err, len(failed)-1)
+	}
+	return err
+}
+
+// Run runs all checks of h concurrently, each bounded by its timeout
+func Run(h *config.Health) Report {
+	report := Report{Time: time.Now(), Results: make([]Result, len(h.Checks))}
+	var wg sync.WaitGroup
+	for i, c := range h.Checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), h.TimeoutDuration())
+			defer cancel()
+			report.Results[i] = check(ctx, c)
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+// check runs a single check
+func check(ctx context.Context, c config.HealthCheck) Result {
+	res := Result{Check: Describe(c)}
+	start := time.Now()
+	switch {
+	case c.TCP != "":
+		res.Err = checkTCP(ctx, c.TCP)
+	case c.HTTP != "":
+		res.Err = checkHTTP(ctx, c.HTTP, c.Status)
+	case c.DNS != "":
+		res.Err = checkDNS(ctx, c.DNS, c.Server)
+	default:
+		res.Err = errors.New("no tcp, http or dns target")
+	}
+	res.Latency = time.Since(start)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		res.Err = errors.New("timed out")
+	}
+	return res
+}
+
+// checkTCP connects to addr
+func checkTCP(ctx context.Context, addr string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return network.DialError(err)
+	}
+	return conn.Close()
+}
+
+// checkHTTP requests target without following redirects and compares the status
+func checkHTTP(ctx context.Context, target string, want int) error {
+	if want == 0 {
+		want = http.StatusOK
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// The URL is already part of the description
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return network.DialError(urlErr.Err)
+		}
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != want {
+		return fmt.Errorf("status %d, want %d", resp.StatusCode, want)
+	}
+	return nil
+}
+
+// checkDNS looks up name, asking server when one is given
+func checkDNS(ctx context.Context, name, server string) error {
+	resolver := net.DefaultResolver
+	if server != "" {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, server)
+			},
+		}
+	}
+	addrs, err := resolver.LookupHost(ctx, name)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) {
+			return errors.New(dnsErr.Err)
+		}
+		return err
+	}
+	if len(addrs) == 0 {
+		return errors.New("no addresses")
+	}
+	return nil
+}
+
+// Describe names a check, e.g. "tcp 10.0.0.1:22", preferring its own name
+func Describe(c config.HealthCheck) string {
+	switch {
+	case c.Name != "":
+		return c.Name
+	case c.TCP != "":
+		return "tcp " + c.TCP
+	case c.HTTP != "":
+		return "http " + c.HTTP
+	case c.DNS != "" && c.Server != "":
+		return fmt.Sprintf("dns %s @%s", c.DNS, c.Server)
+	case c.DNS != "":
+		return "dns " + c.DNS
+	}
+	return "empty check"
+}
+
+// Check reports profiles with invalid health checks
+func Check(cfg *config.Config) []string {
+	var problems []string
+	for _, p := range cfg.Profiles {
+		if p.Health == nil {
+			continue
+		}
+		if len(p.Health.Checks) == 0 {
+			problems = append(problems, fmt.Sprintf("%s: health has no checks", p.Name))
+		}
+		for _, c := range p.Health.Checks {
+			targets := 0
+			for _, t := range []string{c.TCP, c.HTTP, c.DNS} {
+				if t != "" {
+					targets++
+				}
+			}
+			switch {
+			case targets != 1:
+				problems = append(problems, fmt.Sprintf("%s: health check %s needs exactly one of tcp, http and dns", p.Name, Describe(c)))
+			case c.TCP != "":
+				if _, _, err := net.SplitHostPort(c.TCP); err != nil {
+					problems = append(problems, fmt.Sprintf("%s: health check %s: want host:port", p.Name, Describe(c)))
+				}
+			case c.HTTP != "":
+				if u, err := url.Parse(c.HTTP); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+					problems = append(problems, fmt.Sprintf("%s: health check %s: want an http or https URL", p.Name, Describe(c)))
+				}
+			}
+			if c.Status != 0 && c.HTTP == "" {
+				problems = append(problems, fmt.Sprintf("%s: health check %s: status only applies to http", p.Name, Describe(c)))
+			}
+			if c.Server != "" && c.DNS == "" {
+				problems = append(problems, fmt.Sprintf("%s: health check %s: server only applies to dns", p.Name, Describe(c)))
+			}
+		}
+	}
+	return problems
+}
+
+// Summary describes a report for badges, e.g. "12ms" or "1/3 failed"
+func Summary(r Report) string {
+	if failed := len(r.Failed()); failed > 0 {
+		return fmt.Sprintf("%d/%d failed", failed, len(r.Results))
+	}
+	return FormatLatency(r.Latency())
+}
+
+// FormatLatency rounds a latency for display
+func FormatLatency(d time.Duration) string {
+	switch {
+	case d < time.Millisecond:
+		return d.Round(time.Microsecond).String()
+	case d < 10*time.Millisecond:
+		return d.Round(100 * time.Microsecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
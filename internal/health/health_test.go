@@ -0,0 +1,242 @@
+package health
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"openvpn3-tui/internal/config"
+)
+
+// closedAddr returns the address of a TCP port nothing listens on
+func closedAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+// newHTTPServer serves the paths the HTTP checks are run against
+func newHTTPServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/down", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusFound)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// serveDNS answers A queries for the names in records over UDP, with an
+// empty answer for other types and NXDOMAIN for unknown names
+func serveDNS(t *testing.T, records map[string]net.IP) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			query := buf[:n]
+			// The question follows the 12 byte header as labels, type and class
+			end := 12
+			var labels []string
+			for end < n && query[end] != 0 {
+				size := int(query[end])
+				labels = append(labels, string(query[end+1:end+1+size]))
+				end += 1 + size
+			}
+			end += 5
+			if end > n {
+				continue
+			}
+			qtype := binary.BigEndian.Uint16(query[end-4:])
+			ip, known := records[strings.ToLower(strings.Join(labels, "."))]
+
+			resp := append([]byte(nil), query[:end]...)
+			flags := uint16(0x8180) // Response, recursion desired and available
+			if !known {
+				flags |= 3 // NXDOMAIN
+			}
+			binary.BigEndian.PutUint16(resp[2:], flags)
+			binary.BigEndian.PutUint16(resp[6:], 0)  // Answers
+			binary.BigEndian.PutUint16(resp[8:], 0)  // Authority
+			binary.BigEndian.PutUint16(resp[10:], 0) // Additional
+			if known && qtype == 1 {
+				binary.BigEndian.PutUint16(resp[6:], 1)
+				resp = append(resp, 0xc0, 12, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4)
+				resp = append(resp, ip.To4()...)
+			}
+			conn.WriteTo(resp, from)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestCheckTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	if err := checkTCP(context.Background(), ln.Addr().String()); err != nil {
+		t.Errorf("checkTCP() of a listener = %v", err)
+	}
+	err = checkTCP(context.Background(), closedAddr(t))
+	if err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("checkTCP() of a closed port = %v, want connection refused", err)
+	}
+	if strings.Contains(err.Error(), "127.0.0.1") {
+		t.Errorf("checkTCP() error repeats the address: %v", err)
+	}
+}
+
+func TestCheckHTTP(t *testing.T) {
+	srv := newHTTPServer(t)
+	tests := []struct {
+		path   string
+		status int
+		want   string // Error text, empty when the check passes
+	}{
+		{"/ok", 0, ""},
+		{"/down", 0, "status 503, want 200"},
+		{"/down", http.StatusServiceUnavailable, ""},
+		// Redirects are not followed, e.g. to a captive portal
+		{"/login", 0, "status 302, want 200"},
+		{"/login", http.StatusFound, ""},
+	}
+	for _, tt := range tests {
+		err := checkHTTP(context.Background(), srv.URL+tt.path, tt.status)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("checkHTTP(%s, %d) = %v, want success", tt.path, tt.status, err)
+		case tt.want != "" && (err == nil || err.Error() != tt.want):
+			t.Errorf("checkHTTP(%s, %d) = %v, want %q", tt.path, tt.status, err, tt.want)
+		}
+	}
+
+	err := checkHTTP(context.Background(), "http://"+closedAddr(t)+"/", 0)
+	if err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("checkHTTP() of a closed port = %v, want connection refused", err)
+	}
+}
+
+func TestCheckDNS(t *testing.T) {
+	server := serveDNS(t, map[string]net.IP{"wiki.corp.test": net.ParseIP("10.0.0.7")})
+
+	if err := checkDNS(context.Background(), "wiki.corp.test.", server); err != nil {
+		t.Errorf("checkDNS() of a known name = %v", err)
+	}
+	err := checkDNS(context.Background(), "missing.corp.test.", server)
+	if err == nil || !strings.Contains(err.Error(), "no such host") {
+		t.Errorf("checkDNS() of an unknown name = %v, want no such host", err)
+	}
+}
+
+func TestCheckTimeout(t *testing.T) {
+	srv := newHTTPServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	res := check(ctx, config.HealthCheck{Name: "slow", HTTP: srv.URL + "/slow"})
+	if res.Err == nil || res.Err.Error() != "timed out" {
+		t.Errorf("check() of a slow server = %v, want timed out", res.Err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("check() took %v despite the timeout", elapsed)
+	}
+}
+
+func TestRun(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	srv := newHTTPServer(t)
+	dns := serveDNS(t, map[string]net.IP{"wiki.corp.test": net.ParseIP("10.0.0.7")})
+
+	h := &config.Health{Timeout: 1, Checks: []config.HealthCheck{
+		{TCP: ln.Addr().String()},
+		{Name: "wiki", HTTP: srv.URL + "/ok"},
+		{DNS: "wiki.corp.test.", Server: dns},
+		{Name: "portal", HTTP: srv.URL + "/login"},
+		{Name: "db", TCP: closedAddr(t)},
+	}}
+	report := Run(h)
+
+	if len(report.Results) != len(h.Checks) {
+		t.Fatalf("Run() returned %d results for %d checks", len(report.Results), len(h.Checks))
+	}
+	for i, c := range h.Checks {
+		if report.Results[i].Check != Describe(c) {
+			t.Errorf("result %d is for %q, want %q", i, report.Results[i].Check, Describe(c))
+		}
+	}
+	if report.Healthy() {
+		t.Error("Healthy() = true with failing checks")
+	}
+	if failed := report.Failed(); len(failed) != 2 || failed[0].Check != "portal" || failed[1].Check != "db" {
+		t.Errorf("Failed() = %+v, want portal and db", failed)
+	}
+	if got, want := report.Error().Error(), "portal: status 302, want 200 (and 1 more)"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if got := Summary(report); got != "2/5 failed" {
+		t.Errorf("Summary() = %q, want 2/5 failed", got)
+	}
+
+	healthy := Run(&config.Health{Checks: h.Checks[:3]})
+	if !healthy.Healthy() || healthy.Error() != nil {
+		t.Errorf("Run() of passing checks = %+v", healthy.Results)
+	}
+	if healthy.Latency() <= 0 {
+		t.Errorf("Latency() = %v, want the slowest check", healthy.Latency())
+	}
+}
+
+func TestFormatLatency(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{340 * time.Microsecond, "340µs"},
+		{2345 * time.Microsecond, "2.3ms"},
+		{12345 * time.Microsecond, "12ms"},
+		{1500 * time.Millisecond, "1.5s"},
+	}
+	for _, tt := range tests {
+		if got := FormatLatency(tt.in); got != tt.want {
+			t.Errorf("FormatLatency(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
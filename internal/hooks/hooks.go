@@ -0,0 +1,200 @@
+// Package hooks runs the shell commands profiles configure around connecting
+// and disconnecting, e.g. to mount shares or start SSH tunnels.
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"openvpn3-tui/internal/config"
+)
+
+// Event identifies when a hook runs
+type Event string
+
+const (
+	PreConnect     Event = "pre-connect"
+	PostConnect    Event = "post-connect"
+	PreDisconnect  Event = "pre-disconnect"
+	PostDisconnect Event = "post-disconnect"
+	HealthFailed   Event = "health-failed"
+)
+
+// maxOutput limits how much output of a hook is kept
+const maxOutput = 16 * 1024
+
+// maxLogEntries is how many hook runs the log keeps
+const maxLogEntries = 100
+
+// Session describes the VPN session a hook runs for. Fields are empty when
+// unknown, e.g. the session path before connecting.
+type Session struct {
+	Profile  config.Profile
+	Path     string
+	Device   string
+	TunnelIP string
+	Health   string // Failed health checks, for health-failed hooks
+}
+
+// Result is the outcome of one hook run
+type Result struct {
+	Event    Event
+	Profile  string
+	Command  string
+	Started  time.Time
+	Duration time.Duration
+	Output   string
+	Err      error
+}
+
+// Command returns the hook command of a profile for an event
+func Command(h *config.Hooks, event Event) string {
+	if h == nil {
+		return ""
+	}
+	switch event {
+	case PreConnect:
+		return h.PreConnect
+	case PostConnect:
+		return h.PostConnect
+	case PreDisconnect:
+		return h.PreDisconnect
+	case PostDisconnect:
+		return h.PostDisconnect
+	case HealthFailed:
+		return h.HealthFailed
+	}
+	return ""
+}
+
+// Run runs the hook of the session's profile for event with sh, if one is
+// configured. It returns false when there is nothing to run.
+func Run(event Event, s Session) (Result, bool) {
+	command := Command(s.Profile.Hooks, event)
+	if command == "" {
+		return Result{}, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.Profile.Hooks.TimeoutDuration())
+	defer cancel()
+
+	res := Result{Event: event, Profile: s.Profile.Name, Command: command, Started: time.Now()}
+
+	// Output goes to a file rather than a pipe so that background processes
+	// the hook starts, such as SSH tunnels, do not keep it from finishing
+	out, err := os.CreateTemp("", "openvpn3-tui-hook-*")
+	if err != nil {
+		res.Err = fmt.Errorf("%s hook failed: %w", event, err)
+		return res, true
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(), Env(event, s)...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	// On timeout kill everything the hook started while it was still running
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+
+	err = cmd.Run()
+	res.Duration = time.Since(res.Started)
+	res.Output = readOutput(out)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", s.Profile.Hooks.TimeoutDuration())
+	}
+	if err != nil {
+		res.Err = fmt.Errorf("%s hook failed: %w", event, err)
+	}
+	return res, true
+}
+
+// Env returns the environment variables describing the session
+func Env(event Event, s Session) []string {
+	return []string{
+		"OPENVPN3_TUI_EVENT=" + string(event),
+		"OPENVPN3_TUI_PROFILE=" + s.Profile.Name,
+		"OPENVPN3_TUI_CONFIG=" + s.Profile.Path,
+		"OPENVPN3_TUI_SESSION=" + s.Path,
+		"OPENVPN3_TUI_DEVICE=" + s.Device,
+		"OPENVPN3_TUI_TUNNEL_IP=" + s.TunnelIP,
+		"OPENVPN3_TUI_HEALTH=" + s.Health,
+	}
+}
+
+// TunnelIP returns the address of a tunnel device, preferring IPv4
+func TunnelIP(device string) string {
+	if device == "" {
+		return ""
+	}
+	iface, err := net.InterfaceByName(device)
+	if err != nil {
+		return ""
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return ""
+	}
+
+	var first string
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			return ipNet.IP.String()
+		}
+		if first == "" && !ipNet.IP.IsLinkLocalUnicast() {
+			first = ipNet.IP.String()
+		}
+	}
+	return first
+}
+
+// readOutput returns the first maxOutput bytes a hook wrote
+func readOutput(f *os.File) string {
+	buf := make([]byte, maxOutput+1)
+	n, _ := f.ReadAt(buf, 0)
+	if n > maxOutput {
+		return string(buf[:maxOutput]) + "\n[output truncated]"
+	}
+	return string(buf[:n])
+}
+
+// Log keeps the most recent hook results. It is safe for concurrent use.
+type Log struct {
+	mu      sync.Mutex
+	entries []Result
+}
+
+// Add records a result, dropping the oldest once the log is full
+func (l *Log) Add(r Result) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, r)
+	if len(l.entries) > maxLogEntries {
+		l.entries = l.entries[len(l.entries)-maxLogEntries:]
+	}
+}
+
+// Entries returns the recorded results, newest first
+func (l *Log) Entries() []Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entries := make([]Result, len(l.entries))
+	for i, r := range l.entries {
+		entries[len(entries)-1-i] = r
+	}
+	return entries
+}
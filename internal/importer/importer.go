@@ -0,0 +1,553 @@
+package importer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"openvpn3-tui/internal/bundle"
+	"openvpn3-tui/internal/config"
+	"openvpn3-tui/internal/ovpn"
+)
+
+// maxFileSize limits how much of a single archive member is read
+const maxFileSize = 10 << 20
+
+// genericNames are file names that say nothing about the server, so the
+// remote host is used as the suggested name instead
+var genericNames = map[string]bool{
+	"client": true, "config": true, "profile": true, "vpn": true,
+	"openvpn": true, "default": true, "user": true,
+}
+
+// Candidate is a profile found while scanning an import source
+type Candidate struct {
+	Path      string // Path of the .ovpn file relative to the source root
+	Name      string // Suggested profile name
+	Remote    string // First remote host, if any
+	Duplicate string // Name of an existing profile with the same config
+	Selected  bool
+	Notes     []string // Settings lost when converting a NetworkManager connection
+	Username  string   // Username from a NetworkManager connection
+	generated bool     // The config was generated rather than read from the source
+	entry     config.Profile
+	data      []byte
+}
+
+// Profile returns the config entry for the candidate once installed at path.
+// Candidates from a bundle keep the folder, tags and other settings they were
+// exported with.
+func (c Candidate) Profile(path string) config.Profile {
+	p := c.entry
+	p.Name = c.Name
+	p.Path = path
+	return p
+}
+
+// Source is a directory or archive scanned for profiles
+type Source struct {
+	Path       string
+	Archive    bool // Archives are always copied to the managed directory
+	Candidates []Candidate
+	Bundle     *bundle.Manifest  // Set when the archive is an exported bundle
+	files      map[string][]byte // Archive members by slash separated path
+}
+
+// IsArchive reports whether path names a supported archive
+func IsArchive(path string) bool {
+	lower := strings.ToLower(path)
+	for _, ext := range []string{".zip", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Scan looks for .ovpn files in a directory, recursively, or in a .zip or
+// .tar.gz archive. NetworkManager keyfiles of OpenVPN connections found in a
+// directory, or given directly, are converted to .ovpn configs.
+func Scan(root string) (*Source, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+
+	src := &Source{Path: root}
+	var paths, nmPaths []string
+	switch {
+	case isNMConnection(root) && !info.IsDir():
+		nmPaths = append(nmPaths, ".")
+	case info.IsDir():
+		err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() && p != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if d.IsDir() || (!isProfile(p) && !isNMConnection(p)) {
+				return nil
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			if isProfile(p) {
+				paths = append(paths, filepath.ToSlash(rel))
+			} else {
+				nmPaths = append(nmPaths, filepath.ToSlash(rel))
+			}
+			return nil
+		})
+	case IsArchive(root):
+		src.Archive = true
+		if src.files, err = readArchive(root); err != nil {
+			return nil, err
+		}
+		if manifest, ok := src.files[bundle.ManifestName]; ok {
+			return src, src.readBundle(manifest)
+		}
+		for p := range src.files {
+			if isProfile(p) {
+				paths = append(paths, p)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%s is not a directory, .zip or .tar.gz archive", root)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(paths)
+	for _, p := range paths {
+		data, err := src.readFile(p)
+		if err != nil {
+			return nil, err
+		}
+		c := Candidate{Path: p, Selected: true, data: data}
+		if cfg, err := ovpn.Parse(bytes.NewReader(data)); err == nil {
+			if remotes := cfg.Remotes(); len(remotes) > 0 {
+				c.Remote = remotes[0].Host
+			}
+		}
+		src.Candidates = append(src.Candidates, c)
+	}
+	src.suggestNames()
+
+	sort.Strings(nmPaths)
+	for _, p := range nmPaths {
+		if c, ok, err := src.convertNM(p); err != nil {
+			return nil, err
+		} else if ok {
+			src.Candidates = append(src.Candidates, c)
+		}
+	}
+
+	if len(src.Candidates) == 0 {
+		return nil, fmt.Errorf("no .ovpn files or OpenVPN connections found in %s", root)
+	}
+	return src, nil
+}
+
+// readBundle lists the profiles of an exported bundle with their original
+// names and settings
+func (s *Source) readBundle(data []byte) error {
+	manifest, err := bundle.ParseManifest(data, s.files)
+	if err != nil {
+		return err
+	}
+	s.Bundle = manifest
+
+	for _, p := range manifest.Profiles {
+		c := Candidate{Path: p.Path, Name: p.Name, Selected: true, entry: p, data: s.files[p.Path]}
+		if p.Hooks != nil {
+			// A bundle must not be able to run commands on this machine
+			c.entry.Hooks = nil
+			c.Notes = append(c.Notes, "hooks in the bundle were not imported")
+		}
+		if dropped := dropAutomation(&c.entry); len(dropped) > 0 {
+			c.Notes = append(c.Notes, strings.Join(dropped, ", ")+" settings in the bundle were not imported")
+		}
+		cfg, err := ovpn.Parse(bytes.NewReader(c.data))
+		if err != nil {
+			c.Selected = false
+			c.Notes = append(c.Notes, err.Error())
+			s.Candidates = append(s.Candidates, c)
+			continue
+		}
+		if remotes := cfg.Remotes(); len(remotes) > 0 {
+			c.Remote = remotes[0].Host
+		}
+		for _, f := range ovpn.Lint(cfg) {
+			if f.Severity == ovpn.SeverityError {
+				c.Notes = append(c.Notes, f.Message)
+			}
+		}
+		if manifest.Redacted && cfg.Has("cert") && !cfg.Has("key") {
+			c.Notes = append(c.Notes, "private key was removed on export, add your own before connecting")
+		}
+		s.Candidates = append(s.Candidates, c)
+	}
+	return nil
+}
+
+// dropAutomation clears the settings of a bundled profile that connect it or
+// block traffic without being asked, and returns their names. What suits the
+// exporting machine may not suit this one.
+func dropAutomation(p *config.Profile) []string {
+	var dropped []string
+	if p.ConnectOnStart {
+		p.ConnectOnStart = false
+		dropped = append(dropped, "autoconnect")
+	}
+	if len(p.Schedule) > 0 {
+		p.Schedule = nil
+		dropped = append(dropped, "schedule")
+	}
+	if p.KillSwitch != nil {
+		p.KillSwitch = nil
+		dropped = append(dropped, "kill switch")
+	}
+	return dropped
+}
+
+// convertNM turns a NetworkManager keyfile into a candidate. Keyfiles of
+// other connection types are skipped; ones that cannot be converted are
+// listed unselected with the reason.
+func (s *Source) convertNM(rel string) (Candidate, bool, error) {
+	conn, err := ParseNMConnection(s.localPath(rel))
+	if err != nil {
+		return Candidate{}, false, err
+	}
+	if !conn.IsOpenVPN() {
+		return Candidate{}, false, nil
+	}
+
+	c := Candidate{Path: filepath.Base(conn.Path), Name: conn.ID(), generated: true}
+	res, err := ConvertNM(conn)
+	if err != nil {
+		c.Notes = []string{err.Error()}
+		return c, true, nil
+	}
+	c.Selected = true
+	c.Notes = res.Skipped
+	c.Username = res.Username
+	c.data = res.Config.Bytes()
+	if remotes := res.Config.Remotes(); len(remotes) > 0 {
+		c.Remote = remotes[0].Host
+	}
+	return c, true, nil
+}
+
+// isProfile reports whether a file name looks like an OpenVPN config
+func isProfile(name string) bool {
+	return strings.EqualFold(path.Ext(name), ".ovpn")
+}
+
+// baseName returns a file name without directory and extension
+func baseName(p string) string {
+	return strings.TrimSuffix(path.Base(p), path.Ext(p))
+}
+
+// suggestNames names candidates after their file, falling back to the remote
+// host when the file name is generic or shared with another candidate
+func (s *Source) suggestNames() {
+	count := make(map[string]int)
+	for _, c := range s.Candidates {
+		count[strings.ToLower(baseName(c.Path))]++
+	}
+
+	for i := range s.Candidates {
+		c := &s.Candidates[i]
+		name := baseName(c.Path)
+		key := strings.ToLower(name)
+		if genericNames[key] || count[key] > 1 {
+			switch {
+			case c.Remote != "":
+				name = c.Remote
+			case path.Dir(c.Path) != ".":
+				name = path.Base(path.Dir(c.Path)) + " " + name
+			}
+		}
+		c.Name = name
+	}
+}
+
+// MarkDuplicates flags candidates whose config is already saved as a profile
+// and deselects them. Remaining names are made unique against the config and
+// each other.
+func (s *Source) MarkDuplicates(cfg *config.Config) {
+	taken := make(map[string]bool)
+	for _, p := range cfg.Profiles {
+		taken[p.Name] = true
+	}
+
+	for i := range s.Candidates {
+		c := &s.Candidates[i]
+		for _, p := range cfg.Profiles {
+			if s.sameProfile(*c, p.Path) {
+				c.Duplicate = p.Name
+				c.Selected = false
+				break
+			}
+		}
+
+		name := c.Name
+		for n := 2; taken[name]; n++ {
+			name = fmt.Sprintf("%s %d", c.Name, n)
+		}
+		if name != c.Name && c.Duplicate == "" {
+			c.Notes = append(c.Notes, fmt.Sprintf("renamed from %s, which is already in use", c.Name))
+		}
+		c.Name = name
+		taken[name] = true
+	}
+}
+
+// ResolveDependencies points the dependencies of selected bundle candidates
+// at the names their profiles are imported under. A dependency on a profile
+// that is already saved keeps using the saved one; dependencies on profiles
+// that are not imported are dropped with a note, which is also returned.
+func (s *Source) ResolveDependencies() []string {
+	var dropped []string
+	names := make(map[string]string) // Name in the bundle to name after import
+	for _, c := range s.Candidates {
+		switch {
+		case c.Duplicate != "":
+			names[c.entry.Name] = c.Duplicate
+		case c.Selected:
+			names[c.entry.Name] = c.Name
+		}
+	}
+
+	for i := range s.Candidates {
+		c := &s.Candidates[i]
+		if !c.Selected || len(c.entry.DependsOn) == 0 {
+			continue
+		}
+		var deps []string
+		for _, dep := range c.entry.DependsOn {
+			if name, ok := names[dep]; ok {
+				deps = append(deps, name)
+			} else {
+				note := fmt.Sprintf("dependency on %s was dropped, it is not imported", dep)
+				c.Notes = append(c.Notes, note)
+				dropped = append(dropped, c.Name+": "+note)
+			}
+		}
+		c.entry.DependsOn = deps
+	}
+	return dropped
+}
+
+// sameProfile reports whether a candidate is the file at path or has the same content
+func (s *Source) sameProfile(c Candidate, profilePath string) bool {
+	if !s.Archive && filepath.Clean(profilePath) == s.localPath(c.Path) {
+		return true
+	}
+	data, err := os.ReadFile(profilePath)
+	return err == nil && bytes.Equal(bytes.TrimSpace(data), bytes.TrimSpace(c.data))
+}
+
+// localPath returns the on-disk path of a file in a directory source
+func (s *Source) localPath(rel string) string {
+	return filepath.Join(s.Path, filepath.FromSlash(rel))
+}
+
+// readFile returns a file of the source by its relative path
+func (s *Source) readFile(rel string) ([]byte, error) {
+	if s.Archive {
+		data, ok := s.files[rel]
+		if !ok {
+			return nil, fmt.Errorf("%s: not found in archive", rel)
+		}
+		return data, nil
+	}
+	return os.ReadFile(s.localPath(rel))
+}
+
+// Selected returns the candidates selected for import
+func (s *Source) Selected() []Candidate {
+	var selected []Candidate
+	for _, c := range s.Candidates {
+		if c.Selected {
+			selected = append(selected, c)
+		}
+	}
+	return selected
+}
+
+// Install makes a candidate available on disk and returns the path of its
+// config. Without copy, profiles from a directory are used in place. Otherwise
+// the config and the files it references by relative path are written to a
+// subdirectory of destDir named after the source.
+func (s *Source) Install(c Candidate, destDir string, copyFiles bool) (string, error) {
+	if c.generated {
+		if c.data == nil {
+			return "", fmt.Errorf("%s could not be converted", c.Path)
+		}
+		p := filepath.Join(destDir, "networkmanager", config.SafeFileName(c.Name)+".ovpn")
+		return p, writeFile(p, c.data)
+	}
+	if !s.Archive && !copyFiles {
+		return s.localPath(c.Path), nil
+	}
+
+	dir := filepath.Join(destDir, sourceName(s.Path))
+	files := []string{c.Path}
+	if cfg, err := ovpn.Parse(bytes.NewReader(c.data)); err == nil {
+		for _, ref := range cfg.ExternalFiles() {
+			arg := ref.Directive.Arg(0)
+			if filepath.IsAbs(arg) || strings.HasPrefix(arg, "~") {
+				continue
+			}
+			files = append(files, path.Join(path.Dir(c.Path), filepath.ToSlash(arg)))
+		}
+	}
+
+	for _, rel := range files {
+		rel = path.Clean(rel)
+		if !fs.ValidPath(rel) {
+			return "", fmt.Errorf("%s: refers to a file outside the import source", c.Path)
+		}
+		data, err := s.readFile(rel)
+		if err != nil {
+			return "", err
+		}
+		if err := writeFile(filepath.Join(dir, filepath.FromSlash(rel)), data); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(dir, filepath.FromSlash(c.Path)), nil
+}
+
+// sourceName derives a directory name from a source path, e.g. acme for acme.tar.gz
+func sourceName(p string) string {
+	name := filepath.Base(filepath.Clean(p))
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".tar.gz", ".tgz", ".zip"} {
+		if strings.HasSuffix(lower, ext) {
+			return name[:len(name)-len(ext)]
+		}
+	}
+	return name
+}
+
+// writeFile writes data with private permissions since profiles may contain
+// keys. An existing file with different content is not overwritten.
+func writeFile(p string, data []byte) error {
+	if existing, err := os.ReadFile(p); err == nil {
+		if bytes.Equal(existing, data) {
+			return nil
+		}
+		return fmt.Errorf("%s already exists", p)
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, 0600)
+}
+
+// readArchive reads the regular files of a .zip or .tar.gz archive
+func readArchive(p string) (map[string][]byte, error) {
+	if strings.HasSuffix(strings.ToLower(p), ".zip") {
+		return readZip(p)
+	}
+	return readTarGz(p)
+}
+
+// readZip reads the regular files of a zip archive
+func readZip(p string) (map[string][]byte, error) {
+	r, err := zip.OpenReader(p)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	files := make(map[string][]byte)
+	for _, f := range r.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := readLimited(rc, f.Name)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		addMember(files, f.Name, data)
+	}
+	return files, nil
+}
+
+// readTarGz reads the regular files of a gzip compressed tar archive
+func readTarGz(p string) (map[string][]byte, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := readLimited(tr, hdr.Name)
+		if err != nil {
+			return nil, err
+		}
+		addMember(files, hdr.Name, data)
+	}
+	return files, nil
+}
+
+// readLimited reads an archive member, refusing unreasonably large files
+func readLimited(r io.Reader, name string) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxFileSize {
+		return nil, fmt.Errorf("%s: file too large", name)
+	}
+	return data, nil
+}
+
+// addMember stores an archive member under a clean relative path, skipping
+// entries that would escape the archive root
+func addMember(files map[string][]byte, name string, data []byte) {
+	name = path.Clean(strings.TrimPrefix(filepath.ToSlash(name), "./"))
+	if fs.ValidPath(name) {
+		files[name] = data
+	}
+}
@@ -0,0 +1,251 @@
+package importer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"openvpn3-tui/internal/bundle"
+	"openvpn3-tui/internal/config"
+)
+
+// TestScanBundle imports an exported bundle: settings that connect the
+// profile or run commands on their own stay behind and are noted
+func TestScanBundle(t *testing.T) {
+	dir := t.TempDir()
+	ovpnPath := filepath.Join(dir, "acme.ovpn")
+	if err := os.WriteFile(ovpnPath, []byte("client\nremote vpn.example.com 1194\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	profiles := []config.Profile{
+		{
+			Name:           "Acme",
+			Path:           ovpnPath,
+			Folder:         "Work",
+			Tags:           []string{"prod"},
+			Hooks:          &config.Hooks{PreConnect: "touch /tmp/pwned"},
+			ConnectOnStart: true,
+			Schedule:       []config.Window{{Start: "02:00", End: "04:00"}},
+			IdleMinutes:    30,
+			KillSwitch:     &config.KillSwitch{Allow: []string{"192.168.1.0/24"}},
+		},
+		{Name: "Plain", Path: ovpnPath},
+	}
+	archive := filepath.Join(dir, "acme.tar.gz")
+	if err := bundle.ExportFile(archive, profiles, true); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := Scan(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.Bundle == nil || len(src.Candidates) != 2 {
+		t.Fatalf("Scan() found bundle %v with %d candidates, want 2", src.Bundle != nil, len(src.Candidates))
+	}
+
+	acme := src.Candidates[0]
+	p := acme.Profile(filepath.Join(dir, "installed.ovpn"))
+	if p.Hooks != nil || p.ConnectOnStart || p.Schedule != nil || p.KillSwitch != nil {
+		t.Errorf("Profile() kept automation: %+v", p)
+	}
+	if p.Folder != "Work" || !slices.Equal(p.Tags, []string{"prod"}) || p.IdleMinutes != 30 {
+		t.Errorf("Profile() lost settings: %+v", p)
+	}
+	wantNotes := []string{"autoconnect, schedule, kill switch settings in the bundle were not imported"}
+	if !slices.Equal(acme.Notes, wantNotes) {
+		t.Errorf("notes = %q, want %q", acme.Notes, wantNotes)
+	}
+	if notes := src.Candidates[1].Notes; len(notes) != 0 {
+		t.Errorf("notes of a plain profile = %q, want none", notes)
+	}
+}
+
+// TestResolveDependencies follows renamed profiles and drops dependencies on
+// profiles left out of the import
+func TestResolveDependencies(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, remote string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte("client\nremote "+remote+" 1194\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	profiles := []config.Profile{
+		{Name: "jump", Path: write("jump.ovpn", "jump.example.com")},
+		{Name: "lab", Path: write("lab.ovpn", "lab.example.com")},
+		{Name: "shared", Path: write("shared.ovpn", "shared.example.com")},
+		{Name: "internal", Path: write("internal.ovpn", "internal.example.com"), DependsOn: []string{"jump", "lab", "shared", "elsewhere"}},
+	}
+	archive := filepath.Join(dir, "bundle.tar.gz")
+	if err := bundle.ExportFile(archive, profiles, false); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := Scan(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An unrelated profile already uses the name jump, and shared is
+	// already saved
+	existing := &config.Config{Profiles: []config.Profile{
+		{Name: "jump", Path: write("other.ovpn", "other.example.com")},
+		{Name: "shared here", Path: profiles[2].Path},
+	}}
+	src.MarkDuplicates(existing)
+	src.Candidates[1].Selected = false // lab
+
+	dropped := src.ResolveDependencies()
+	internal := src.Candidates[3]
+	p := internal.Profile(filepath.Join(dir, "installed.ovpn"))
+	if want := []string{"jump 2", "shared here"}; !slices.Equal(p.DependsOn, want) {
+		t.Errorf("DependsOn = %q, want %q", p.DependsOn, want)
+	}
+	wantDropped := []string{
+		"internal: dependency on lab was dropped, it is not imported",
+		"internal: dependency on elsewhere was dropped, it is not imported",
+	}
+	if !slices.Equal(dropped, wantDropped) {
+		t.Errorf("ResolveDependencies() = %q, want %q", dropped, wantDropped)
+	}
+	if len(internal.Notes) != 2 {
+		t.Errorf("notes = %q, want the two dropped dependencies", internal.Notes)
+	}
+}
+
+// archiveMembers are the files of the test archives, including members and
+// references that try to leave the archive
+var archiveMembers = map[string]string{
+	"../evil.ovpn":        "client\nremote evil.example.com\n",
+	"/etc/evil.ovpn":      "client\nremote evil.example.com\n",
+	"vpn/ca.crt":          "-----BEGIN CERTIFICATE-----\n",
+	"vpn/eu/client.ovpn":  "client\nremote eu.example.com 1194\nca ../ca.crt\n",
+	"vpn/us/client.ovpn":  "client\n",
+	"vpn/office.ovpn":     "client\nremote office.example.com\n",
+	"old/office.ovpn":     "client\n",
+	"vpn/sneaky.ovpn":     "client\nremote sneaky.example.com\nca ../../key\n",
+	"./vpn/homelab.ovpn":  "client\nremote home.example.com\n",
+	"vpn/notes/README.md": "not a profile\n",
+}
+
+// writeZip builds a zip archive of the members in dir
+func writeZip(t *testing.T, dir string, members map[string]string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range members {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(dir, "profiles.zip")
+	if err := os.WriteFile(p, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// writeTarGz builds a gzip compressed tar archive of the members in dir
+func writeTarGz(t *testing.T, dir string, members map[string]string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	w := tar.NewWriter(gz)
+	for name, content := range members {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := w.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(dir, "profiles.tar.gz")
+	if err := os.WriteFile(p, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// TestScanArchive names the profiles of zip and tar.gz archives and installs
+// them without writing outside the destination
+func TestScanArchive(t *testing.T) {
+	for _, format := range []struct {
+		name  string
+		write func(*testing.T, string, map[string]string) string
+	}{
+		{"zip", writeZip},
+		{"tar.gz", writeTarGz},
+	} {
+		t.Run(format.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src, err := Scan(format.write(t, dir, archiveMembers))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Generic and shared file names fall back to the remote host,
+			// then to the directory
+			names := make(map[string]string)
+			for _, c := range src.Candidates {
+				names[c.Path] = c.Name
+			}
+			want := map[string]string{
+				"old/office.ovpn":    "old office",
+				"vpn/eu/client.ovpn": "eu.example.com",
+				"vpn/homelab.ovpn":   "homelab",
+				"vpn/office.ovpn":    "office.example.com",
+				"vpn/sneaky.ovpn":    "sneaky",
+				"vpn/us/client.ovpn": "us client",
+			}
+			if len(names) != len(want) {
+				t.Errorf("candidates = %v, want %v", names, want)
+			}
+			for p, name := range want {
+				if names[p] != name {
+					t.Errorf("%s named %q, want %q", p, names[p], name)
+				}
+			}
+
+			dest := filepath.Join(dir, "dest")
+			for _, c := range src.Candidates {
+				installed, err := src.Install(c, dest, true)
+				if c.Path == "vpn/sneaky.ovpn" {
+					if err == nil {
+						t.Errorf("Install(%s) followed ../../key out of the archive", c.Path)
+					}
+					continue
+				}
+				if err != nil {
+					t.Errorf("Install(%s): %v", c.Path, err)
+				} else if want := filepath.Join(dest, "profiles", filepath.FromSlash(c.Path)); installed != want {
+					t.Errorf("Install(%s) = %s, want %s", c.Path, installed, want)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(dest, "profiles", "vpn", "ca.crt")); err != nil {
+				t.Errorf("referenced ca.crt was not copied: %v", err)
+			}
+			filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+				if err == nil && d.Name() == "evil.ovpn" {
+					t.Errorf("wrote %s", p)
+				}
+				return nil
+			})
+		})
+	}
+}
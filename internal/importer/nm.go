@@ -0,0 +1,303 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"openvpn3-tui/internal/ovpn"
+)
+
+// NMConnectionsDir is where NetworkManager stores system connections
+const NMConnectionsDir = "/etc/NetworkManager/system-connections"
+
+// nmServiceType identifies connections of the NetworkManager openvpn plugin
+const nmServiceType = "org.freedesktop.NetworkManager.openvpn"
+
+// NMConnection is a parsed NetworkManager keyfile
+type NMConnection struct {
+	Path     string
+	Sections map[string]map[string]string
+}
+
+// Get returns the value of key in section
+func (c *NMConnection) Get(section, key string) string {
+	return c.Sections[section][key]
+}
+
+// ID returns the connection name shown by NetworkManager
+func (c *NMConnection) ID() string {
+	if id := c.Get("connection", "id"); id != "" {
+		return id
+	}
+	return strings.TrimSuffix(filepath.Base(c.Path), filepath.Ext(c.Path))
+}
+
+// IsOpenVPN reports whether the connection uses the openvpn plugin
+func (c *NMConnection) IsOpenVPN() bool {
+	return c.Get("connection", "type") == "vpn" && c.Get("vpn", "service-type") == nmServiceType
+}
+
+// isNMConnection reports whether a file name looks like a NetworkManager keyfile
+func isNMConnection(name string) bool {
+	return strings.HasSuffix(name, ".nmconnection")
+}
+
+// ParseNMConnection reads a NetworkManager keyfile
+func ParseNMConnection(path string) (*NMConnection, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseNMConnection(path, data)
+}
+
+// parseNMConnection parses the INI style keyfile format
+func parseNMConnection(path string, data []byte) (*NMConnection, error) {
+	conn := &NMConnection{Path: path, Sections: make(map[string]map[string]string)}
+	section := ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = line[1 : len(line)-1]
+			if conn.Sections[section] == nil {
+				conn.Sections[section] = make(map[string]string)
+			}
+		default:
+			key, value, ok := strings.Cut(line, "=")
+			if !ok || section == "" {
+				return nil, fmt.Errorf("%s:%d: invalid line", path, lineNum)
+			}
+			conn.Sections[section][strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return conn, scanner.Err()
+}
+
+// NMResult is a NetworkManager connection translated to an .ovpn config
+type NMResult struct {
+	Name     string
+	Config   *ovpn.Config
+	Username string   // OpenVPN3 asks for it when connecting
+	Skipped  []string // Settings that have no .ovpn equivalent
+}
+
+// nmHandled are keys of the vpn section that are translated separately or
+// need no translation
+var nmHandled = map[string]bool{
+	"ca": true, "cert": true, "key": true, "ta": true, "tls-crypt": true,
+	"tls-crypt-v2": true, "verify-x509-name": true, "comp-lzo": true,
+	"service-type": true, "connection-type": true, "remote": true, "port": true,
+	"proto-tcp": true, "dev-type": true, "dev": true, "username": true,
+	"password-flags": true, "cert-pass-flags": true, "ta-dir": true,
+	"proxy-type": true, "proxy-server": true, "proxy-port": true,
+	"proxy-retry": true, "http-proxy-username": true,
+	"http-proxy-password-flags": true, "challenge-response-flags": true,
+}
+
+// nmDirect maps keys of the vpn section that translate to a directive taking
+// the value as its single argument
+var nmDirect = map[string]string{
+	"cipher":            "cipher",
+	"data-ciphers":      "data-ciphers",
+	"auth":              "auth",
+	"tls-cipher":        "tls-cipher",
+	"tls-version-min":   "tls-version-min",
+	"tls-version-max":   "tls-version-max",
+	"remote-cert-tls":   "remote-cert-tls",
+	"reneg-seconds":     "reneg-sec",
+	"ping":              "ping",
+	"ping-exit":         "ping-exit",
+	"ping-restart":      "ping-restart",
+	"tun-mtu":           "tun-mtu",
+	"fragment-size":     "fragment",
+	"mtu-disc":          "mtu-disc",
+	"connect-timeout":   "connect-timeout",
+	"max-routes":        "max-routes",
+	"compress":          "compress",
+	"ns-cert-type":      "ns-cert-type",
+	"allow-compression": "allow-compression",
+}
+
+// nmFlags maps yes/no keys of the vpn section to directives without arguments
+var nmFlags = map[string]string{
+	"remote-random": "remote-random",
+	"float":         "float",
+}
+
+// ConvertNM translates an openvpn plugin connection to an .ovpn config
+func ConvertNM(conn *NMConnection) (*NMResult, error) {
+	if !conn.IsOpenVPN() {
+		return nil, fmt.Errorf("%s: not an OpenVPN connection", conn.Path)
+	}
+	vpn := conn.Sections["vpn"]
+	res := &NMResult{Name: conn.ID(), Config: &ovpn.Config{}, Username: vpn["username"]}
+	cfg := res.Config
+	skip := func(format string, args ...any) {
+		res.Skipped = append(res.Skipped, fmt.Sprintf(format, args...))
+	}
+
+	cfg.AddComment(fmt.Sprintf("Imported from NetworkManager connection %q", conn.ID()))
+	cfg.Add("client")
+	devType := vpn["dev-type"]
+	if devType == "" {
+		devType = "tun"
+	}
+	dev := vpn["dev"]
+	if dev == "" {
+		dev = devType
+	}
+	cfg.Add("dev", dev)
+	if !strings.HasPrefix(dev, devType) {
+		// A custom device name does not tell OpenVPN whether it is tun or tap
+		cfg.Add("dev-type", devType)
+	}
+	cfg.Add("nobind")
+
+	// Remotes may carry their own port and protocol, e.g. "a.example:443:tcp, b.example"
+	port, proto := vpn["port"], "udp"
+	if vpn["proto-tcp"] == "yes" {
+		proto = "tcp"
+	}
+	if port == "" {
+		port = "1194"
+	}
+	remotes := strings.FieldsFunc(vpn["remote"], func(r rune) bool { return r == ',' })
+	if len(remotes) == 0 {
+		return nil, fmt.Errorf("%s: connection has no remote", conn.Path)
+	}
+	for _, r := range remotes {
+		host, rport, rproto := parseNMRemote(strings.TrimSpace(r))
+		if rport == "" {
+			rport = port
+		}
+		if rproto == "" {
+			rproto = proto
+		}
+		cfg.Add("remote", host, rport, rproto)
+	}
+
+	switch typ := vpn["connection-type"]; typ {
+	case "", "tls":
+	case "password", "password-tls":
+		cfg.Add("auth-user-pass")
+	default:
+		skip("connection-type %s is not supported by OpenVPN3", typ)
+	}
+
+	for _, name := range []string{"ca", "cert", "key"} {
+		if path := vpn[name]; path != "" {
+			cfg.Add(name, path)
+		}
+	}
+	if ta := vpn["ta"]; ta != "" {
+		cfg.Add("tls-auth", ta)
+		if dir := vpn["ta-dir"]; dir != "" {
+			cfg.Add("key-direction", dir)
+		}
+	}
+	for _, name := range []string{"tls-crypt", "tls-crypt-v2"} {
+		if path := vpn[name]; path != "" {
+			cfg.Add(name, path)
+		}
+	}
+
+	if v := vpn["verify-x509-name"]; v != "" {
+		// NetworkManager stores "type:name", OpenVPN expects "name type"
+		if typ, name, ok := strings.Cut(v, ":"); ok {
+			cfg.Add("verify-x509-name", name, typ)
+		} else {
+			cfg.Add("verify-x509-name", v)
+		}
+	}
+	if v := vpn["comp-lzo"]; v != "" && v != "no-by-default" {
+		cfg.Add("comp-lzo", v)
+	}
+
+	switch vpn["proxy-type"] {
+	case "":
+	case "http":
+		cfg.Add("http-proxy", vpn["proxy-server"], vpn["proxy-port"])
+	case "socks":
+		cfg.Add("socks-proxy", vpn["proxy-server"], vpn["proxy-port"])
+	default:
+		skip("proxy-type %s", vpn["proxy-type"])
+	}
+
+	for _, key := range sortedKeys(vpn) {
+		value := vpn[key]
+		switch {
+		case nmHandled[key]:
+		case nmDirect[key] != "":
+			cfg.Add(nmDirect[key], value)
+		case nmFlags[key] != "":
+			if value == "yes" {
+				cfg.Add(nmFlags[key])
+			}
+		case key == "mssfix":
+			// Either yes/no or a size in bytes
+			switch value {
+			case "yes":
+				cfg.Add("mssfix")
+			case "no":
+			default:
+				cfg.Add("mssfix", value)
+			}
+		default:
+			skip("vpn.%s=%s", key, value)
+		}
+	}
+
+	if secrets := conn.Sections["vpn-secrets"]; len(secrets) > 0 {
+		skip("stored secrets (%s) are not copied", strings.Join(sortedKeys(secrets), ", "))
+	}
+
+	// Routing and DNS are pushed by the server or configured by openvpn3
+	for _, section := range []string{"ipv4", "ipv6"} {
+		for _, key := range sortedKeys(conn.Sections[section]) {
+			value := conn.Sections[section][key]
+			if key == "method" && (value == "auto" || value == "ignore" || value == "disabled") {
+				continue
+			}
+			skip("%s.%s=%s", section, key, value)
+		}
+	}
+
+	return res, nil
+}
+
+// parseNMRemote splits a remote entry of the form host, host:port,
+// host:port:proto or "host port proto"
+func parseNMRemote(r string) (host, port, proto string) {
+	fields := strings.Fields(r)
+	if len(fields) == 1 && strings.Count(r, ":") <= 2 {
+		fields = strings.Split(r, ":")
+	}
+	host = fields[0]
+	if len(fields) > 1 {
+		port = fields[1]
+	}
+	if len(fields) > 2 {
+		proto = strings.TrimSuffix(strings.ToLower(fields[2]), "-client")
+	}
+	return host, port, proto
+}
+
+// sortedKeys returns the keys of a section in alphabetical order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,171 @@
+package importer
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the expected configs in testdata/nm-ovpn")
+
+// nmTests are the fixtures in testdata/nm with what converting them yields
+var nmTests = []struct {
+	file     string
+	name     string
+	username string
+	notes    []string
+}{
+	{
+		file:     "password-tls",
+		name:     "Acme Password TLS",
+		username: "bob",
+		notes:    []string{"ipv4.never-default=true"},
+	},
+	{
+		file:     "password",
+		name:     "Acme Password",
+		username: "alice",
+		notes:    []string{"stored secrets (password) are not copied"},
+	},
+	{
+		file: "static-key",
+		name: "Legacy Static",
+		notes: []string{
+			"connection-type static-key is not supported by OpenVPN3",
+			"vpn.local-ip=10.8.0.2",
+			"vpn.remote-ip=10.8.0.1",
+			"vpn.static-key=/etc/openvpn/legacy/static.key",
+			"vpn.static-key-direction=0",
+		},
+	},
+	{
+		file:  "tls",
+		name:  "Acme TLS",
+		notes: []string{"vpn.keysize=256", "ipv4.dns=10.0.0.53;"},
+	},
+}
+
+// golden compares a generated config with its expected text
+func golden(t *testing.T, file string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", "nm-ovpn", file+".ovpn")
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s converted to\n%s\nwant\n%s", file, got, want)
+	}
+}
+
+func TestConvertNM(t *testing.T) {
+	for _, tt := range nmTests {
+		t.Run(tt.file, func(t *testing.T) {
+			conn, err := ParseNMConnection(filepath.Join("testdata", "nm", tt.file+".nmconnection"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !conn.IsOpenVPN() {
+				t.Fatal("IsOpenVPN() = false")
+			}
+			res, err := ConvertNM(conn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Name != tt.name {
+				t.Errorf("Name = %q, want %q", res.Name, tt.name)
+			}
+			if res.Username != tt.username {
+				t.Errorf("Username = %q, want %q", res.Username, tt.username)
+			}
+			if !slices.Equal(res.Skipped, tt.notes) {
+				t.Errorf("Skipped = %q, want %q", res.Skipped, tt.notes)
+			}
+			golden(t, tt.file, res.Config.Bytes())
+		})
+	}
+}
+
+func TestParseNMRemote(t *testing.T) {
+	tests := []struct {
+		in                string
+		host, port, proto string
+	}{
+		{"vpn.example.com", "vpn.example.com", "", ""},
+		{"vpn.example.com:443", "vpn.example.com", "443", ""},
+		{"vpn.example.com:443:tcp", "vpn.example.com", "443", "tcp"},
+		{"vpn.example.com 443 TCP-client", "vpn.example.com", "443", "tcp"},
+		{"2001:db8::1", "2001:db8::1", "", ""},
+	}
+	for _, tt := range tests {
+		host, port, proto := parseNMRemote(tt.in)
+		if host != tt.host || port != tt.port || proto != tt.proto {
+			t.Errorf("parseNMRemote(%q) = %q, %q, %q, want %q, %q, %q", tt.in, host, port, proto, tt.host, tt.port, tt.proto)
+		}
+	}
+}
+
+func TestParseNMConnectionInvalid(t *testing.T) {
+	if _, err := parseNMConnection("bad.nmconnection", []byte("id=outside a section\n")); err == nil {
+		t.Error("parseNMConnection() accepted a key outside a section")
+	}
+}
+
+// TestScanNM imports the fixture directory the way "import-nm" does: the
+// notes are what a dry run prints, and installing writes the converted configs
+func TestScanNM(t *testing.T) {
+	src, err := Scan(filepath.Join("testdata", "nm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The ethernet connection is not offered
+	if len(src.Candidates) != len(nmTests) {
+		t.Fatalf("found %d candidates, want %d", len(src.Candidates), len(nmTests))
+	}
+
+	dest := t.TempDir()
+	for i, tt := range nmTests {
+		c := src.Candidates[i]
+		if c.Path != tt.file+".nmconnection" || c.Name != tt.name {
+			t.Errorf("candidate %d = %s (%s), want %s.nmconnection (%s)", i, c.Path, c.Name, tt.file, tt.name)
+			continue
+		}
+		if !c.Selected {
+			t.Errorf("%s is not selected", c.Name)
+		}
+		if !slices.Equal(c.Notes, tt.notes) {
+			t.Errorf("%s notes = %q, want %q", c.Name, c.Notes, tt.notes)
+		}
+		if c.Username != tt.username {
+			t.Errorf("%s username = %q, want %q", c.Name, c.Username, tt.username)
+		}
+
+		path, err := src.Install(c, dest, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(path, filepath.Join(dest, "networkmanager")+string(filepath.Separator)) {
+			t.Errorf("%s installed at %s, want inside %s/networkmanager", c.Name, path, dest)
+		}
+		p := c.Profile(path)
+		if p.Name != tt.name || p.Path != path {
+			t.Errorf("Profile() = %+v, want name %q and path %s", p, tt.name, path)
+		}
+		if p.Hooks != nil || p.ConnectOnStart || len(p.Schedule) > 0 || p.KillSwitch != nil {
+			t.Errorf("Profile() of %s carries settings: %+v", c.Name, p)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		golden(t, tt.file, data)
+	}
+}
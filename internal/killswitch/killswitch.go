@@ -0,0 +1,219 @@
+// Package killswitch generates nftables rules that block traffic outside the
+// tunnel while a profile is connected.
+package killswitch
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+
+	"openvpn3-tui/internal/config"
+	"openvpn3-tui/internal/ovpn"
+	"openvpn3-tui/internal/probe"
+)
+
+// Table is the nftables table holding the rules of every active kill switch
+const Table = "openvpn3_tui_killswitch"
+
+// Endpoint is a VPN server traffic may still reach outside the tunnel
+type Endpoint struct {
+	Addr  netip.Addr
+	Proto string // "udp" or "tcp"
+	Port  uint16
+}
+
+// Guard is what the kill switch of one profile lets through
+type Guard struct {
+	Profile string
+	Device  string // Tunnel device, empty until connected
+	Servers []Endpoint
+	Allow   []netip.Prefix // LAN exceptions
+}
+
+// ForProfile builds the guard of a connected profile, resolving its servers
+func ForProfile(ctx context.Context, profile config.Profile, device string) (Guard, error) {
+	g := Guard{Profile: profile.Name, Device: device}
+	if profile.KillSwitch != nil {
+		allow, err := ParseAllow(profile.KillSwitch.Allow)
+		if err != nil {
+			return g, err
+		}
+		g.Allow = allow
+	}
+	cfg, err := ovpn.ParseFile(profile.Path)
+	if err != nil {
+		return g, err
+	}
+	if g.Servers, err = Endpoints(ctx, cfg); err != nil {
+		return g, err
+	}
+	return g, nil
+}
+
+// Endpoints resolves the remote servers of a profile. Servers that do not
+// resolve are left out unless none does.
+func Endpoints(ctx context.Context, cfg *ovpn.Config) ([]Endpoint, error) {
+	var endpoints []Endpoint
+	var lastErr error
+	for _, r := range cfg.Remotes() {
+		port, err := strconv.ParseUint(r.Port, 10, 16)
+		if err != nil {
+			lastErr = fmt.Errorf("remote %s: invalid port", r)
+			continue
+		}
+		proto := "udp"
+		if strings.HasPrefix(probe.Network(r.Proto), "tcp") {
+			proto = "tcp"
+		}
+		addrs, err := lookup(ctx, r.Host)
+		if err != nil {
+			lastErr = fmt.Errorf("remote %s: %w", r, err)
+			continue
+		}
+		for _, addr := range addrs {
+			endpoints = append(endpoints, Endpoint{Addr: addr.Unmap(), Proto: proto, Port: uint16(port)})
+		}
+	}
+	if len(endpoints) == 0 {
+		if lastErr == nil {
+			lastErr = fmt.Errorf("profile lists no remote servers")
+		}
+		return nil, lastErr
+	}
+	return endpoints, nil
+}
+
+// lookup returns the addresses of a host, which may already be an address
+func lookup(ctx context.Context, host string) ([]netip.Addr, error) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{addr}, nil
+	}
+	return net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+}
+
+// ParseAllow parses LAN exceptions, which are prefixes or single addresses
+func ParseAllow(allow []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, s := range allow {
+		if addr, err := netip.ParseAddr(s); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("kill switch: invalid allow entry %q", s)
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return prefixes, nil
+}
+
+// Check returns a description of every invalid kill switch setting
+func Check(cfg *config.Config) []string {
+	var problems []string
+	for _, p := range cfg.Profiles {
+		if p.KillSwitch == nil {
+			continue
+		}
+		if _, err := ParseAllow(p.KillSwitch.Allow); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", p.Name, err))
+		}
+	}
+	return problems
+}
+
+// Ruleset returns the nft script replacing the table with the rules of
+// guards. Without guards the script only removes the table.
+//
+// Outgoing traffic may use loopback, the tunnel devices, the VPN servers and
+// the LAN exceptions. Incoming traffic is limited to replies and the same
+// devices and networks. DHCP and IPv6 neighbor discovery stay open so the
+// machine keeps its LAN address and can reach its gateway.
+func Ruleset(guards []Guard) string {
+	var b strings.Builder
+	// Declaring the table first makes deleting it safe when it does not exist
+	fmt.Fprintf(&b, "table inet %s\ndelete table inet %s\n", Table, Table)
+	if len(guards) == 0 {
+		return b.String()
+	}
+
+	var devices []string
+	var servers []Endpoint
+	var allow []netip.Prefix
+	for _, g := range guards {
+		if g.Device != "" && !slices.Contains(devices, g.Device) {
+			devices = append(devices, g.Device)
+		}
+		for _, s := range g.Servers {
+			if !slices.Contains(servers, s) {
+				servers = append(servers, s)
+			}
+		}
+		for _, p := range g.Allow {
+			if !slices.Contains(allow, p) {
+				allow = append(allow, p)
+			}
+		}
+	}
+	slices.Sort(devices)
+	slices.SortFunc(servers, func(a, b Endpoint) int {
+		if c := a.Addr.Compare(b.Addr); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.Proto, b.Proto); c != 0 {
+			return c
+		}
+		return int(a.Port) - int(b.Port)
+	})
+	slices.SortFunc(allow, func(a, b netip.Prefix) int {
+		if c := a.Addr().Compare(b.Addr()); c != 0 {
+			return c
+		}
+		return a.Bits() - b.Bits()
+	})
+
+	fmt.Fprintf(&b, "table inet %s {\n", Table)
+	b.WriteString("\tchain output {\n")
+	b.WriteString("\t\ttype filter hook output priority filter; policy drop;\n")
+	b.WriteString("\t\toifname \"lo\" accept\n")
+	for _, d := range devices {
+		fmt.Fprintf(&b, "\t\toifname %q accept\n", d)
+	}
+	for _, s := range servers {
+		fmt.Fprintf(&b, "\t\t%s daddr %s %s dport %d accept\n", family(s.Addr), s.Addr, s.Proto, s.Port)
+	}
+	for _, p := range allow {
+		fmt.Fprintf(&b, "\t\t%s daddr %s accept\n", family(p.Addr()), p)
+	}
+	b.WriteString("\t\tudp sport 68 udp dport 67 accept\n")
+	b.WriteString("\t\ticmpv6 type { nd-router-solicit, nd-neighbor-solicit, nd-neighbor-advert } accept\n")
+	b.WriteString("\t}\n")
+
+	b.WriteString("\tchain input {\n")
+	b.WriteString("\t\ttype filter hook input priority filter; policy drop;\n")
+	b.WriteString("\t\tiifname \"lo\" accept\n")
+	b.WriteString("\t\tct state established,related accept\n")
+	for _, d := range devices {
+		fmt.Fprintf(&b, "\t\tiifname %q accept\n", d)
+	}
+	for _, p := range allow {
+		fmt.Fprintf(&b, "\t\t%s saddr %s accept\n", family(p.Addr()), p)
+	}
+	b.WriteString("\t\tudp sport 67 udp dport 68 accept\n")
+	b.WriteString("\t\ticmpv6 type { nd-router-advert, nd-neighbor-solicit, nd-neighbor-advert } accept\n")
+	b.WriteString("\t}\n")
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// family returns the nftables address family keyword of addr
+func family(addr netip.Addr) string {
+	if addr.Is4() {
+		return "ip"
+	}
+	return "ip6"
+}
@@ -0,0 +1,123 @@
+package killswitch
+
+import (
+	"flag"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden rulesets in testdata")
+
+func endpoint(addr, proto string, port uint16) Endpoint {
+	return Endpoint{Addr: netip.MustParseAddr(addr), Proto: proto, Port: port}
+}
+
+func TestRuleset(t *testing.T) {
+	tests := []struct {
+		name   string
+		guards []Guard
+	}{
+		{
+			name: "no_guards",
+		},
+		{
+			name: "one_guard",
+			guards: []Guard{{
+				Profile: "Acme",
+				Device:  "tun0",
+				Servers: []Endpoint{endpoint("198.51.100.7", "udp", 1194)},
+			}},
+		},
+		{
+			name: "shared_device",
+			guards: []Guard{
+				{
+					Profile: "Acme EU",
+					Device:  "tun0",
+					Servers: []Endpoint{endpoint("198.51.100.8", "udp", 1194), endpoint("198.51.100.7", "tcp", 443)},
+				},
+				{
+					Profile: "Acme US",
+					Device:  "tun0",
+					Servers: []Endpoint{endpoint("198.51.100.7", "tcp", 443), endpoint("203.0.113.1", "udp", 1194)},
+				},
+			},
+		},
+		{
+			name: "ipv6_servers",
+			guards: []Guard{{
+				Profile: "Acme",
+				Device:  "tun1",
+				Servers: []Endpoint{endpoint("2001:db8::7", "udp", 1194), endpoint("198.51.100.7", "udp", 1194)},
+			}},
+		},
+		{
+			name: "lan_exceptions",
+			guards: []Guard{
+				{
+					Profile: "Acme",
+					Device:  "tun0",
+					Servers: []Endpoint{endpoint("198.51.100.7", "udp", 1194)},
+					Allow:   []netip.Prefix{netip.MustParsePrefix("192.168.1.0/24"), netip.MustParsePrefix("fd00::/64")},
+				},
+				{
+					Profile: "Globex",
+					Device:  "tun1",
+					Servers: []Endpoint{endpoint("203.0.113.1", "tcp", 443)},
+					Allow:   []netip.Prefix{netip.MustParsePrefix("10.0.0.5/32"), netip.MustParsePrefix("192.168.1.0/24")},
+				},
+			},
+		},
+		{
+			name: "connecting",
+			guards: []Guard{{
+				Profile: "Acme",
+				Servers: []Endpoint{endpoint("198.51.100.7", "udp", 1194)},
+				Allow:   []netip.Prefix{netip.MustParsePrefix("192.168.1.0/24")},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Ruleset(tt.guards)
+			golden := filepath.Join("testdata", tt.name+".nft")
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("Ruleset() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestParseAllow(t *testing.T) {
+	got, err := ParseAllow([]string{"192.168.1.7/24", "10.0.0.5", "fd00::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []netip.Prefix{
+		netip.MustParsePrefix("192.168.1.0/24"),
+		netip.MustParsePrefix("10.0.0.5/32"),
+		netip.MustParsePrefix("fd00::1/128"),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ParseAllow() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ParseAllow()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if _, err := ParseAllow([]string{"192.168.1.0/33"}); err == nil {
+		t.Error("ParseAllow() accepted an invalid prefix")
+	}
+}
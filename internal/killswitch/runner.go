@@ -0,0 +1,45 @@
+package killswitch
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Runner applies rulesets with root privileges
+type Runner interface {
+	Apply(ruleset string) error
+}
+
+// commandRunner pipes rulesets to "nft -f -" behind a privilege command
+type commandRunner struct {
+	argv []string
+}
+
+// NewRunner returns a runner that runs nft through command, e.g. "pkexec" or
+// "sudo -n". An empty command means pkexec and "none" runs nft directly.
+func NewRunner(command string) Runner {
+	var argv []string
+	switch command = strings.TrimSpace(command); command {
+	case "":
+		argv = []string{"pkexec"}
+	case "none":
+	default:
+		argv = strings.Fields(command)
+	}
+	return commandRunner{argv: append(argv, "nft", "-f", "-")}
+}
+
+// Apply runs the ruleset
+func (r commandRunner) Apply(ruleset string) error {
+	cmd := exec.Command(r.argv[0], r.argv[1:]...)
+	cmd.Stdin = strings.NewReader(ruleset)
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if msg := strings.TrimSpace(string(out)); msg != "" {
+		return fmt.Errorf("%s: %w: %s", strings.Join(r.argv, " "), err, msg)
+	}
+	return fmt.Errorf("%s: %w", strings.Join(r.argv, " "), err)
+}
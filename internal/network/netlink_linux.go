@@ -0,0 +1,53 @@
+package network
+
+import "syscall"
+
+// Multicast groups of routing netlink, which the syscall package lacks
+const (
+	rtmgrpLink       = 0x1
+	rtmgrpIPv4IfAddr = 0x10
+	rtmgrpIPv4Route  = 0x40
+	rtmgrpIPv6IfAddr = 0x100
+	rtmgrpIPv6Route  = 0x400
+)
+
+// watchNetlink signals events for every link, address or route change until
+// the netlink socket fails
+func watchNetlink(events chan struct{}) error {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	addr := &syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+		Groups: rtmgrpLink | rtmgrpIPv4IfAddr | rtmgrpIPv4Route | rtmgrpIPv6IfAddr | rtmgrpIPv6Route,
+	}
+	if err := syscall.Bind(fd, addr); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 1<<16)
+	for {
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			continue
+		}
+		for _, msg := range msgs {
+			switch msg.Header.Type {
+			case syscall.RTM_NEWLINK, syscall.RTM_DELLINK,
+				syscall.RTM_NEWADDR, syscall.RTM_DELADDR,
+				syscall.RTM_NEWROUTE, syscall.RTM_DELROUTE:
+				notify(events)
+			}
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package network
+
+import "errors"
+
+// watchNetlink is only available on Linux, elsewhere changes are polled
+func watchNetlink(events chan struct{}) error {
+	return errors.New("netlink is not supported on this platform")
+}
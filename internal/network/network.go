@@ -0,0 +1,374 @@
+// Package network describes the network the machine is attached to and
+// reports when it changes, so that profiles can be connected depending on
+// where the machine is.
+package network
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// State is what the rules know about the current network. Tunnel interfaces
+// are left out so that a VPN coming up does not look like a new network.
+type State struct {
+	Interface  string         // Interface of the default route
+	Gateway    string         // Default gateway address
+	GatewayMAC string         // Hardware address of the gateway, if known
+	SSID       string         // Connected Wi-Fi network
+	Addresses  []netip.Prefix // Addresses of the physical interfaces
+}
+
+// Online reports whether there is a default route outside of any VPN
+func (s State) Online() bool {
+	return s.Gateway != ""
+}
+
+// Key identifies the network, changing whenever the machine moves to another
+// one. The gateway's hardware address is left out: it may only show up once
+// the neighbour table has it, which is not a move.
+func (s State) Key() string {
+	if !s.Online() {
+		return ""
+	}
+	return strings.Join([]string{s.Interface, s.Gateway, s.SSID}, "|")
+}
+
+// String describes the network for status messages
+func (s State) String() string {
+	if !s.Online() {
+		return "offline"
+	}
+	desc := fmt.Sprintf("%s via %s", s.Interface, s.Gateway)
+	if s.SSID != "" {
+		desc = fmt.Sprintf("Wi-Fi %q (%s)", s.SSID, desc)
+	}
+	return desc
+}
+
+// Source provides the network state and signals when it may have changed
+type Source interface {
+	State() (State, error)
+	// Changes delivers a value after the network changed. Bursts of changes
+	// are coalesced into a single value.
+	Changes() <-chan struct{}
+}
+
+// SSIDProvider returns the connected Wi-Fi network, or "" when there is none
+type SSIDProvider func() (string, error)
+
+// CommandSSID runs a shell command and uses its output as the SSID
+func CommandSSID(command string) SSIDProvider {
+	return func() (string, error) {
+		out, err := exec.Command("sh", "-c", command).Output()
+		if err != nil {
+			return "", fmt.Errorf("ssid command: %w", err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+}
+
+// DetectSSID asks iwgetid, falling back to NetworkManager. A machine with
+// neither tool is treated as having no Wi-Fi.
+func DetectSSID() (string, error) {
+	if _, err := exec.LookPath("iwgetid"); err == nil {
+		// iwgetid exits with an error when not associated
+		out, err := exec.Command("iwgetid", "-r").Output()
+		if err == nil {
+			return strings.TrimSpace(string(out)), nil
+		}
+	}
+	if _, err := exec.LookPath("nmcli"); err == nil {
+		out, err := exec.Command("nmcli", "-t", "-f", "active,ssid", "dev", "wifi").Output()
+		if err != nil {
+			return "", fmt.Errorf("nmcli: %w", err)
+		}
+		return parseNMCLIWifi(string(out)), nil
+	}
+	return "", nil
+}
+
+// parseNMCLIWifi picks the active network from terse nmcli output, where
+// colons in the SSID are escaped as "\:"
+func parseNMCLIWifi(out string) string {
+	for _, line := range strings.Split(out, "\n") {
+		if ssid, ok := strings.CutPrefix(line, "yes:"); ok {
+			return strings.ReplaceAll(ssid, `\:`, ":")
+		}
+	}
+	return ""
+}
+
+// tunnelPrefixes name interfaces created by VPNs
+var tunnelPrefixes = []string{"tun", "tap", "wg", "ppp"}
+
+// IsTunnel reports whether an interface belongs to a VPN
+func IsTunnel(name string) bool {
+	for _, prefix := range tunnelPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// settleDelay lets a burst of changes, e.g. while DHCP configures a new
+// link, finish before the state is read
+const settleDelay = 2 * time.Second
+
+// pollInterval re-reads the state regularly, since roaming between Wi-Fi
+// networks does not always change an address or route
+const pollInterval = 30 * time.Second
+
+// System reads the state of this machine and watches routing netlink events
+type System struct {
+	ssid    SSIDProvider
+	once    sync.Once
+	changes chan struct{}
+}
+
+// NewSystem creates a source for this machine. A nil ssid uses DetectSSID.
+func NewSystem(ssid SSIDProvider) *System {
+	if ssid == nil {
+		ssid = DetectSSID
+	}
+	return &System{ssid: ssid, changes: make(chan struct{}, 1)}
+}
+
+// State reads the default route, gateway and addresses of this machine
+func (s *System) State() (State, error) {
+	var st State
+	iface, gw, err := defaultRoute()
+	if err != nil {
+		return st, err
+	}
+	if gw.IsValid() {
+		st.Interface = iface
+		st.Gateway = gw.String()
+		st.GatewayMAC = neighbour(gw)
+	}
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return st, err
+	}
+	for _, ifi := range ifaces {
+		if ifi.Flags&net.FlagLoopback != 0 || ifi.Flags&net.FlagUp == 0 || IsTunnel(ifi.Name) {
+			continue
+		}
+		addrs, err := ifi.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if p, err := netip.ParsePrefix(a.String()); err == nil {
+				st.Addresses = append(st.Addresses, p)
+			}
+		}
+	}
+
+	if st.SSID, err = s.ssid(); err != nil {
+		return st, err
+	}
+	return st, nil
+}
+
+// Changes starts watching on first use
+func (s *System) Changes() <-chan struct{} {
+	s.once.Do(func() {
+		events := make(chan struct{}, 1)
+		go func() {
+			// Without netlink the regular poll still notices changes
+			_ = watchNetlink(events)
+		}()
+		go s.coalesce(events)
+	})
+	return s.changes
+}
+
+// coalesce forwards events once they settle, and polls in between
+func (s *System) coalesce(events <-chan struct{}) {
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+	for {
+		select {
+		case <-events:
+			settle := time.After(settleDelay)
+		drain:
+			for {
+				select {
+				case <-events:
+				case <-settle:
+					break drain
+				}
+			}
+		case <-poll.C:
+		}
+		notify(s.changes)
+	}
+}
+
+// notify signals ch without blocking when a signal is already pending
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// Route is an entry of the IPv4 routing table
+type Route struct {
+	Device  string
+	Prefix  netip.Prefix // 0.0.0.0/0 for a default route
+	Gateway netip.Addr   // Unspecified for directly connected networks
+	Metric  int
+}
+
+// ReadRoutes reads the IPv4 routing table of this machine
+func ReadRoutes() ([]Route, error) {
+	f, err := os.Open("/proc/net/route")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseRoutes(f)
+}
+
+// ParseRoutes reads the /proc/net/route table, whose addresses are
+// little-endian hex. Malformed entries are skipped.
+func ParseRoutes(r io.Reader) ([]Route, error) {
+	var routes []Route
+	scanner := bufio.NewScanner(r)
+	scanner.Scan() // Header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 {
+			continue
+		}
+		dest, ok1 := hexAddr4(fields[1])
+		gw, ok2 := hexAddr4(fields[2])
+		mask, ok3 := hexAddr4(fields[7])
+		metric, err := strconv.Atoi(fields[6])
+		if !ok1 || !ok2 || !ok3 || err != nil {
+			continue
+		}
+		ones, bits := net.IPMask(mask.AsSlice()).Size()
+		if bits == 0 {
+			continue
+		}
+		prefix, err := dest.Prefix(ones)
+		if err != nil {
+			continue
+		}
+		routes = append(routes, Route{Device: fields[0], Prefix: prefix, Gateway: gw, Metric: metric})
+	}
+	return routes, scanner.Err()
+}
+
+// hexAddr4 decodes a little-endian hex IPv4 address
+func hexAddr4(s string) (netip.Addr, bool) {
+	raw, err := hex.DecodeString(s)
+	if err != nil || len(raw) != 4 {
+		return netip.Addr{}, false
+	}
+	var ip [4]byte
+	binary.BigEndian.PutUint32(ip[:], binary.LittleEndian.Uint32(raw))
+	return netip.AddrFrom4(ip), true
+}
+
+// defaultRoute returns the lowest metric IPv4 default route outside of any VPN
+func defaultRoute() (string, netip.Addr, error) {
+	routes, err := ReadRoutes()
+	if err != nil {
+		return "", netip.Addr{}, err
+	}
+	iface, gw := pickDefault(routes)
+	return iface, gw, nil
+}
+
+// pickDefault returns the interface and gateway of the lowest metric default
+// route outside of any VPN
+func pickDefault(routes []Route) (string, netip.Addr) {
+	var best *Route
+	for i, r := range routes {
+		if r.Prefix.Bits() != 0 || IsTunnel(r.Device) {
+			continue
+		}
+		if best == nil || r.Metric < best.Metric {
+			best = &routes[i]
+		}
+	}
+	if best == nil {
+		return "", netip.Addr{}
+	}
+	return best.Device, best.Gateway
+}
+
+// DialError drops the addresses from a network error, for messages that
+// already name the remote, e.g. "connection refused" instead of
+// "dial tcp 10.0.0.1:22: connect: connection refused"
+func DialError(err error) error {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return opErr.Err
+	}
+	return err
+}
+
+// neighbour looks up the hardware address of gw in the ARP table
+func neighbour(gw netip.Addr) string {
+	data, err := os.ReadFile("/proc/net/arp")
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 4 && fields[0] == gw.String() && fields[3] != "00:00:00:00:00:00" {
+			return fields[3]
+		}
+	}
+	return ""
+}
+
+// Static is a source whose state is set by hand, for dry runs and tests
+type Static struct {
+	mu      sync.Mutex
+	state   State
+	changes chan struct{}
+}
+
+// NewStatic creates a source reporting st
+func NewStatic(st State) *Static {
+	return &Static{state: st, changes: make(chan struct{}, 1)}
+}
+
+// State returns the state last set
+func (s *Static) State() (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state, nil
+}
+
+// Set replaces the state and signals the change
+func (s *Static) Set(st State) {
+	s.mu.Lock()
+	s.state = st
+	s.mu.Unlock()
+	notify(s.changes)
+}
+
+// Changes delivers a value after each Set
+func (s *Static) Changes() <-chan struct{} {
+	return s.changes
+}
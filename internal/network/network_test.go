@@ -0,0 +1,59 @@
+package network
+
+import (
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// procRoute is /proc/net/route with Wi-Fi and ethernet default routes, a
+// tunnel that routes all traffic and a malformed line
+const procRoute = `Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+wlp2s0	00000000	0101A8C0	0003	0	0	600	00000000	0	0	0
+eth0	00000000	FE01A8C0	0003	0	0	100	00000000	0	0	0
+tun0	00000000	00000000	0001	0	0	0	00000000	0	0	0
+tun0	0000080A	00000000	0001	0	0	0	0000FFFF	0	0	0
+wlp2s0	0001A8C0	00000000	0001	0	0	600	00FFFFFF	0	0	0
+eth0	zzzzzzzz	00000000	0001	0	0	100	00FFFFFF	0	0	0
+`
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := ParseRoutes(strings.NewReader(procRoute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Route{
+		{"wlp2s0", netip.MustParsePrefix("0.0.0.0/0"), netip.MustParseAddr("192.168.1.1"), 600},
+		{"eth0", netip.MustParsePrefix("0.0.0.0/0"), netip.MustParseAddr("192.168.1.254"), 100},
+		{"tun0", netip.MustParsePrefix("0.0.0.0/0"), netip.IPv4Unspecified(), 0},
+		{"tun0", netip.MustParsePrefix("10.8.0.0/16"), netip.IPv4Unspecified(), 0},
+		{"wlp2s0", netip.MustParsePrefix("192.168.1.0/24"), netip.IPv4Unspecified(), 600},
+	}
+	if !slices.Equal(routes, want) {
+		t.Errorf("ParseRoutes() = %v, want %v", routes, want)
+	}
+
+	iface, gw := pickDefault(routes)
+	if iface != "eth0" || gw != netip.MustParseAddr("192.168.1.254") {
+		t.Errorf("pickDefault() = %s via %s, want eth0 via 192.168.1.254", iface, gw)
+	}
+	if iface, gw := pickDefault(routes[2:]); iface != "" || gw.IsValid() {
+		t.Errorf("pickDefault() with only a tunnel default = %s via %s, want none", iface, gw)
+	}
+}
+
+func TestStateKey(t *testing.T) {
+	office := State{Interface: "eth0", Gateway: "10.0.0.1"}
+	known := office
+	known.GatewayMAC = "3c:a6:2f:11:22:33"
+	if office.Key() != known.Key() {
+		t.Errorf("learning the gateway address changed the key from %q to %q", office.Key(), known.Key())
+	}
+	if other := (State{Interface: "eth0", Gateway: "10.0.0.254"}); other.Key() == office.Key() {
+		t.Errorf("another gateway kept the key %q", office.Key())
+	}
+	if key := (State{Interface: "eth0"}).Key(); key != "" {
+		t.Errorf("offline key = %q, want empty", key)
+	}
+}
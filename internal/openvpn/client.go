@@ -3,11 +3,26 @@ package openvpn
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os/exec"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// ErrAuthRequired is returned when openvpn3 asks for input that could not be answered
+var ErrAuthRequired = errors.New("authentication required")
+
+// ErrAuthFailed is returned when openvpn3 asks again for input that was already answered
+var ErrAuthFailed = errors.New("authentication failed")
+
+// AnswerFunc returns the reply to an openvpn3 prompt such as "Auth Password",
+// or false when the prompt cannot be answered
+type AnswerFunc func(prompt string) (string, bool)
+
 // Session represents an active OpenVPN3 session
 type Session struct {
 	Path        string
@@ -19,15 +34,23 @@ type Session struct {
 	ConnectedTo string
 }
 
+// Connected reports whether the client finished connecting
+func (s Session) Connected() bool {
+	return strings.Contains(strings.ToLower(s.Status), "client connected")
+}
+
 // SessionStats holds statistics for a session
 type SessionStats struct {
-	BytesIn      string
-	BytesOut     string
-	PacketsIn    string
-	PacketsOut   string
-	TunnelIP     string
-	TunnelIPv6   string
-	Connected    string
+	BytesIn  string
+	BytesOut string
+	// Unformatted byte counters, e.g. to notice idle sessions
+	RawBytesIn  int64
+	RawBytesOut int64
+	PacketsIn   string
+	PacketsOut  string
+	TunnelIP    string
+	TunnelIPv6  string
+	Connected   string
 }
 
 // Client wraps the openvpn3 CLI commands
@@ -175,8 +198,10 @@ func parseSessionStats(output []byte) *SessionStats {
 		switch key {
 		case "BYTES_IN":
 			stats.BytesIn = formatBytes(value)
+			stats.RawBytesIn, _ = strconv.ParseInt(value, 10, 64)
 		case "BYTES_OUT":
 			stats.BytesOut = formatBytes(value)
+			stats.RawBytesOut, _ = strconv.ParseInt(value, 10, 64)
 		case "PACKETS_IN":
 			stats.PacketsIn = value
 		case "PACKETS_OUT":
@@ -214,10 +239,151 @@ func formatBytes(bytesStr string) string {
 	}
 }
 
-// Connect starts a new VPN session with the given config file
-func (c *Client) Connect(configPath string) error {
-	cmd := exec.Command("openvpn3", "session-start", "--config", configPath)
-	return cmd.Run()
+// Connect starts a new VPN session with the given config file. Prompts for
+// credentials are passed to answer, which may be nil when none are expected.
+func (c *Client) Connect(configPath string, answer AnswerFunc) error {
+	return c.startSession(answer, "--config", configPath)
+}
+
+// Server selects the remote a session connects to instead of trying those in
+// the config in order
+type Server struct {
+	Host  string
+	Port  string
+	Proto string // tcp or udp
+}
+
+// ConnectTo starts a session like Connect, but with server overriding the
+// remotes of the config. The config is imported for the session with
+// openvpn3's server overrides set and removed again once the session started.
+func (c *Client) ConnectTo(configPath string, server Server, answer AnswerFunc) error {
+	out, err := exec.Command("openvpn3", "config-import", "--config", configPath, "--name", configPath).CombinedOutput()
+	if err != nil {
+		return commandError("config-import", out, err)
+	}
+	_, rest, ok := strings.Cut(string(out), "Configuration path:")
+	if !ok {
+		return fmt.Errorf("config-import: no configuration path in %q", strings.TrimSpace(string(out)))
+	}
+	imported := strings.TrimSpace(rest)
+	// A running session keeps its configuration
+	defer exec.Command("openvpn3", "config-remove", "--config-path", imported, "--force").Run()
+
+	args := []string{"config-manage", "--config-path", imported, "--server-override", server.Host}
+	if server.Port != "" {
+		args = append(args, "--port-override", server.Port)
+	}
+	if server.Proto != "" {
+		args = append(args, "--proto-override", server.Proto)
+	}
+	if out, err := exec.Command("openvpn3", args...).CombinedOutput(); err != nil {
+		return commandError("config-manage", out, err)
+	}
+	return c.startSession(answer, "--config-path", imported)
+}
+
+// commandError adds the output of a failed openvpn3 command to its error
+func commandError(name string, out []byte, err error) error {
+	if msg := strings.TrimSpace(string(out)); msg != "" {
+		return fmt.Errorf("%s: %w: %s", name, err, msg)
+	}
+	return fmt.Errorf("%s: %w", name, err)
+}
+
+// startSession runs session-start with args, answering its prompts
+func (c *Client) startSession(answer AnswerFunc, args ...string) error {
+	cmd := exec.Command("openvpn3", append([]string{"session-start"}, args...)...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stderr = cmd.Stdout
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	promptErr := answerPrompts(stdout, stdin, answer)
+	stdin.Close()
+	waitErr := cmd.Wait()
+	if promptErr != nil {
+		return promptErr
+	}
+	return waitErr
+}
+
+// promptPause is how long session-start must stay quiet after a line ending
+// in a colon before it is taken as a prompt. Its output arrives in blocks, so
+// such a line may only be the first part of one like "Session path: /net/…".
+const promptPause = 300 * time.Millisecond
+
+// answerPrompts reads the output of session-start and writes a reply to stdin
+// whenever it waits at a prompt ending in a colon. An unanswerable prompt
+// closes stdin so openvpn3 gives up instead of waiting forever.
+func answerPrompts(output io.Reader, stdin io.WriteCloser, answer AnswerFunc) error {
+	chunks := make(chan string)
+	go func() {
+		defer close(chunks)
+		buf := make([]byte, 4096)
+		for {
+			n, err := output.Read(buf)
+			if n > 0 {
+				chunks <- string(buf[:n])
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	var line string
+	var promptErr error
+	answered := make(map[string]bool)
+	pause := time.NewTimer(promptPause)
+	pause.Stop()
+	defer pause.Stop()
+
+	for {
+		select {
+		case chunk, ok := <-chunks:
+			if !ok {
+				return promptErr
+			}
+			if i := strings.LastIndex(chunk, "\n"); i >= 0 {
+				line = chunk[i+1:]
+			} else {
+				line += chunk
+			}
+			// Prompts are printed without a trailing newline
+			if strings.HasSuffix(strings.TrimSpace(line), ":") && promptErr == nil {
+				pause.Reset(promptPause)
+			} else {
+				pause.Stop()
+			}
+
+		case <-pause.C:
+			prompt := strings.TrimSuffix(strings.TrimSpace(line), ":")
+			reply, ok := "", false
+			if answer != nil && !answered[prompt] {
+				reply, ok = answer(prompt)
+			}
+			switch {
+			case ok:
+				answered[prompt] = true
+				line = ""
+				fmt.Fprintln(stdin, reply)
+			case answered[prompt]:
+				promptErr = fmt.Errorf("%w: %s was rejected", ErrAuthFailed, prompt)
+				stdin.Close()
+			default:
+				promptErr = fmt.Errorf("%w: %s", ErrAuthRequired, prompt)
+				stdin.Close()
+			}
+		}
+	}
 }
 
 // Disconnect terminates a VPN session
@@ -226,6 +392,34 @@ func (c *Client) Disconnect(sessionPath string) error {
 	return cmd.Run()
 }
 
+// WaitClosed polls the session list until none of the sessions at paths is
+// left, giving up after timeout
+func (c *Client) WaitClosed(paths []string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		sessions, err := c.ListSessions()
+		if err != nil {
+			return err
+		}
+		open := 0
+		for _, s := range sessions {
+			if slices.Contains(paths, s.Path) {
+				open++
+			}
+		}
+		if open == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%d session(s) still open after %s", open, timeout)
+		}
+		time.Sleep(closePollInterval)
+	}
+}
+
+// closePollInterval is how often WaitClosed checks the session list
+const closePollInterval = 250 * time.Millisecond
+
 // Pause pauses a VPN session
 func (c *Client) Pause(sessionPath string) error {
 	cmd := exec.Command("openvpn3", "session-manage", "--path", sessionPath, "--pause")
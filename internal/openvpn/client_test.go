@@ -0,0 +1,97 @@
+package openvpn
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// fakeSession plays session-start: it prints each step and, for steps that
+// are prompts, waits for a reply line. It returns the replies it read.
+func fakeSession(t *testing.T, steps []string, prompts map[int]bool, answer AnswerFunc) ([]string, error) {
+	t.Helper()
+	outR, outW := io.Pipe()
+	inR, inW := io.Pipe()
+
+	replies := make(chan []string)
+	go func() {
+		var got []string
+		in := bufio.NewScanner(inR)
+		for i, step := range steps {
+			if step == "" {
+				time.Sleep(promptPause / 3)
+				continue
+			}
+			io.WriteString(outW, step)
+			if prompts[i] {
+				if !in.Scan() {
+					// stdin was closed, like openvpn3 giving up
+					io.WriteString(outW, "\nAborted\n")
+					break
+				}
+				got = append(got, in.Text())
+			}
+		}
+		outW.Close()
+		replies <- got
+	}()
+
+	err := answerPrompts(outR, inW, answer)
+	inW.Close()
+	return <-replies, err
+}
+
+func TestAnswerPrompts(t *testing.T) {
+	t.Run("split line", func(t *testing.T) {
+		// A block boundary right after the colon is not a prompt
+		steps := []string{"Using pre-loaded configuration profile\nSession path:", "", " /net/openvpn/v3/sessions/1\nConnected\n"}
+		_, err := fakeSession(t, steps, nil, func(prompt string) (string, bool) {
+			t.Errorf("answer(%q) called for split output", prompt)
+			return "", false
+		})
+		if err != nil {
+			t.Errorf("answerPrompts() = %v", err)
+		}
+	})
+
+	t.Run("answered", func(t *testing.T) {
+		steps := []string{"Auth User name: ", "Auth Password: ", "Connected\n"}
+		asked := map[string]string{"Auth User name": "alice", "Auth Password": "hunter2"}
+		got, err := fakeSession(t, steps, map[int]bool{0: true, 1: true}, func(prompt string) (string, bool) {
+			reply, ok := asked[prompt]
+			return reply, ok
+		})
+		if err != nil {
+			t.Errorf("answerPrompts() = %v", err)
+		}
+		if len(got) != 2 || got[0] != "alice" || got[1] != "hunter2" {
+			t.Errorf("replies = %q, want alice and hunter2", got)
+		}
+	})
+
+	t.Run("unanswerable", func(t *testing.T) {
+		steps := []string{"Enter Authenticator Code: "}
+		_, err := fakeSession(t, steps, map[int]bool{0: true}, func(string) (string, bool) { return "", false })
+		if !errors.Is(err, ErrAuthRequired) || err.Error() != "authentication required: Enter Authenticator Code" {
+			t.Errorf("answerPrompts() = %v, want %v for the code", err, ErrAuthRequired)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		steps := []string{"Auth Password: ", "Auth Password: "}
+		_, err := fakeSession(t, steps, map[int]bool{0: true, 1: true}, func(string) (string, bool) { return "wrong", true })
+		if !errors.Is(err, ErrAuthFailed) {
+			t.Errorf("answerPrompts() = %v, want %v", err, ErrAuthFailed)
+		}
+	})
+
+	t.Run("no answer func", func(t *testing.T) {
+		steps := []string{"Auth Password: "}
+		_, err := fakeSession(t, steps, map[int]bool{0: true}, nil)
+		if !errors.Is(err, ErrAuthRequired) {
+			t.Errorf("answerPrompts() = %v, want %v", err, ErrAuthRequired)
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package ovpn
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNothingToInline is returned when a config references no files that can be inlined
+var ErrNothingToInline = errors.New("profile has no external files to inline")
+
+// inlineDirectives are the file directives OpenVPN accepts as inline blocks
+var inlineDirectives = map[string]bool{
+	"ca":           true,
+	"cert":         true,
+	"key":          true,
+	"extra-certs":  true,
+	"dh":           true,
+	"tls-auth":     true,
+	"tls-crypt":    true,
+	"tls-crypt-v2": true,
+	"pkcs12":       true,
+}
+
+// Inline replaces references to certificate and key files with inline blocks
+// and returns the names of the directives that were inlined. The direction
+// argument of tls-auth becomes a key-direction directive, since inline
+// tls-auth blocks cannot carry one.
+func (c *Config) Inline() ([]string, error) {
+	var inlined []string
+	var nodes []Node
+	hasKeyDirection := c.Has("key-direction")
+
+	for _, n := range c.Nodes {
+		d := n.Directive
+		if d == nil || !inlineDirectives[d.Name] || d.Arg(0) == "" || d.Arg(0) == "[inline]" {
+			nodes = append(nodes, n)
+			continue
+		}
+
+		data, err := os.ReadFile(c.Resolve(d.Arg(0)))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", d.Line, err)
+		}
+		content := string(data)
+		if d.Name == "pkcs12" {
+			content = wrapBase64(data)
+		}
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+
+		if d.Name == "tls-auth" && d.Arg(1) != "" && !hasKeyDirection {
+			nodes = append(nodes, Node{Directive: &Directive{Name: "key-direction", Args: []string{d.Arg(1)}}})
+			hasKeyDirection = true
+		}
+		nodes = append(nodes, Node{Block: &Block{Name: d.Name, Content: content, Closed: true}})
+		inlined = append(inlined, d.Name)
+	}
+
+	if len(inlined) == 0 {
+		return nil, ErrNothingToInline
+	}
+	c.Nodes = nodes
+	return inlined, nil
+}
+
+// wrapBase64 encodes binary data as base64 in 64 character lines
+func wrapBase64(data []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	for len(encoded) > 64 {
+		b.WriteString(encoded[:64] + "\n")
+		encoded = encoded[64:]
+	}
+	b.WriteString(encoded + "\n")
+	return b.String()
+}
+
+// WriteFile saves the config to path with permissions that keep inlined keys
+// private. The file is replaced atomically so a failed write leaves any
+// existing file intact.
+func (c *Config) WriteFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".ovpn-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(c.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// InlineFile converts the config at src into a self-contained config at dst
+// and returns the names of the inlined directives. An existing file at dst is
+// only replaced when it is src itself.
+func InlineFile(src, dst string) ([]string, error) {
+	cfg, err := ParseFile(src)
+	if err != nil {
+		return nil, err
+	}
+	inlined, err := cfg.Inline()
+	if err != nil {
+		return nil, err
+	}
+
+	if filepath.Clean(src) != filepath.Clean(dst) {
+		if _, err := os.Stat(dst); err == nil {
+			return nil, fmt.Errorf("%s already exists", dst)
+		}
+	}
+	if err := cfg.WriteFile(dst); err != nil {
+		return nil, err
+	}
+	return inlined, nil
+}
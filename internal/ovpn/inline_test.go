@@ -0,0 +1,114 @@
+package ovpn
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInlineFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"work.ovpn": "client\nremote vpn.example.com 1194\nca ca.crt\ntls-auth ta.key 1\nverb 3\n",
+		"ca.crt":    "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----",
+		"ta.key":    "-----BEGIN OpenVPN Static key V1-----\n00ff\n-----END OpenVPN Static key V1-----\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dst := filepath.Join(dir, "work-inline.ovpn")
+	inlined, err := InlineFile(filepath.Join(dir, "work.ovpn"), dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(inlined, ","); got != "ca,tls-auth" {
+		t.Errorf("inlined %q, want ca,tls-auth", got)
+	}
+
+	data, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The tls-auth direction moves to key-direction, in place of the directive
+	want := `client
+remote vpn.example.com 1194
+<ca>
+-----BEGIN CERTIFICATE-----
+MIIB
+-----END CERTIFICATE-----
+</ca>
+key-direction 1
+<tls-auth>
+-----BEGIN OpenVPN Static key V1-----
+00ff
+-----END OpenVPN Static key V1-----
+</tls-auth>
+verb 3
+`
+	if string(data) != want {
+		t.Errorf("inlined config =\n%s\nwant\n%s", data, want)
+	}
+
+	info, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("inlined config has mode %o, want 600", perm)
+	}
+
+	if _, err := InlineFile(filepath.Join(dir, "work.ovpn"), dst); err == nil {
+		t.Error("InlineFile() replaced an existing file")
+	}
+	if _, err := InlineFile(dst, dst); !errors.Is(err, ErrNothingToInline) {
+		t.Errorf("InlineFile() of an inline config = %v, want ErrNothingToInline", err)
+	}
+}
+
+func TestInlineKeepsKeyDirection(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ta.key"), []byte("key\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Parse(strings.NewReader("key-direction 0\ntls-auth ta.key 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Path = filepath.Join(dir, "work.ovpn")
+	if _, err := cfg.Inline(); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(cfg.All("key-direction")); got != 1 {
+		t.Errorf("found %d key-direction directives, want the existing one only", got)
+	}
+}
+
+func TestWriteFileMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles", "work.ovpn")
+	cfg, err := Parse(strings.NewReader("client\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Replacing a world readable file tightens its permissions
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("WriteFile() left mode %o, want 600", perm)
+	}
+}
@@ -0,0 +1,215 @@
+package ovpn
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Severity ranks lint findings
+type Severity int
+
+const (
+	SeverityWarning Severity = iota
+	SeverityError
+)
+
+// String returns the lowercase name of the severity
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Finding is a problem detected in a config
+type Finding struct {
+	Severity Severity
+	Line     int // 0 when the finding is not tied to a line
+	Message  string
+}
+
+// String formats the finding as "line 12: error: message"
+func (f Finding) String() string {
+	if f.Line > 0 {
+		return fmt.Sprintf("line %d: %s: %s", f.Line, f.Severity, f.Message)
+	}
+	return fmt.Sprintf("%s: %s", f.Severity, f.Message)
+}
+
+// unsupportedDirectives are OpenVPN 2.x options that OpenVPN3 rejects or
+// ignores, mostly because it never runs scripts or daemonizes itself
+var unsupportedDirectives = map[string]string{
+	"script-security":       "scripts are not supported",
+	"up":                    "scripts are not supported",
+	"down":                  "scripts are not supported",
+	"route-up":              "scripts are not supported",
+	"route-pre-down":        "scripts are not supported",
+	"ipchange":              "scripts are not supported",
+	"tls-verify":            "scripts are not supported",
+	"auth-user-pass-verify": "server-side option",
+	"client-connect":        "server-side option",
+	"client-disconnect":     "server-side option",
+	"learn-address":         "server-side option",
+	"plugin":                "plugins are not supported",
+	"management":            "use the openvpn3 D-Bus API instead",
+	"daemon":                "sessions are managed by openvpn3",
+	"log":                   "logging is handled by openvpn3-service-log",
+	"log-append":            "logging is handled by openvpn3-service-log",
+	"writepid":              "sessions are managed by openvpn3",
+	"user":                  "privileges are managed by openvpn3",
+	"group":                 "privileges are managed by openvpn3",
+	"chroot":                "privileges are managed by openvpn3",
+	"secret":                "static key mode is not supported",
+}
+
+// weakCiphers use 64-bit blocks and are vulnerable to SWEET32
+var weakCiphers = []string{"BF-", "CAST5-", "DES-", "RC2-", "IDEA-", "SEED-"}
+
+// weakDigests are HMAC digests that should no longer be used
+var weakDigests = map[string]bool{"md5": true, "sha1": true, "ripemd160": true, "none": true}
+
+// pemBlocks are inline blocks that must contain PEM data
+var pemBlocks = map[string]string{
+	"ca":           "CERTIFICATE",
+	"cert":         "CERTIFICATE",
+	"extra-certs":  "CERTIFICATE",
+	"key":          "PRIVATE KEY",
+	"tls-auth":     "OpenVPN Static key V1",
+	"tls-crypt":    "OpenVPN Static key V1",
+	"tls-crypt-v2": "OpenVPN tls-crypt-v2 client key",
+	"dh":           "DH PARAMETERS",
+}
+
+// LintFile parses and lints the config at path
+func LintFile(path string) ([]Finding, error) {
+	cfg, err := ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Lint(cfg), nil
+}
+
+// Lint checks a config for errors that prevent connecting with OpenVPN3 and
+// for insecure settings
+func Lint(cfg *Config) []Finding {
+	var findings []Finding
+	add := func(sev Severity, line int, format string, args ...any) {
+		findings = append(findings, Finding{Severity: sev, Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(cfg.Remotes()) == 0 {
+		add(SeverityError, 0, "no remote server configured")
+	}
+
+	// Referenced files
+	for _, ref := range cfg.ExternalFiles() {
+		if _, err := os.Stat(ref.Path); err != nil {
+			add(SeverityError, ref.Directive.Line, "%s file not found: %s", ref.Directive.Name, ref.Path)
+		}
+	}
+
+	// Inline blocks
+	for _, d := range cfg.Directives() {
+		if d.Arg(0) == "[inline]" {
+			if _, ok := cfg.Block(d.Name); !ok {
+				add(SeverityError, d.Line, "%s refers to a missing <%s> block", d.Name, d.Name)
+			}
+		}
+	}
+	for _, b := range cfg.Blocks() {
+		if !b.Closed {
+			add(SeverityError, b.Line, "<%s> block is not closed", b.Name)
+			continue
+		}
+		if strings.TrimSpace(b.Content) == "" {
+			add(SeverityError, b.Line, "<%s> block is empty", b.Name)
+			continue
+		}
+		if marker, ok := pemBlocks[b.Name]; ok && !validPEM(b.Content, marker) {
+			add(SeverityError, b.Line, "<%s> block does not contain a valid %s", b.Name, marker)
+		}
+	}
+
+	// Options OpenVPN3 does not support
+	for _, d := range cfg.Directives() {
+		if reason, ok := unsupportedDirectives[d.Name]; ok {
+			add(SeverityError, d.Line, "%s is not supported by OpenVPN3 (%s)", d.Name, reason)
+		}
+	}
+
+	// Ciphers
+	if d, ok := cfg.Get("cipher"); ok && isWeakCipher(d.Arg(0)) {
+		add(SeverityWarning, d.Line, "cipher %s uses a 64-bit block size (SWEET32)", d.Arg(0))
+	}
+	for _, c := range cfg.DataCiphers() {
+		if isWeakCipher(c) {
+			d, _ := cfg.Get("data-ciphers")
+			add(SeverityWarning, d.Line, "data-ciphers includes weak cipher %s", c)
+		}
+	}
+
+	// Compression enables VORACLE style attacks
+	for _, name := range []string{"comp-lzo", "compress"} {
+		for _, d := range cfg.All(name) {
+			if name == "compress" && (d.Arg(0) == "stub" || d.Arg(0) == "stub-v2") {
+				continue
+			}
+			if name == "comp-lzo" && d.Arg(0) == "no" {
+				continue
+			}
+			add(SeverityWarning, d.Line, "%s enables compression, which can leak data (VORACLE)", name)
+		}
+	}
+	if d, ok := cfg.Get("allow-compression"); ok && d.Arg(0) == "yes" {
+		add(SeverityWarning, d.Line, "allow-compression yes permits compressed traffic")
+	}
+
+	// Control channel protection
+	if !cfg.Has("tls-auth") && !cfg.Has("tls-crypt") && !cfg.Has("tls-crypt-v2") {
+		add(SeverityWarning, 0, "no tls-auth or tls-crypt key protects the control channel")
+	}
+
+	if d, ok := cfg.Get("auth"); ok && weakDigests[strings.ToLower(d.Arg(0))] {
+		add(SeverityWarning, d.Line, "weak auth digest %s", d.Arg(0))
+	}
+
+	if !cfg.Has("remote-cert-tls") && !cfg.Has("verify-x509-name") {
+		add(SeverityWarning, 0, "server certificate is not verified (add remote-cert-tls server)")
+	}
+
+	return findings
+}
+
+// validPEM reports whether content has a matching BEGIN/END pair containing marker
+func validPEM(content, marker string) bool {
+	begin := strings.Index(content, "-----BEGIN ")
+	end := strings.LastIndex(content, "-----END ")
+	if begin == -1 || end == -1 || end < begin {
+		return false
+	}
+	return strings.Contains(content, marker+"-----")
+}
+
+// isWeakCipher reports whether a cipher name uses a 64-bit block cipher
+func isWeakCipher(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, prefix := range weakCiphers {
+		if strings.HasPrefix(upper, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of errors and warnings in findings
+func Count(findings []Finding) (errors, warnings int) {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			errors++
+		} else {
+			warnings++
+		}
+	}
+	return errors, warnings
+}
@@ -0,0 +1,476 @@
+// Package ovpn parses OpenVPN .ovpn configuration files.
+//
+// The parser keeps every line, including comments and blank lines, in file
+// order so a config can be inspected, modified and written back without
+// losing the author's formatting.
+package ovpn
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Directive is a single configuration option such as "remote host 1194"
+type Directive struct {
+	Name string
+	Args []string
+	Line int // 1-based line number, 0 for directives added programmatically
+}
+
+// Arg returns the argument at index i, or an empty string
+func (d Directive) Arg(i int) string {
+	if i < len(d.Args) {
+		return d.Args[i]
+	}
+	return ""
+}
+
+// String formats the directive as a config line, quoting arguments as needed
+func (d Directive) String() string {
+	parts := []string{d.Name}
+	for _, arg := range d.Args {
+		parts = append(parts, quote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Block is an inline block such as <ca>...</ca>
+type Block struct {
+	Name    string
+	Content string // Text between the tags, including the trailing newline
+	Line    int    // Line of the opening tag
+	Closed  bool   // False if the closing tag was missing
+}
+
+// String formats the block with its opening and closing tags
+func (b Block) String() string {
+	content := b.Content
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return fmt.Sprintf("<%s>\n%s</%s>", b.Name, content, b.Name)
+}
+
+// Node is one element of a config in file order: a comment or blank line,
+// a directive or an inline block
+type Node struct {
+	Directive *Directive
+	Block     *Block
+	Raw       string // Original text; clear it after changing the node so it is re-formatted
+}
+
+// IsComment reports whether the node is a comment or blank line
+func (n Node) IsComment() bool {
+	return n.Directive == nil && n.Block == nil
+}
+
+// Config is a parsed .ovpn file
+type Config struct {
+	Path  string // File the config was read from, used to resolve relative paths
+	Nodes []Node
+}
+
+// ParseFile reads and parses the config at path
+func ParseFile(path string) (*Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	cfg, err := Parse(file)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Path = path
+	return cfg, nil
+}
+
+// Parse parses a config. Malformed content such as unclosed blocks is kept
+// in the result rather than reported as an error, so callers can lint it.
+func Parse(r io.Reader) (*Config, error) {
+	cfg := &Config{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var block *Block
+	var raw, content strings.Builder
+	lineNo := 0
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNo++
+
+		// Collect inline block content until the closing tag
+		if block != nil {
+			raw.WriteString("\n" + line)
+			if strings.TrimSpace(line) == "</"+block.Name+">" {
+				block.Content = content.String()
+				block.Closed = true
+				cfg.Nodes = append(cfg.Nodes, Node{Block: block, Raw: raw.String()})
+				block = nil
+				continue
+			}
+			content.WriteString(line + "\n")
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if name, ok := openingTag(trimmed); ok {
+			block = &Block{Name: name, Line: lineNo}
+			raw.Reset()
+			raw.WriteString(line)
+			content.Reset()
+			continue
+		}
+
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";") {
+			cfg.Nodes = append(cfg.Nodes, Node{Raw: line})
+			continue
+		}
+
+		fields := tokenize(trimmed)
+		d := &Directive{
+			Name: strings.TrimPrefix(fields[0], "--"),
+			Args: fields[1:],
+			Line: lineNo,
+		}
+		cfg.Nodes = append(cfg.Nodes, Node{Directive: d, Raw: line})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Keep an unterminated block so the linter can report it
+	if block != nil {
+		block.Content = content.String()
+		cfg.Nodes = append(cfg.Nodes, Node{Block: block, Raw: raw.String()})
+	}
+
+	return cfg, nil
+}
+
+// openingTag reports whether line is an inline block opening tag like <ca>
+func openingTag(line string) (string, bool) {
+	if len(line) < 3 || line[0] != '<' || line[1] == '/' || line[len(line)-1] != '>' {
+		return "", false
+	}
+	name := line[1 : len(line)-1]
+	if strings.ContainsAny(name, " \t<>") {
+		return "", false
+	}
+	return name, true
+}
+
+// tokenize splits a directive line into fields, honouring quotes and
+// backslash escapes the same way OpenVPN does. Text after an unquoted
+// # or ; that starts a field is treated as a comment.
+func tokenize(line string) []string {
+	var fields []string
+	var cur strings.Builder
+	inField := false
+	var quote rune
+
+	for i := 0; i < len(line); i++ {
+		c := rune(line[i])
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' && i+1 < len(line) {
+				i++
+				cur.WriteByte(line[i])
+			} else {
+				cur.WriteRune(c)
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			inField = true
+		case c == '\\' && i+1 < len(line):
+			i++
+			cur.WriteByte(line[i])
+			inField = true
+		case c == ' ' || c == '\t':
+			if inField {
+				fields = append(fields, cur.String())
+				cur.Reset()
+				inField = false
+			}
+		case (c == '#' || c == ';') && !inField:
+			i = len(line)
+		default:
+			cur.WriteRune(c)
+			inField = true
+		}
+	}
+	if inField {
+		fields = append(fields, cur.String())
+	}
+	return fields
+}
+
+// quote wraps an argument in double quotes if it contains special characters
+func quote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\#;") {
+		return arg
+	}
+	arg = strings.ReplaceAll(arg, `\`, `\\`)
+	arg = strings.ReplaceAll(arg, `"`, `\"`)
+	return `"` + arg + `"`
+}
+
+// Bytes serializes the config, writing unchanged nodes exactly as they were read
+func (c *Config) Bytes() []byte {
+	var b bytes.Buffer
+	for _, n := range c.Nodes {
+		switch {
+		case n.Raw != "" || n.IsComment():
+			b.WriteString(n.Raw)
+		case n.Directive != nil:
+			b.WriteString(n.Directive.String())
+		case n.Block != nil:
+			b.WriteString(n.Block.String())
+		}
+		b.WriteString("\n")
+	}
+	return b.Bytes()
+}
+
+// Directives returns all directives in file order
+func (c *Config) Directives() []Directive {
+	var ds []Directive
+	for _, n := range c.Nodes {
+		if n.Directive != nil {
+			ds = append(ds, *n.Directive)
+		}
+	}
+	return ds
+}
+
+// All returns every directive with the given name
+func (c *Config) All(name string) []Directive {
+	var ds []Directive
+	for _, n := range c.Nodes {
+		if n.Directive != nil && n.Directive.Name == name {
+			ds = append(ds, *n.Directive)
+		}
+	}
+	return ds
+}
+
+// Get returns the last directive with the given name, as OpenVPN lets later
+// options override earlier ones
+func (c *Config) Get(name string) (Directive, bool) {
+	ds := c.All(name)
+	if len(ds) == 0 {
+		return Directive{}, false
+	}
+	return ds[len(ds)-1], true
+}
+
+// Has reports whether the config contains a directive or block with the given name
+func (c *Config) Has(name string) bool {
+	if _, ok := c.Get(name); ok {
+		return true
+	}
+	_, ok := c.Block(name)
+	return ok
+}
+
+// Add appends a directive to the end of the config
+func (c *Config) Add(name string, args ...string) {
+	c.Nodes = append(c.Nodes, Node{Directive: &Directive{Name: name, Args: args}})
+}
+
+// AddComment appends a comment line to the end of the config
+func (c *Config) AddComment(text string) {
+	c.Nodes = append(c.Nodes, Node{Raw: "# " + text})
+}
+
+// Blocks returns all inline blocks in file order
+func (c *Config) Blocks() []Block {
+	var bs []Block
+	for _, n := range c.Nodes {
+		if n.Block != nil {
+			bs = append(bs, *n.Block)
+		}
+	}
+	return bs
+}
+
+// Block returns the inline block with the given name
+func (c *Config) Block(name string) (Block, bool) {
+	for _, n := range c.Nodes {
+		if n.Block != nil && n.Block.Name == name {
+			return *n.Block, true
+		}
+	}
+	return Block{}, false
+}
+
+// Remote is a server entry from a remote directive
+type Remote struct {
+	Host  string
+	Port  string
+	Proto string
+}
+
+// String formats the remote as host:port/proto
+func (r Remote) String() string {
+	return fmt.Sprintf("%s:%s/%s", r.Host, r.Port, r.Proto)
+}
+
+// Remotes returns the servers listed in remote directives and <connection>
+// blocks, filling in the default port and protocol
+func (c *Config) Remotes() []Remote {
+	port := "1194"
+	if d, ok := c.Get("port"); ok && d.Arg(0) != "" {
+		port = d.Arg(0)
+	}
+	if d, ok := c.Get("rport"); ok && d.Arg(0) != "" {
+		port = d.Arg(0)
+	}
+	proto := c.Proto()
+
+	var remotes []Remote
+	for _, n := range c.Nodes {
+		switch {
+		case n.Directive != nil && n.Directive.Name == "remote":
+			remotes = append(remotes, newRemote(*n.Directive, port, proto))
+		case n.Block != nil && n.Block.Name == "connection":
+			// Connection blocks carry their own remote and proto
+			inner, err := Parse(strings.NewReader(n.Block.Content))
+			if err != nil {
+				continue
+			}
+			innerProto := proto
+			if d, ok := inner.Get("proto"); ok {
+				innerProto = d.Arg(0)
+			}
+			for _, d := range inner.All("remote") {
+				remotes = append(remotes, newRemote(d, port, innerProto))
+			}
+		}
+	}
+	return remotes
+}
+
+// newRemote builds a Remote from a remote directive and the defaults
+func newRemote(d Directive, port, proto string) Remote {
+	r := Remote{Host: d.Arg(0), Port: port, Proto: proto}
+	if p := d.Arg(1); p != "" {
+		r.Port = p
+	}
+	if p := d.Arg(2); p != "" {
+		r.Proto = p
+	}
+	return r
+}
+
+// Proto returns the transport protocol, defaulting to udp
+func (c *Config) Proto() string {
+	if d, ok := c.Get("proto"); ok && d.Arg(0) != "" {
+		return d.Arg(0)
+	}
+	return "udp"
+}
+
+// Dev returns the tunnel device type or name, e.g. tun
+func (c *Config) Dev() string {
+	d, _ := c.Get("dev")
+	return d.Arg(0)
+}
+
+// Cipher returns the value of the legacy cipher directive
+func (c *Config) Cipher() string {
+	d, _ := c.Get("cipher")
+	return d.Arg(0)
+}
+
+// DataCiphers returns the ciphers negotiable for the data channel
+func (c *Config) DataCiphers() []string {
+	d, ok := c.Get("data-ciphers")
+	if !ok {
+		d, ok = c.Get("ncp-ciphers")
+	}
+	if !ok || d.Arg(0) == "" {
+		return nil
+	}
+	return strings.Split(d.Arg(0), ":")
+}
+
+// AuthUserPass reports whether the profile asks for a username and password,
+// and the credentials file if one is given
+func (c *Config) AuthUserPass() (bool, string) {
+	d, ok := c.Get("auth-user-pass")
+	if !ok {
+		return false, ""
+	}
+	return true, d.Arg(0)
+}
+
+// StaticChallenge returns the text of the static-challenge directive, which
+// openvpn3 shows as the prompt for a one-time password
+func (c *Config) StaticChallenge() string {
+	d, _ := c.Get("static-challenge")
+	return d.Arg(0)
+}
+
+// fileDirectives lists the directives whose first argument names a file.
+// The value "[inline]" refers to an inline block instead.
+var fileDirectives = map[string]bool{
+	"ca":                   true,
+	"cert":                 true,
+	"key":                  true,
+	"pkcs12":               true,
+	"dh":                   true,
+	"extra-certs":          true,
+	"tls-auth":             true,
+	"tls-crypt":            true,
+	"tls-crypt-v2":         true,
+	"secret":               true,
+	"crl-verify":           true,
+	"auth-user-pass":       true,
+	"askpass":              true,
+	"http-proxy-user-pass": true,
+}
+
+// FileRef is a file referenced by a directive
+type FileRef struct {
+	Directive Directive
+	Path      string // Absolute path, resolved against the config file's directory
+}
+
+// ExternalFiles returns the files referenced by directives such as ca and key
+func (c *Config) ExternalFiles() []FileRef {
+	var refs []FileRef
+	for _, d := range c.Directives() {
+		if !fileDirectives[d.Name] {
+			continue
+		}
+		arg := d.Arg(0)
+		if arg == "" || arg == "[inline]" || (d.Name == "auth-user-pass" && arg == "stdin") {
+			continue
+		}
+		refs = append(refs, FileRef{Directive: d, Path: c.Resolve(arg)})
+	}
+	return refs
+}
+
+// Resolve returns path as an absolute path relative to the config's directory
+func (c *Config) Resolve(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	if filepath.IsAbs(path) || c.Path == "" {
+		return path
+	}
+	return filepath.Join(filepath.Dir(c.Path), path)
+}
@@ -0,0 +1,150 @@
+// Package probe measures how quickly the servers listed in a profile answer,
+// so that the closest one can be picked.
+package probe
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"openvpn3-tui/internal/network"
+	"openvpn3-tui/internal/ovpn"
+)
+
+// ErrSigned is returned for UDP servers that drop unsigned packets
+var ErrSigned = errors.New("tls-auth/tls-crypt servers only answer signed packets")
+
+// Result is the latency of one server, or why it could not be measured
+type Result struct {
+	Remote  ovpn.Remote
+	Latency time.Duration
+	Err     error
+}
+
+// Remotes probes every server of a profile concurrently and returns the
+// results sorted by latency, with unreachable servers last in file order
+func Remotes(cfg *ovpn.Config, timeout time.Duration) []Result {
+	remotes := cfg.Remotes()
+	signed := cfg.Has("tls-auth") || cfg.Has("tls-crypt") || cfg.Has("tls-crypt-v2")
+
+	results := make([]Result, len(remotes))
+	var wg sync.WaitGroup
+	for i, r := range remotes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			results[i] = Probe(ctx, r, signed)
+		}()
+	}
+	wg.Wait()
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if (a.Err == nil) != (b.Err == nil) {
+			return a.Err == nil
+		}
+		return a.Err == nil && a.Latency < b.Latency
+	})
+	return results
+}
+
+// Probe times a TCP connect, or for UDP an OpenVPN handshake reset and its
+// reply. Name resolution is not part of the latency.
+func Probe(ctx context.Context, r ovpn.Remote, signed bool) Result {
+	res := Result{Remote: r}
+	network := Network(r.Proto)
+	if strings.HasPrefix(network, "udp") && signed {
+		res.Err = ErrSigned
+		return res
+	}
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, r.Host)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) {
+			err = errors.New(dnsErr.Err)
+		}
+		res.Err = err
+		return res
+	}
+	addr := net.JoinHostPort(addrs[0], r.Port)
+
+	start := time.Now()
+	if strings.HasPrefix(network, "tcp") {
+		res.Err = dialTCP(ctx, network, addr)
+	} else {
+		res.Err = resetUDP(ctx, network, addr)
+	}
+	res.Latency = time.Since(start)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		res.Err = errors.New("timed out")
+	}
+	return res
+}
+
+// Network maps an OpenVPN proto such as "tcp-client" or "udp6" to a Go network
+func Network(proto string) string {
+	network := strings.TrimSuffix(strings.ToLower(proto), "-client")
+	switch network {
+	case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6":
+		return network
+	}
+	return "udp"
+}
+
+// dialTCP connects to addr over proto, a Go network such as "tcp4"
+func dialTCP(ctx context.Context, proto, addr string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, proto, addr)
+	if err != nil {
+		return network.DialError(err)
+	}
+	return conn.Close()
+}
+
+// OpenVPN control packet opcodes, stored in the upper five bits of the first byte
+const (
+	opHardResetClientV2 = 7
+	opHardResetServerV2 = 8
+)
+
+// resetUDP sends the hard reset that starts an OpenVPN handshake and waits
+// for the server's reset in reply
+func resetUDP(ctx context.Context, proto, addr string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, proto, addr)
+	if err != nil {
+		return network.DialError(err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// Opcode and key id, session id, an empty ack array and packet id 0
+	packet := make([]byte, 14)
+	packet[0] = opHardResetClientV2 << 3
+	if _, err := rand.Read(packet[1:9]); err != nil {
+		return err
+	}
+	if _, err := conn.Write(packet); err != nil {
+		return network.DialError(err)
+	}
+
+	reply := make([]byte, 1500)
+	n, err := conn.Read(reply)
+	if err != nil {
+		return network.DialError(err)
+	}
+	if n == 0 || reply[0]>>3 != opHardResetServerV2 {
+		return errors.New("unexpected reply")
+	}
+	return nil
+}
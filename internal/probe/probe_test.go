@@ -0,0 +1,112 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"openvpn3-tui/internal/ovpn"
+)
+
+// udpServer answers every packet with reply until the test ends. A nil
+// reply never answers.
+func udpServer(t *testing.T, reply []byte) ovpn.Remote {
+	t.Helper()
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			if n > 0 && buf[0]>>3 == opHardResetClientV2 && reply != nil {
+				conn.WriteToUDP(reply, from)
+			}
+		}
+	}()
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+	return ovpn.Remote{Host: "127.0.0.1", Port: strconv.Itoa(port), Proto: "udp"}
+}
+
+func TestProbeUDP(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   []byte
+		signed  bool
+		wantErr string
+	}{
+		{"hard reset", []byte{opHardResetServerV2 << 3, 1, 2, 3}, false, ""},
+		{"other reply", []byte{opHardResetClientV2 << 3}, false, "unexpected reply"},
+		{"no reply", nil, false, "timed out"},
+		{"signed", []byte{opHardResetServerV2 << 3}, true, ErrSigned.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			res := Probe(ctx, udpServer(t, tt.reply), tt.signed)
+			if tt.wantErr == "" && res.Err != nil {
+				t.Errorf("Probe() error = %v", res.Err)
+			}
+			if tt.wantErr != "" && (res.Err == nil || res.Err.Error() != tt.wantErr) {
+				t.Errorf("Probe() error = %v, want %s", res.Err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProbeTCP(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	port := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	res := Probe(ctx, ovpn.Remote{Host: "127.0.0.1", Port: port, Proto: "tcp-client"}, true)
+	if res.Err != nil || res.Latency <= 0 {
+		t.Errorf("Probe() = %v, %v, want a latency", res.Latency, res.Err)
+	}
+
+	// Nothing listens once the listener is closed
+	ln.Close()
+	res = Probe(ctx, ovpn.Remote{Host: "127.0.0.1", Port: port, Proto: "tcp"}, false)
+	if res.Err == nil {
+		t.Error("Probe() of a closed port succeeded")
+	}
+}
+
+func TestRemotesOrder(t *testing.T) {
+	up := udpServer(t, []byte{opHardResetServerV2 << 3})
+	down := udpServer(t, nil)
+	cfg, err := ovpn.Parse(strings.NewReader("remote 127.0.0.1 " + down.Port + "\nremote 127.0.0.1 " + up.Port + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := Remotes(cfg, 200*time.Millisecond)
+	if len(results) != 2 || results[0].Remote.Port != up.Port || results[0].Err != nil {
+		t.Fatalf("Remotes() = %+v, want the answering server first", results)
+	}
+	if results[1].Err == nil || results[1].Err.Error() != "timed out" {
+		t.Errorf("silent server error = %v, want a timeout", results[1].Err)
+	}
+}
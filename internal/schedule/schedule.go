@@ -0,0 +1,143 @@
+// Package schedule decides whether a profile is inside one of its weekly
+// connection windows.
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"openvpn3-tui/internal/config"
+)
+
+// parseDay parses a day name, either in full or abbreviated to three letters
+func parseDay(d string) (time.Weekday, bool) {
+	name := strings.ToLower(strings.TrimSpace(d))
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		full := strings.ToLower(day.String())
+		if name == full || name == full[:3] {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// window is a parsed config.Window with times in minutes after midnight
+type window struct {
+	days       [7]bool
+	start, end int
+}
+
+// parse validates a window. Day names may be abbreviated to three letters.
+func parse(w config.Window) (window, error) {
+	var pw window
+	if len(w.Days) == 0 {
+		pw.days = [7]bool{true, true, true, true, true, true, true}
+	}
+	for _, d := range w.Days {
+		day, ok := parseDay(d)
+		if !ok {
+			return pw, fmt.Errorf("unknown day %q", d)
+		}
+		pw.days[day] = true
+	}
+
+	var err error
+	if pw.start, err = parseClock(w.Start); err != nil {
+		return pw, fmt.Errorf("start: %w", err)
+	}
+	if pw.end, err = parseClock(w.End); err != nil {
+		return pw, fmt.Errorf("end: %w", err)
+	}
+	return pw, nil
+}
+
+// parseClock parses HH:MM into minutes after midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// contains reports whether t lies in the window. A window ending before it
+// starts runs past midnight and belongs to the day it starts on; one ending
+// when it starts lasts the whole day.
+func (w window) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	today := w.days[t.Weekday()]
+	yesterday := w.days[(t.Weekday()+6)%7]
+	switch {
+	case w.start < w.end:
+		return today && minute >= w.start && minute < w.end
+	case w.start > w.end:
+		return (today && minute >= w.start) || (yesterday && minute < w.end)
+	default:
+		return today
+	}
+}
+
+// Active reports whether t lies in one of the windows. Invalid windows never match.
+func Active(windows []config.Window, t time.Time) bool {
+	for _, w := range windows {
+		if pw, err := parse(w); err == nil && pw.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns when the windows next open or close after t, looking a week ahead
+func Next(windows []config.Window, t time.Time) (time.Time, bool) {
+	var next time.Time
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	// Start a day early for windows running past midnight into today
+	for d := -1; d <= 7; d++ {
+		day := midnight.AddDate(0, 0, d)
+		for _, w := range windows {
+			pw, err := parse(w)
+			if err != nil || !pw.days[day.Weekday()] {
+				continue
+			}
+			start := day.Add(time.Duration(pw.start) * time.Minute)
+			end := day.Add(time.Duration(pw.end) * time.Minute)
+			switch {
+			case pw.start == pw.end:
+				start, end = day, day.AddDate(0, 0, 1)
+			case pw.end < pw.start:
+				end = end.AddDate(0, 0, 1)
+			}
+			for _, c := range []time.Time{start, end} {
+				// Overlapping windows do not change anything at every edge
+				changes := Active(windows, c) != Active(windows, c.Add(-time.Minute))
+				if c.After(t) && changes && (next.IsZero() || c.Before(next)) {
+					next = c
+				}
+			}
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// Describe formats a window, e.g. "sat,sun 02:00-04:00"
+func Describe(w config.Window) string {
+	days := "daily"
+	if len(w.Days) > 0 {
+		days = strings.ToLower(strings.Join(w.Days, ","))
+	}
+	return fmt.Sprintf("%s %s-%s", days, w.Start, w.End)
+}
+
+// Check reports profiles with invalid schedule windows
+func Check(cfg *config.Config) []string {
+	var problems []string
+	for _, p := range cfg.Profiles {
+		for _, w := range p.Schedule {
+			if _, err := parse(w); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: schedule %s: %v", p.Name, Describe(w), err))
+			}
+		}
+	}
+	return problems
+}
@@ -0,0 +1,104 @@
+package schedule
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"openvpn3-tui/internal/config"
+)
+
+// at returns a time in the first week of 2024, which starts on a Monday
+func at(day int, clock string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", fmt.Sprintf("2024-01-%02d %s", day, clock))
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseDays(t *testing.T) {
+	tests := []struct {
+		day string
+		ok  bool
+	}{
+		{"mon", true},
+		{"Monday", true},
+		{" SAT ", true},
+		{"saturday", true},
+		{"month", false},
+		{"satellite", false},
+		{"mo", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		_, err := parse(config.Window{Days: []string{tt.day}, Start: "09:00", End: "17:00"})
+		if (err == nil) != tt.ok {
+			t.Errorf("parse(days %q) error = %v, want ok %v", tt.day, err, tt.ok)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	office := config.Window{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "17:00"}
+	night := config.Window{Days: []string{"sat"}, Start: "22:00", End: "02:00"}
+	allDay := config.Window{Days: []string{"monday"}, Start: "08:00", End: "08:00"}
+
+	tests := []struct {
+		name   string
+		window config.Window
+		t      time.Time
+		want   bool
+	}{
+		{"office start", office, at(1, "09:00"), true},
+		{"office end", office, at(1, "17:00"), false},
+		{"office weekend", office, at(6, "12:00"), false},
+		{"night on its day", night, at(6, "23:00"), true},
+		{"night past midnight", night, at(7, "01:59"), true},
+		{"night over", night, at(7, "02:00"), false},
+		{"night next evening", night, at(7, "22:30"), false},
+		{"night before it starts", night, at(6, "01:00"), false},
+		{"all day at midnight", allDay, at(1, "00:00"), true},
+		{"all day late", allDay, at(1, "23:59"), true},
+		{"all day next day", allDay, at(2, "00:00"), false},
+	}
+	for _, tt := range tests {
+		pw, err := parse(tt.window)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := pw.contains(tt.t); got != tt.want {
+			t.Errorf("%s: contains(%s) = %v, want %v", tt.name, tt.t.Format("Mon 15:04"), got, tt.want)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	night := config.Window{Days: []string{"sat"}, Start: "22:00", End: "02:00"}
+	allDay := config.Window{Days: []string{"mon"}, Start: "08:00", End: "08:00"}
+	morning := config.Window{Days: []string{"mon"}, Start: "06:00", End: "10:00"}
+
+	tests := []struct {
+		name    string
+		windows []config.Window
+		t       time.Time
+		want    time.Time
+	}{
+		{"opens later in the week", []config.Window{night}, at(1, "12:00"), at(6, "22:00")},
+		{"closes past midnight", []config.Window{night}, at(6, "23:00"), at(7, "02:00")},
+		{"closes on the next day", []config.Window{night}, at(7, "01:00"), at(7, "02:00")},
+		{"all day opens at midnight", []config.Window{allDay}, at(7, "12:00"), at(8, "00:00")},
+		{"all day closes at midnight", []config.Window{allDay}, at(1, "12:00"), at(2, "00:00")},
+		{"overlap hides inner edges", []config.Window{allDay, morning}, at(1, "05:00"), at(2, "00:00")},
+	}
+	for _, tt := range tests {
+		got, ok := Next(tt.windows, tt.t)
+		if !ok || !got.Equal(tt.want) {
+			t.Errorf("%s: Next(%s) = %s, %v, want %s", tt.name, tt.t.Format("Mon 15:04"), got.Format("Mon Jan 2 15:04"), ok, tt.want.Format("Mon Jan 2 15:04"))
+		}
+	}
+
+	if _, ok := Next(nil, at(1, "12:00")); ok {
+		t.Error("Next() without windows reported a change")
+	}
+}
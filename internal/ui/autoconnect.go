@@ -0,0 +1,99 @@
+package ui
+
+import (
+	"fmt"
+
+	"openvpn3-tui/internal/autoconnect"
+	"openvpn3-tui/internal/config"
+	"openvpn3-tui/internal/network"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// networkMsg is sent when the network may have changed
+type networkMsg struct {
+	state network.State
+	err   error
+}
+
+// watchNetwork reads the network state, after waiting for a change unless
+// this is the first read
+func watchNetwork(src network.Source, first bool) tea.Cmd {
+	return func() tea.Msg {
+		if !first {
+			<-src.Changes()
+		}
+		st, err := src.State()
+		return networkMsg{state: st, err: err}
+	}
+}
+
+// handleNetwork fires the first matching auto-connect rule when the machine
+// moved to another network. Staying on the same network never fires a rule
+// again, so profiles the user connected or disconnected by hand stay that way.
+func (m Model) handleNetwork(msg networkMsg) (tea.Model, tea.Cmd) {
+	watch := watchNetwork(m.netSource, false)
+	if msg.err != nil {
+		// Report a failing source once instead of on every poll
+		if msg.err.Error() != m.netErr {
+			m.netErr = msg.err.Error()
+			m.errorMsg = fmt.Sprintf("Auto-connect: %v", msg.err)
+		}
+		return m, watch
+	}
+	m.netErr = ""
+
+	// The gateway's hardware address can show up a poll or two after the
+	// network does. Rules get a second look then, unless one already fired.
+	key := msg.state.Key()
+	lateMAC := m.netMAC == "" && msg.state.GatewayMAC != "" && !m.netMatched
+	if key == m.netKey && !lateMAC {
+		return m, watch
+	}
+	m.netKey = key
+	m.netMAC = msg.state.GatewayMAC
+
+	rules := m.config.AutoConnect.Rules
+	index, ok := autoconnect.Evaluate(rules, msg.state)
+	m.netMatched = ok
+	if !ok {
+		return m, watch
+	}
+	rule := rules[index]
+	name := autoconnect.RuleName(rule, index)
+
+	if m.config.AutoConnect.DryRun {
+		m.statusMsg = fmt.Sprintf("Dry run: rule %s on %s would %s", name, msg.state, autoconnect.Actions(rule))
+		return m, watch
+	}
+
+	connect, disconnect, err := m.ruleProfiles(rule)
+	if err != nil {
+		m.errorMsg = fmt.Sprintf("Rule %s: %v", name, err)
+		return m, watch
+	}
+	m.statusMsg = fmt.Sprintf("Rule %s on %s: %s", name, msg.state, autoconnect.Actions(rule))
+	return m, tea.Batch(watch, m.ensureProfiles(fmt.Sprintf("rule %s changed", name), connect, disconnect))
+}
+
+// ruleProfiles looks up the profiles a rule connects and disconnects
+func (m Model) ruleProfiles(rule config.Rule) (connect, disconnect []config.Profile, err error) {
+	lookup := func(names []string) ([]config.Profile, error) {
+		var profiles []config.Profile
+		for _, name := range names {
+			index, ok := m.config.FindProfile(name)
+			if !ok {
+				return nil, fmt.Errorf("unknown profile %q", name)
+			}
+			profiles = append(profiles, m.config.Profiles[index])
+		}
+		return profiles, nil
+	}
+	if connect, err = lookup(rule.Connect); err != nil {
+		return nil, nil, err
+	}
+	if disconnect, err = lookup(rule.Disconnect); err != nil {
+		return nil, nil, err
+	}
+	return connect, disconnect, nil
+}
@@ -0,0 +1,115 @@
+package ui
+
+import (
+	"testing"
+
+	"openvpn3-tui/internal/config"
+	"openvpn3-tui/internal/network"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TestHandleNetwork feeds network changes through the watch loop the way the
+// TUI does: rules fire once per network, never while offline
+func TestHandleNetwork(t *testing.T) {
+	src := network.NewStatic(network.State{Interface: "wlan0"})
+	m := Model{
+		config: &config.Config{
+			Profiles: []config.Profile{{Name: "Work", Path: "/vpn/work.ovpn"}},
+			AutoConnect: &config.AutoConnect{Rules: []config.Rule{
+				{Name: "home", When: config.Match{SSID: []string{"HomeNet"}}, Disconnect: []string{"Work"}},
+				{Name: "away", When: config.Match{SSID: []string{"!HomeNet"}}, Connect: []string{"Work"}},
+			}},
+		},
+		netSource: src,
+	}
+	home := network.State{Interface: "wlan0", Gateway: "192.168.1.1", SSID: "HomeNet"}
+	cafe := network.State{Interface: "wlan0", Gateway: "172.16.0.1", SSID: "Free WiFi"}
+
+	// step hands the next network message to the model and returns the
+	// command it continues with. The watch blocks until the network changes.
+	step := func(cmd tea.Cmd) tea.Cmd {
+		t.Helper()
+		msg := cmd()
+		if batch, ok := msg.(tea.BatchMsg); ok {
+			// The watch comes first, followed by connecting and disconnecting
+			msg = batch[0]()
+		}
+		m.statusMsg = ""
+		next, cont := m.handleNetwork(msg.(networkMsg))
+		m = next.(Model)
+		return cont
+	}
+
+	cmd := step(watchNetwork(src, true))
+	if m.statusMsg != "" {
+		t.Errorf("offline: status %q, want no rule", m.statusMsg)
+	}
+
+	tests := []struct {
+		state network.State
+		want  string
+	}{
+		{home, `Rule home on Wi-Fi "HomeNet" (wlan0 via 192.168.1.1): disconnect Work`},
+		{home, ""}, // Same network, profiles changed by hand stay that way
+		{cafe, `Rule away on Wi-Fi "Free WiFi" (wlan0 via 172.16.0.1): connect Work`},
+		{network.State{Interface: "wlan0"}, ""},
+		{home, `Rule home on Wi-Fi "HomeNet" (wlan0 via 192.168.1.1): disconnect Work`},
+	}
+	for i, tt := range tests {
+		src.Set(tt.state)
+		cmd = step(cmd)
+		if m.statusMsg != tt.want {
+			t.Errorf("change %d to %s: status %q, want %q", i, tt.state, m.statusMsg, tt.want)
+		}
+	}
+
+	m.config.AutoConnect.DryRun = true
+	src.Set(cafe)
+	step(cmd)
+	if want := `Dry run: rule away on Wi-Fi "Free WiFi" (wlan0 via 172.16.0.1) would connect Work`; m.statusMsg != want {
+		t.Errorf("dry run: status %q, want %q", m.statusMsg, want)
+	}
+}
+
+// TestHandleNetworkLateMAC runs the rules again when the gateway's hardware
+// address shows up after the network, but only if none fired before
+func TestHandleNetworkLateMAC(t *testing.T) {
+	const mac = "3c:a6:2f:11:22:33"
+	office := network.State{Interface: "eth0", Gateway: "10.0.0.1"}
+	known := office
+	known.GatewayMAC = mac
+
+	tests := []struct {
+		name  string
+		rules []config.Rule
+		want  []string // Status after each of office, known and known again
+	}{
+		{
+			name:  "rule needs the address",
+			rules: []config.Rule{{Name: "office", When: config.Match{Gateway: []string{mac}}, Connect: []string{"Work"}}},
+			want:  []string{"", "Rule office on eth0 via 10.0.0.1: connect Work", ""},
+		},
+		{
+			name:  "rule fired without it",
+			rules: []config.Rule{{Name: "wired", When: config.Match{Gateway: []string{"10.0.0.0/8"}}, Connect: []string{"Work"}}},
+			want:  []string{"Rule wired on eth0 via 10.0.0.1: connect Work", "", ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := Model{config: &config.Config{
+				Profiles:    []config.Profile{{Name: "Work", Path: "/vpn/work.ovpn"}},
+				AutoConnect: &config.AutoConnect{Rules: tt.rules},
+			}}
+			for i, state := range []network.State{office, known, known} {
+				m.statusMsg = ""
+				next, _ := m.handleNetwork(networkMsg{state: state})
+				m = next.(Model)
+				if m.statusMsg != tt.want[i] {
+					t.Errorf("poll %d: status %q, want %q", i, m.statusMsg, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"openvpn3-tui/internal/certs"
+	"openvpn3-tui/internal/ovpn"
+)
+
+// certInfo holds the certificates read from a profile
+type certInfo struct {
+	certs []certs.Certificate
+	err   error
+}
+
+// certCache remembers the certificates read for each config file, so that
+// PKCS#12 bundles only go through openssl again when a file changed
+type certCache struct {
+	mu      sync.Mutex
+	entries map[string]certEntry // By config file path
+}
+
+// certEntry holds the certificates read for a version of a config file
+type certEntry struct {
+	stamp string
+	info  certInfo
+}
+
+// newCertCache returns an empty certificate cache
+func newCertCache() *certCache {
+	return &certCache{entries: make(map[string]certEntry)}
+}
+
+// load returns the certificates of the config parsed from path, reading them
+// again only when the config or one of the files it references changed
+func (c *certCache) load(path string, cfg *ovpn.Config) certInfo {
+	stamp := fileStamp(path, cfg)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[path]; ok && e.stamp == stamp {
+		return e.info
+	}
+	found, err := certs.Load(cfg)
+	info := certInfo{certs: found, err: err}
+	c.entries[path] = certEntry{stamp: stamp, info: info}
+	return info
+}
+
+// fileStamp identifies the versions of a config file and the files it
+// references by their size and modification time
+func fileStamp(path string, cfg *ovpn.Config) string {
+	paths := []string{path}
+	for _, ref := range cfg.ExternalFiles() {
+		paths = append(paths, ref.Path)
+	}
+	var b strings.Builder
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			fmt.Fprintf(&b, "%s missing\n", p)
+			continue
+		}
+		fmt.Fprintf(&b, "%s %d %d\n", p, info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String()
+}
+
+// certExpiring returns the first expiring certificate of a profile if it
+// expires within the configured warning window
+func (m Model) certExpiring(index int) (certs.Certificate, bool) {
+	soonest, ok := certs.Soonest(m.profileCerts[index].certs)
+	if !ok || !soonest.ExpiresWithin(m.config.CertWarningWindow(), time.Now()) {
+		return certs.Certificate{}, false
+	}
+	return soonest, true
+}
+
+// certBadge flags profiles whose certificates have expired or expire soon
+func (m Model) certBadge(index int) string {
+	c, ok := m.certExpiring(index)
+	if !ok {
+		return ""
+	}
+	now := time.Now()
+	if c.Expired(now) {
+		return m.styles.Error.Render("[cert expired]")
+	}
+	return m.styles.Paused.Render(fmt.Sprintf("[cert %dd]", c.DaysLeft(now)))
+}
+
+// certWarning summarizes expiring certificates across all profiles for the
+// startup message, or returns an empty string when none expire soon
+func (m Model) certWarning() string {
+	var names []string
+	now := time.Now()
+	for i, p := range m.config.Profiles {
+		c, ok := m.certExpiring(i)
+		if !ok {
+			continue
+		}
+		if c.Expired(now) {
+			names = append(names, p.Name+" (expired)")
+		} else {
+			names = append(names, fmt.Sprintf("%s (%s)", p.Name, daysLeft(c.DaysLeft(now))))
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	return "Certificates expiring: " + strings.Join(names, ", ")
+}
+
+// daysLeft formats a remaining number of days
+func daysLeft(days int) string {
+	switch days {
+	case 0:
+		return "today"
+	case 1:
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", days)
+}
+
+// renderCertSummary shows when the first certificate of a profile expires
+func (m Model) renderCertSummary(index int) string {
+	info := m.profileCerts[index]
+	c, ok := certs.Soonest(info.certs)
+	if !ok {
+		if info.err != nil {
+			return detailRow("Certificate", m.styles.Error.Render(info.err.Error()))
+		}
+		return ""
+	}
+
+	now := time.Now()
+	expiry := c.NotAfter.Format("2006-01-02")
+	switch {
+	case c.Expired(now):
+		expiry = m.styles.Error.Render("expired " + expiry)
+	case c.ExpiresWithin(m.config.CertWarningWindow(), now):
+		expiry = m.styles.Paused.Render(fmt.Sprintf("%s (%s)", expiry, daysLeft(c.DaysLeft(now))))
+	default:
+		expiry = fmt.Sprintf("%s (%s)", expiry, daysLeft(c.DaysLeft(now)))
+	}
+	return detailRow("Expires", expiry)
+}
+
+// renderCertDetail lists every certificate of the selected profile
+func (m Model) renderCertDetail() string {
+	var b strings.Builder
+
+	index, ok := m.selectedProfile()
+	if !ok {
+		b.WriteString(m.styles.Subtitle.Render("No profile selected"))
+		return m.styles.Box.Render(b.String())
+	}
+
+	info := m.profileCerts[index]
+	b.WriteString(m.styles.Subtitle.Render("Certificates: " + m.config.Profiles[index].Name))
+	b.WriteString("\n\n")
+
+	if len(info.certs) == 0 && info.err == nil {
+		b.WriteString(m.styles.Muted.Render("No certificates found"))
+		b.WriteString("\n")
+	}
+
+	now := time.Now()
+	for i, c := range info.certs {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(m.styles.DetailTitle.Render(c.Source))
+		if c.IsCA {
+			b.WriteString(" " + m.styles.Muted.Render("(CA)"))
+		}
+		b.WriteString("\n")
+		b.WriteString(detailRow("Subject", c.Subject))
+		b.WriteString(detailRow("Issuer", c.Issuer))
+		if len(c.SANs) > 0 {
+			b.WriteString(detailRow("SANs", strings.Join(c.SANs, ", ")))
+		}
+		b.WriteString(detailRow("Valid from", c.NotBefore.Format("2006-01-02 15:04")))
+
+		until := c.NotAfter.Format("2006-01-02 15:04")
+		switch {
+		case c.Expired(now):
+			until = m.styles.Error.Render(until + " (expired)")
+		case c.ExpiresWithin(m.config.CertWarningWindow(), now):
+			until = m.styles.Paused.Render(fmt.Sprintf("%s (%s)", until, daysLeft(c.DaysLeft(now))))
+		}
+		b.WriteString(detailRow("Valid until", until))
+	}
+
+	if info.err != nil {
+		b.WriteString("\n")
+		b.WriteString(m.styles.Error.Render(info.err.Error()))
+	}
+
+	b.WriteString("\n")
+	b.WriteString(m.styles.Help.Render("press any key to close"))
+	return m.styles.Box.Render(b.String())
+}
@@ -0,0 +1,46 @@
+package ui
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"openvpn3-tui/internal/ovpn"
+)
+
+func TestCertCache(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "work.ovpn")
+	ca := filepath.Join(dir, "ca.crt")
+	if err := os.WriteFile(path, []byte("client\nremote vpn.example.com 1194\nca ca.crt\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(ca, []byte("not a certificate\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := ovpn.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := newCertCache()
+	cache.load(path, cfg)
+
+	// Unchanged files are served from the cache
+	cached := errors.New("cached")
+	e := cache.entries[path]
+	e.info = certInfo{err: cached}
+	cache.entries[path] = e
+	if got := cache.load(path, cfg); got.err != cached {
+		t.Errorf("unchanged files: err = %v, want the cached entry", got.err)
+	}
+
+	// A changed referenced file is read again
+	if err := os.WriteFile(ca, []byte("still not a certificate\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := cache.load(path, cfg); got.err == cached {
+		t.Error("changed ca file: got the cached entry, want a reload")
+	}
+}
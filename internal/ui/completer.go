@@ -12,6 +12,7 @@ type PathCompleter struct {
 	suggestions    []string
 	selectedIndex  int
 	maxSuggestions int
+	extensions     []string // File extensions offered besides directories
 }
 
 // NewPathCompleter creates a new path completer
@@ -19,9 +20,26 @@ func NewPathCompleter() *PathCompleter {
 	return &PathCompleter{
 		maxSuggestions: 5,
 		selectedIndex:  -1,
+		extensions:     []string{".ovpn"},
 	}
 }
 
+// SetExtensions changes which files are suggested
+func (c *PathCompleter) SetExtensions(exts ...string) {
+	c.extensions = exts
+}
+
+// hasExtension reports whether name ends in one of the suggested extensions
+func (c *PathCompleter) hasExtension(name string) bool {
+	lower := strings.ToLower(name)
+	for _, ext := range c.extensions {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
 // Update refreshes suggestions based on the current input
 func (c *PathCompleter) Update(input string) {
 	c.suggestions = c.getSuggestions(input)
@@ -88,8 +106,7 @@ func (c *PathCompleter) getSuggestions(input string) []string {
 		if entry.IsDir() {
 			fullPath += "/"
 			matches = append(matches, fullPath)
-		} else if strings.HasSuffix(strings.ToLower(name), ".ovpn") {
-			// Only show .ovpn files
+		} else if c.hasExtension(name) {
 			matches = append(matches, fullPath)
 		}
 	}
@@ -0,0 +1,211 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"openvpn3-tui/internal/config"
+	"openvpn3-tui/internal/conflict"
+	"openvpn3-tui/internal/openvpn"
+	"openvpn3-tui/internal/ovpn"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// conflictsMsg carries the conflicts of the running sessions
+type conflictsMsg struct {
+	found map[string][]conflict.Conflict // By session path
+	err   error                          // Failed to remember footprints
+}
+
+// conflictCheckMsg carries what connecting a profile would conflict with
+type conflictCheckMsg struct {
+	profile   string
+	conflicts []conflict.Conflict
+}
+
+// openFootprints loads the remembered footprints of the profiles
+func (m *Model) openFootprints() error {
+	path, err := config.FootprintsPath()
+	if err != nil {
+		return err
+	}
+	// A store that failed to load still remembers new sessions
+	m.footprints, err = conflict.OpenStore(path)
+	if err != nil {
+		return fmt.Errorf("Failed to load footprints: %w", err)
+	}
+	return nil
+}
+
+// sessionOwners returns the footprint of every session with a tunnel device,
+// named after its profile when it has one
+func (m Model) sessionOwners(snap *conflict.Snapshot, sessions []openvpn.Session) ([]conflict.Owner, []openvpn.Session) {
+	m.sessions = sessions
+	var owners []conflict.Owner
+	var owned []openvpn.Session
+	for _, s := range sessions {
+		if s.Device == "" {
+			continue
+		}
+		name := s.ConfigName
+		if p, ok := m.sessionProfile(s); ok {
+			name = p.Name
+		}
+		owners = append(owners, conflict.Owner{Name: name, Footprint: snap.Device(s.Device)})
+		owned = append(owned, s)
+	}
+	return owners, owned
+}
+
+// analyzeConflicts finds the conflicts between sessions and with the local
+// network, and remembers what each profile's session added
+func (m Model) analyzeConflicts(sessions []openvpn.Session) tea.Cmd {
+	if len(sessions) == 0 {
+		return func() tea.Msg { return conflictsMsg{} }
+	}
+	return func() tea.Msg {
+		snap, err := conflict.Read()
+		if err != nil {
+			return conflictsMsg{}
+		}
+		owners, owned := m.sessionOwners(snap, sessions)
+
+		msg := conflictsMsg{found: make(map[string][]conflict.Conflict)}
+		for i, found := range conflict.Analyze(owners, snap.LAN()) {
+			if len(found) > 0 {
+				msg.found[owned[i].Path] = found
+			}
+		}
+
+		remembered := make(map[string]conflict.Footprint)
+		for i, s := range owned {
+			p, ok := m.sessionProfile(s)
+			if ok && s.Connected() && !owners[i].Empty() {
+				remembered[p.Name] = owners[i].Footprint
+			}
+		}
+		if m.footprints != nil {
+			msg.err = m.footprints.Update(remembered)
+		}
+		return msg
+	}
+}
+
+// handleConflicts stores the conflicts of the running sessions
+func (m Model) handleConflicts(msg conflictsMsg) (tea.Model, tea.Cmd) {
+	m.conflicts = msg.found
+	if msg.err != nil {
+		m.errorMsg = fmt.Sprintf("Failed to save footprints: %v", msg.err)
+	}
+	return m, nil
+}
+
+// checkConflicts predicts what a profile adds from its config and its last
+// session, and compares that with the running sessions and the local network
+func (m Model) checkConflicts(profile config.Profile) tea.Cmd {
+	return func() tea.Msg {
+		msg := conflictCheckMsg{profile: profile.Name}
+		var f conflict.Footprint
+		if cfg, err := ovpn.ParseFile(profile.Path); err == nil {
+			f = conflict.Predict(cfg)
+		}
+		if m.footprints != nil {
+			f = conflict.Merge(f, m.footprints.Get(profile.Name))
+		}
+		if f.Empty() {
+			return msg
+		}
+
+		// Without a snapshot or sessions there is nothing to compare with;
+		// connecting reports its own errors
+		snap, err := conflict.Read()
+		if err != nil {
+			return msg
+		}
+		sessions, err := m.client.ListSessions()
+		if err != nil {
+			return msg
+		}
+		owners, _ := m.sessionOwners(snap, m.unrelatedSessions(profile, sessions))
+		others := append([]conflict.Owner{{Name: conflict.LAN, Footprint: snap.LAN()}}, owners...)
+		msg.conflicts = conflict.Find(f, others)
+		return msg
+	}
+}
+
+// unrelatedSessions leaves out the sessions connecting a profile takes care
+// of: its own, those of the exclusive siblings it disconnects and those of the
+// profiles it depends on, whose routes usually cover its own
+func (m Model) unrelatedSessions(profile config.Profile, sessions []openvpn.Session) []openvpn.Session {
+	related := append([]config.Profile{profile}, m.config.ExclusiveSiblings(profile.Name)...)
+	if deps, err := m.config.DependencyOrder(profile.Name); err == nil {
+		related = append(related, deps...)
+	}
+	skip := make(map[string]bool)
+	for _, p := range related {
+		for _, s := range sessionsFor(sessions, p.Path) {
+			skip[s.Path] = true
+		}
+	}
+
+	var others []openvpn.Session
+	for _, s := range sessions {
+		if !skip[s.Path] {
+			others = append(others, s)
+		}
+	}
+	return others
+}
+
+// handleConflictCheck connects a profile, or asks first when it would conflict
+func (m Model) handleConflictCheck(msg conflictCheckMsg) (tea.Model, tea.Cmd) {
+	m.loading = false
+	index, ok := m.config.FindProfile(msg.profile)
+	if !ok {
+		return m, nil
+	}
+	if len(msg.conflicts) == 0 {
+		return m.startConnect(index)
+	}
+	m.statusMsg = ""
+	m.confirmMode = ConfirmConnectConflicts
+	m.confirmTarget = msg.profile
+	m.confirmIndex = index
+	m.confirmConflicts = msg.conflicts
+	return m, nil
+}
+
+// conflictBadge marks a session that conflicts with another or the local network
+func (m Model) conflictBadge(s openvpn.Session) string {
+	if len(m.conflicts[s.Path]) == 0 {
+		return ""
+	}
+	return m.styles.Paused.Render("[conflict]")
+}
+
+// renderConflictDetail lists the conflicts of a session
+func (m Model) renderConflictDetail(s openvpn.Session) string {
+	var out string
+	for i, c := range m.conflicts[s.Path] {
+		label := ""
+		if i == 0 {
+			label = "Conflicts"
+		}
+		out += detailRow(label, m.styles.Paused.Render(c.String()))
+	}
+	return out
+}
+
+// renderConflictList lists conflicts for the connect confirmation, up to limit
+func renderConflictList(conflicts []conflict.Conflict, limit int) string {
+	var b strings.Builder
+	for i, c := range conflicts {
+		if i == limit {
+			b.WriteString(fmt.Sprintf("  … and %d more\n", len(conflicts)-limit))
+			break
+		}
+		b.WriteString("  • " + c.String() + "\n")
+	}
+	return b.String()
+}
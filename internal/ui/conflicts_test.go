@@ -0,0 +1,50 @@
+package ui
+
+import (
+	"slices"
+	"testing"
+
+	"openvpn3-tui/internal/config"
+	"openvpn3-tui/internal/openvpn"
+)
+
+func TestUnrelatedSessions(t *testing.T) {
+	m := Model{config: &config.Config{
+		Profiles: []config.Profile{
+			{Name: "EU", Path: "/vpn/eu.ovpn"},
+			{Name: "US", Path: "/vpn/us.ovpn"},
+			{Name: "Jump", Path: "/vpn/jump.ovpn"},
+			{Name: "Internal", Path: "/vpn/internal.ovpn", DependsOn: []string{"Jump"}},
+			{Name: "Lab", Path: "/vpn/lab.ovpn"},
+		},
+		Exclusive: []config.ExclusiveGroup{{Name: "region", Profiles: []string{"EU", "US"}}},
+	}}
+	sessions := []openvpn.Session{
+		{Path: "/s/us", ConfigName: "us"},
+		{Path: "/s/jump", ConfigName: "jump"},
+		{Path: "/s/lab", ConfigName: "lab"},
+		{Path: "/s/other", ConfigName: "other"},
+	}
+
+	tests := []struct {
+		profile string
+		want    []string
+	}{
+		// The region being switched away from is disconnected first
+		{"EU", []string{"/s/jump", "/s/lab", "/s/other"}},
+		// The jump VPN routes what Internal needs by design
+		{"Internal", []string{"/s/us", "/s/lab", "/s/other"}},
+		// A running session of the profile itself is no conflict either
+		{"Lab", []string{"/s/us", "/s/jump", "/s/other"}},
+	}
+	for _, tt := range tests {
+		index, _ := m.config.FindProfile(tt.profile)
+		var got []string
+		for _, s := range m.unrelatedSessions(m.config.Profiles[index], sessions) {
+			got = append(got, s.Path)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("unrelatedSessions(%s) = %q, want %q", tt.profile, got, tt.want)
+		}
+	}
+}
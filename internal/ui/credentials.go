@@ -0,0 +1,443 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"openvpn3-tui/internal/config"
+	"openvpn3-tui/internal/credentials"
+	"openvpn3-tui/internal/openvpn"
+	"openvpn3-tui/internal/ovpn"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// openCredentials opens the credential store selected in the config
+func (m *Model) openCredentials() error {
+	store, err := credentials.Open(m.config.CredentialStore)
+	if err != nil {
+		return err
+	}
+	m.creds = store
+	m.loadCredentialList()
+	return nil
+}
+
+// loadCredentialList refreshes which profiles have saved credentials. The
+// list stays unknown while the credential file is locked.
+func (m *Model) loadCredentialList() {
+	m.credSaved = nil
+	m.otpSecrets = make(map[string]*credentials.TOTP)
+	if m.creds == nil || m.creds.Locked() {
+		return
+	}
+	names, err := m.creds.List()
+	if err != nil {
+		return
+	}
+	m.credSaved = make(map[string]bool)
+	for _, name := range names {
+		m.credSaved[name] = true
+	}
+}
+
+// startUnlock asks for the passphrase of the credential file and continues
+// with then once it is unlocked
+func (m Model) startUnlock(then func(Model) (tea.Model, tea.Cmd)) (tea.Model, tea.Cmd) {
+	m.afterUnlock = then
+	m.inputMode = InputPassphrase
+	m.textInput.SetValue("")
+	m.textInput.EchoMode = textinput.EchoPassword
+	m.textInput.Placeholder = "Passphrase"
+	m.textInput.Focus()
+	return m, textinput.Blink
+}
+
+// unlockCredentials unlocks the credential file and runs the pending action.
+// A new passphrase is asked for twice, since a typo would lock the user out
+// of the file it creates.
+func (m Model) unlockCredentials(passphrase string) (tea.Model, tea.Cmd) {
+	switch {
+	case m.inputMode == InputPassphrase && passphrase != "" && !m.credentialFileExists():
+		m.clearMessages()
+		m.passphrase = passphrase
+		m.inputMode = InputPassphraseConfirm
+		m.textInput.SetValue("")
+		return m, nil
+	case m.inputMode == InputPassphraseConfirm && passphrase != m.passphrase:
+		m.errorMsg = "Passphrases do not match, choose one again"
+		m.passphrase = ""
+		m.inputMode = InputPassphrase
+		m.textInput.SetValue("")
+		return m, nil
+	}
+
+	if err := m.creds.Unlock(passphrase); err != nil {
+		// Stay in the prompt so the passphrase can be typed again
+		m.errorMsg = err.Error()
+		m.textInput.SetValue("")
+		return m, nil
+	}
+
+	m.clearMessages()
+	then := m.afterUnlock
+	m = m.endCredentialInput()
+	m.loadCredentialList()
+	if then == nil {
+		return m, nil
+	}
+	return then(m)
+}
+
+// startCredentials asks for the username and password of a profile. With
+// connect the profile is connected once they are saved.
+func (m Model) startCredentials(index int, connect bool) (tea.Model, tea.Cmd) {
+	if m.creds == nil {
+		m.errorMsg = "No credential store available"
+		return m, nil
+	}
+	if m.creds.Locked() {
+		return m.startUnlock(func(m Model) (tea.Model, tea.Cmd) {
+			return m.startCredentials(index, connect)
+		})
+	}
+
+	profile := m.config.Profiles[index]
+	saved, _, err := m.creds.Get(profile.Name)
+	if err != nil {
+		m.errorMsg = fmt.Sprintf("Failed to read credentials: %v", err)
+		return m, nil
+	}
+
+	m.inputMode = InputCredUsername
+	m.editIndex = index
+	m.newProfile = profile
+	m.credEntry = saved
+	m.credConnect = connect
+	m.textInput.SetValue(saved.Username)
+	m.textInput.CursorEnd()
+	m.textInput.Placeholder = "Username"
+	m.textInput.Focus()
+	return m, textinput.Blink
+}
+
+// startPassword moves from the username to the password prompt
+func (m Model) startPassword(username string) (tea.Model, tea.Cmd) {
+	m.credEntry.Username = username
+	m.inputMode = InputCredPassword
+	m.textInput.SetValue("")
+	m.textInput.EchoMode = textinput.EchoPassword
+	m.textInput.Placeholder = "Password"
+	if m.credEntry.Password != "" {
+		m.textInput.Placeholder = "Password (empty keeps the saved one)"
+	}
+	return m, nil
+}
+
+// saveCredentials stores the entered credentials of the profile being edited
+func (m Model) saveCredentials(password string) (tea.Model, tea.Cmd) {
+	if password != "" {
+		m.credEntry.Password = password
+	}
+	if m.credEntry.Password == "" {
+		m.errorMsg = "Enter a password"
+		return m, nil
+	}
+
+	name := m.newProfile.Name
+	if err := m.creds.Set(name, m.credEntry); err != nil {
+		m.errorMsg = fmt.Sprintf("Failed to save credentials: %v", err)
+		return m, nil
+	}
+
+	index, connect := m.editIndex, m.credConnect
+	m = m.endCredentialInput()
+	m.loadCredentialList()
+	m.clearMessages()
+	m.statusMsg = fmt.Sprintf("Saved credentials for %s in the %s", name, m.creds.Name())
+	if connect {
+		return m.connectProfile(index)
+	}
+	return m, nil
+}
+
+// endCredentialInput leaves the credential and passphrase prompts
+func (m Model) endCredentialInput() Model {
+	m.inputMode = InputNone
+	m.textInput.EchoMode = textinput.EchoNormal
+	m.textInput.SetValue("")
+	m.newProfile = config.Profile{}
+	m.editIndex = -1
+	m.credEntry = credentials.Credentials{}
+	m.credConnect = false
+	m.afterUnlock = nil
+	m.passphrase = ""
+	return m
+}
+
+// confirmForget asks before removing the saved credentials of the selected profile
+func (m Model) confirmForget() (tea.Model, tea.Cmd) {
+	m.clearMessages()
+	index, ok := m.selectedProfile()
+	if !ok {
+		m.errorMsg = "Select a profile first"
+		return m, nil
+	}
+	if m.creds == nil {
+		m.errorMsg = "No credential store available"
+		return m, nil
+	}
+	if m.creds.Locked() {
+		return m.startUnlock(func(m Model) (tea.Model, tea.Cmd) {
+			return m.confirmForget()
+		})
+	}
+
+	name := m.config.Profiles[index].Name
+	if !m.credSaved[name] {
+		m.errorMsg = fmt.Sprintf("No credentials saved for %s", name)
+		return m, nil
+	}
+	m.confirmMode = ConfirmForgetCredentials
+	m.confirmTarget = name
+	m.confirmIndex = index
+	return m, nil
+}
+
+// forgetCredentials removes the saved credentials of a profile
+func (m *Model) forgetCredentials(name string) {
+	if err := m.creds.Delete(name); err != nil {
+		m.errorMsg = fmt.Sprintf("Failed to forget credentials: %v", err)
+		return
+	}
+	m.loadCredentialList()
+	m.statusMsg = fmt.Sprintf("Forgot credentials for %s", name)
+}
+
+// renameCredentials keeps saved credentials with a renamed profile
+func (m *Model) renameCredentials(from, to string) {
+	if from == to || !m.credSaved[from] {
+		return
+	}
+	if err := credentials.Move(m.creds, from, to); err != nil {
+		m.errorMsg = fmt.Sprintf("Failed to move credentials: %v", err)
+	}
+	m.loadCredentialList()
+}
+
+// answerer returns a function answering openvpn3 prompts with the saved
+// credentials of a profile. The store is only read once a prompt appears, so
+// profiles without authentication never need the credential file unlocked.
+// Errors reading the store are kept in errp.
+func (m Model) answerer(profile config.Profile, errp *error) openvpn.AnswerFunc {
+	var saved *credentials.Credentials
+	var challenge string
+	if cfg, err := ovpn.ParseFile(profile.Path); err == nil {
+		challenge = cfg.StaticChallenge()
+	}
+
+	return func(prompt string) (string, bool) {
+		if m.creds == nil {
+			return "", false
+		}
+		if saved == nil {
+			c, _, err := m.creds.Get(profile.Name)
+			if err != nil {
+				*errp = err
+				return "", false
+			}
+			saved = &c
+		}
+		// The static challenge text may not mention a code at all
+		if challenge != "" && strings.EqualFold(strings.TrimSpace(prompt), strings.TrimSuffix(challenge, ":")) {
+			return saved.OTPCode()
+		}
+		return saved.Answer(prompt)
+	}
+}
+
+// handleConnectError offers to fix what made a connection attempt fail: a
+// locked credential file or missing credentials
+func (m Model) handleConnectError(msg connectMsg) (tea.Model, tea.Cmd) {
+	index, found := m.config.FindProfile(msg.profile)
+	switch {
+	case found && errors.Is(msg.err, credentials.ErrLocked):
+		m.statusMsg = fmt.Sprintf("Unlock the credential file to connect %s", msg.profile)
+		return m.startUnlock(func(m Model) (tea.Model, tea.Cmd) {
+			return m.connectProfile(index)
+		})
+	case found && errors.Is(msg.err, openvpn.ErrAuthRequired) && m.credSaved != nil && !m.credSaved[msg.profile]:
+		m.statusMsg = fmt.Sprintf("%s needs a username and password", msg.profile)
+		return m.startCredentials(index, true)
+	case errors.Is(msg.err, openvpn.ErrAuthFailed):
+		m.errorMsg = fmt.Sprintf("Connection failed: %v (press %s to update the credentials)",
+			msg.err, m.keys.Credentials.Help().Key)
+	default:
+		m.errorMsg = fmt.Sprintf("Connection failed: %v", msg.err)
+	}
+	return m, nil
+}
+
+// renderCredentialSummary shows whether credentials are saved for a profile
+// that asks for them
+func (m Model) renderCredentialSummary(name string, cfg *ovpn.Config) string {
+	auth, file := cfg.AuthUserPass()
+	switch {
+	case m.credSaved[name]:
+		return detailRow("Credentials", m.styles.Success.Render("saved in the "+m.creds.Name())) + m.renderOTPCode(name)
+	case !auth || file != "" || m.creds == nil:
+		return ""
+	case m.creds.Locked() && m.credentialFileExists():
+		return detailRow("Credentials", m.styles.Muted.Render("credential file locked"))
+	}
+	return detailRow("Credentials", m.styles.Muted.Render(fmt.Sprintf("not saved (%s to save)", m.keys.Credentials.Help().Key)))
+}
+
+// credentialFileExists reports whether the encrypted credential file is in use
+// and has been created
+func (m Model) credentialFileExists() bool {
+	fs, ok := m.creds.(*credentials.FileStore)
+	return ok && fs.Exists()
+}
+
+// renderPassphraseHint explains what the passphrase protects
+func (m Model) renderPassphraseHint() string {
+	fs, ok := m.creds.(*credentials.FileStore)
+	if !ok {
+		return ""
+	}
+	if fs.Exists() {
+		return m.styles.Muted.Render("Decrypts "+CompactPath(fs.Path())) + "\n"
+	}
+	return m.styles.Muted.Render("No Secret Service is running, passwords are encrypted in "+CompactPath(fs.Path())) + "\n"
+}
+
+// startTOTP asks for the TOTP secret of a profile
+func (m Model) startTOTP(index int) (tea.Model, tea.Cmd) {
+	if m.creds == nil {
+		m.errorMsg = "No credential store available"
+		return m, nil
+	}
+	if m.creds.Locked() {
+		return m.startUnlock(func(m Model) (tea.Model, tea.Cmd) {
+			return m.startTOTP(index)
+		})
+	}
+
+	profile := m.config.Profiles[index]
+	saved, _, err := m.creds.Get(profile.Name)
+	if err != nil {
+		m.errorMsg = fmt.Sprintf("Failed to read credentials: %v", err)
+		return m, nil
+	}
+
+	m.inputMode = InputTOTPSecret
+	m.editIndex = index
+	m.newProfile = profile
+	m.credEntry = saved
+	m.textInput.SetValue("")
+	m.textInput.EchoMode = textinput.EchoPassword
+	m.textInput.Placeholder = "otpauth://totp/... URI or base32 secret"
+	if saved.OTP != nil {
+		m.textInput.Placeholder = "New otpauth:// URI or secret (empty removes the saved one)"
+	}
+	m.textInput.Focus()
+	return m, textinput.Blink
+}
+
+// saveTOTP stores the entered TOTP secret, or removes it when empty
+func (m Model) saveTOTP(value string) (tea.Model, tea.Cmd) {
+	name := m.newProfile.Name
+	entry := m.credEntry
+	var status string
+	switch {
+	case value != "":
+		otp, err := credentials.ParseTOTP(value)
+		if err != nil {
+			m.errorMsg = err.Error()
+			return m, nil
+		}
+		entry.OTP = &otp
+		code, _ := otp.Code(time.Now())
+		status = fmt.Sprintf("Saved the TOTP secret of %s, current code %s", name, code)
+	case entry.OTP == nil:
+		return m.endCredentialInput(), nil
+	default:
+		entry.OTP = nil
+		status = fmt.Sprintf("Removed the TOTP secret of %s", name)
+	}
+
+	var err error
+	if entry.IsZero() {
+		err = m.creds.Delete(name)
+	} else {
+		err = m.creds.Set(name, entry)
+	}
+	if err != nil {
+		m.errorMsg = fmt.Sprintf("Failed to save credentials: %v", err)
+		return m, nil
+	}
+
+	m = m.endCredentialInput()
+	m.loadCredentialList()
+	m.clearMessages()
+	m.statusMsg = status
+	return m, nil
+}
+
+// otpTickMsg refreshes the TOTP code shown in the detail pane
+type otpTickMsg time.Time
+
+// otpTick schedules the next refresh at the start of the next second
+func otpTick() tea.Cmd {
+	return tea.Every(time.Second, func(t time.Time) tea.Msg {
+		return otpTickMsg(t)
+	})
+}
+
+// loadSelectedOTP reads the TOTP secret of the selected profile once, so the
+// detail pane can show its code without querying the store on every render
+func (m *Model) loadSelectedOTP() {
+	if m.currentView != ViewProfiles || m.creds == nil {
+		return
+	}
+	index, ok := m.selectedProfile()
+	if !ok {
+		return
+	}
+	name := m.config.Profiles[index].Name
+	if _, loaded := m.otpSecrets[name]; loaded || !m.credSaved[name] {
+		return
+	}
+	saved, _, err := m.creds.Get(name)
+	if err != nil {
+		return
+	}
+	m.otpSecrets[name] = saved.OTP
+}
+
+// renderOTPCode shows the current TOTP code of a profile and how long it is valid
+func (m Model) renderOTPCode(name string) string {
+	otp := m.otpSecrets[name]
+	if otp == nil {
+		return ""
+	}
+	now := time.Now()
+	code, err := otp.Code(now)
+	if err != nil {
+		return detailRow("TOTP", m.styles.Error.Render(err.Error()))
+	}
+	// Group the digits for reading aloud, e.g. 123 456
+	if half := len(code) / 2; len(code) >= 6 {
+		code = code[:half] + " " + code[half:]
+	}
+	left := otp.Remaining(now)
+	style := m.styles.Muted
+	if left <= 5*time.Second {
+		style = m.styles.Paused
+	}
+	return detailRow("TOTP", m.styles.Connected.Render(code)+" "+style.Render(fmt.Sprintf("%ds left", int(left.Seconds()))))
+}
@@ -0,0 +1,45 @@
+package ui
+
+import (
+	"path/filepath"
+	"testing"
+
+	"openvpn3-tui/internal/credentials"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TestNewPassphrase asks for the passphrase of a new credential file twice
+// and starts over when the two differ
+func TestNewPassphrase(t *testing.T) {
+	store := credentials.NewFileStore(filepath.Join(t.TempDir(), "credentials.enc"))
+	m := Model{creds: store, textInput: textinput.New()}
+	unlocked := false
+	next, _ := m.startUnlock(func(m Model) (tea.Model, tea.Cmd) {
+		unlocked = true
+		return m, nil
+	})
+	m = next.(Model)
+
+	enter := func(passphrase string) {
+		t.Helper()
+		next, _ := m.unlockCredentials(passphrase)
+		m = next.(Model)
+	}
+
+	enter("correct horse")
+	if m.inputMode != InputPassphraseConfirm || !store.Locked() {
+		t.Fatalf("first entry: mode %v, locked %v, want the confirmation prompt", m.inputMode, store.Locked())
+	}
+	enter("correct hrose")
+	if m.inputMode != InputPassphrase || m.errorMsg == "" || !store.Locked() {
+		t.Fatalf("mismatch: mode %v, error %q, want the first prompt again", m.inputMode, m.errorMsg)
+	}
+
+	enter("correct horse")
+	enter("correct horse")
+	if store.Locked() || !unlocked || m.inputMode != InputNone {
+		t.Errorf("matching entries: locked %v, continued %v, mode %v, want unlocked", store.Locked(), unlocked, m.inputMode)
+	}
+}
@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"openvpn3-tui/internal/config"
+	"openvpn3-tui/internal/health"
+)
+
+// dependencyTimeout bounds how long connecting waits for a dependency to
+// report a connected client
+const dependencyTimeout = 30 * time.Second
+
+// dependencyPollInterval is how often the session list is checked meanwhile
+const dependencyPollInterval = 500 * time.Millisecond
+
+// connectWithDependencies connects the dependencies of a profile that are not
+// up yet, waits until all of them are connected and then connects the
+// profile. A failing dependency is reported under its own name so that
+// missing credentials are asked for it.
+func (m Model) connectWithDependencies(profile config.Profile) connectMsg {
+	deps, err := m.config.DependencyOrder(profile.Name)
+	if err != nil {
+		return connectMsg{profile: profile.Name, err: err}
+	}
+
+	var started []string
+	for _, dep := range deps {
+		sessions, err := m.client.ListSessions()
+		if err != nil {
+			return connectMsg{profile: profile.Name, err: err}
+		}
+		if len(sessionsFor(sessions, dep.Path)) == 0 {
+			res := m.connectExclusive(dep)
+			if res.err != nil {
+				res.err = fmt.Errorf("dependency %s: %w", dep.Name, res.err)
+				return res
+			}
+			started = append(started, dep.Name)
+		}
+		if err := m.waitConnected(dep); err != nil {
+			return connectMsg{profile: profile.Name, err: fmt.Errorf("dependency %s: %w", dep.Name, err)}
+		}
+		if dep.Health != nil && len(dep.Health.Checks) > 0 {
+			if err := health.Run(dep.Health).Error(); err != nil {
+				return connectMsg{profile: profile.Name, err: fmt.Errorf("dependency %s is unhealthy: %w", dep.Name, err)}
+			}
+		}
+	}
+
+	msg := m.connectExclusive(profile)
+	msg.dependencies = started
+	return msg
+}
+
+// waitConnected polls the session list until a session of the profile
+// reports a connected client, giving up after dependencyTimeout
+func (m Model) waitConnected(profile config.Profile) error {
+	deadline := time.Now().Add(dependencyTimeout)
+	for {
+		sessions, err := m.client.ListSessions()
+		if err != nil {
+			return err
+		}
+		matches := sessionsFor(sessions, profile.Path)
+		for _, s := range matches {
+			if s.Connected() {
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			if len(matches) == 0 {
+				return fmt.Errorf("no session after %s", dependencyTimeout)
+			}
+			return fmt.Errorf("not connected after %s: %s", dependencyTimeout, matches[0].Status)
+		}
+		time.Sleep(dependencyPollInterval)
+	}
+}
+
+// unneededDependencies returns the connected dependencies of a profile that
+// no other connected profile depends on, in the order to disconnect them
+func (m Model) unneededDependencies(profile config.Profile) []config.Profile {
+	deps, err := m.config.DependencyOrder(profile.Name)
+	if err != nil || len(deps) == 0 {
+		return nil
+	}
+	candidates := make(map[string]bool)
+	for _, dep := range deps {
+		if m.isProfileConnected(dep.Path) {
+			candidates[dep.Name] = true
+		}
+	}
+	for _, p := range m.config.Profiles {
+		// The sessions still list the profile that was just disconnected
+		if p.Path == profile.Path || candidates[p.Name] || !m.isProfileConnected(p.Path) {
+			continue
+		}
+		needed, _ := m.config.DependencyOrder(p.Name)
+		for _, dep := range needed {
+			delete(candidates, dep.Name)
+		}
+	}
+
+	// Dependents go down before what they depend on
+	var unneeded []config.Profile
+	for i := len(deps) - 1; i >= 0; i-- {
+		if candidates[deps[i].Name] {
+			unneeded = append(unneeded, deps[i])
+		}
+	}
+	return unneeded
+}
+
+// renderDependencySummary lists the profiles a profile depends on
+func (m Model) renderDependencySummary(profile config.Profile) string {
+	if len(profile.DependsOn) == 0 {
+		return ""
+	}
+	deps, err := m.config.DependencyOrder(profile.Name)
+	if err != nil {
+		return detailRow("Depends on", m.styles.Error.Render(err.Error()))
+	}
+	var indirect []config.Profile
+	for _, dep := range deps {
+		if !slices.Contains(profile.DependsOn, dep.Name) {
+			indirect = append(indirect, dep)
+		}
+	}
+	value := strings.Join(profile.DependsOn, ", ")
+	if len(indirect) > 0 {
+		value += m.styles.Muted.Render(" • also " + profileNames(indirect))
+	}
+	return detailRow("Depends on", value)
+}
@@ -0,0 +1,103 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"openvpn3-tui/internal/config"
+)
+
+// switchTimeout bounds how long a switch waits for siblings to disconnect
+const switchTimeout = 15 * time.Second
+
+// connectExclusive connects a profile after disconnecting the connected
+// members of its exclusive groups and waiting for their sessions to close.
+// When the profile fails to connect the previous members are connected again.
+func (m Model) connectExclusive(profile config.Profile) connectMsg {
+	siblings := m.config.ExclusiveSiblings(profile.Name)
+	if len(siblings) == 0 {
+		return m.connectWithHooks(profile)
+	}
+
+	msg := connectMsg{profile: profile.Name}
+	sessions, err := m.client.ListSessions()
+	if err != nil {
+		msg.err = err
+		return msg
+	}
+	// Hooks look up profiles in the current sessions
+	m.sessions = sessions
+
+	// Only siblings whose sessions all closed are connected again on failure
+	var previous []config.Profile
+	var closed []string
+	for _, sibling := range siblings {
+		matches := sessionsFor(sessions, sibling.Path)
+		if len(matches) == 0 {
+			continue
+		}
+		for _, s := range matches {
+			if res := m.disconnectWithHooks(s); res.err != nil {
+				msg.err = fmt.Errorf("disconnecting %s: %w", sibling.Name, res.err)
+				m.client.WaitClosed(closed, switchTimeout)
+				return m.rollback(msg, previous)
+			}
+			closed = append(closed, s.Path)
+		}
+		previous = append(previous, sibling)
+	}
+	if err := m.client.WaitClosed(closed, switchTimeout); err != nil {
+		msg.err = fmt.Errorf("waiting for %s to disconnect: %w", profileNames(previous), err)
+		return m.rollback(msg, previous)
+	}
+
+	msg = m.connectWithHooks(profile)
+	if msg.err != nil {
+		return m.rollback(msg, previous)
+	}
+	msg.switched = profileNames(previous)
+	return msg
+}
+
+// rollback connects the profiles that were up before a failed switch and
+// adds the outcome to the error
+func (m Model) rollback(msg connectMsg, previous []config.Profile) connectMsg {
+	var restored []string
+	for _, p := range previous {
+		if res := m.connectWithHooks(p); res.err != nil {
+			msg.err = fmt.Errorf("%w; reconnecting %s failed: %v", msg.err, p.Name, res.err)
+		} else {
+			restored = append(restored, p.Name)
+		}
+	}
+	if len(restored) > 0 {
+		msg.err = fmt.Errorf("%w; reconnected %s", msg.err, strings.Join(restored, ", "))
+	}
+	return msg
+}
+
+// profileNames joins the names of profiles for messages
+func profileNames(profiles []config.Profile) string {
+	names := make([]string, len(profiles))
+	for i, p := range profiles {
+		names[i] = p.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// renderExclusiveSummary lists the exclusive groups of a profile with the
+// members connecting it would disconnect
+func (m Model) renderExclusiveSummary(profile config.Profile) string {
+	var b strings.Builder
+	for _, g := range m.config.ExclusiveGroups(profile.Name) {
+		var others []string
+		for _, name := range g.Profiles {
+			if name != profile.Name {
+				others = append(others, name)
+			}
+		}
+		b.WriteString(detailRow("Exclusive", fmt.Sprintf("%s %s", g.Name, m.styles.Muted.Render("with "+strings.Join(others, ", ")))))
+	}
+	return b.String()
+}
@@ -0,0 +1,162 @@
+package ui
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"openvpn3-tui/internal/config"
+	"openvpn3-tui/internal/openvpn"
+)
+
+// stubClient keeps sessions in memory and records the calls made to it
+type stubClient struct {
+	sessions   []openvpn.Session
+	calls      []string
+	failOn     map[string]error // By call, e.g. "disconnect /s/us" or "connect eu"
+	waitFailed error
+}
+
+func (c *stubClient) fail(call string) error {
+	c.calls = append(c.calls, call)
+	return c.failOn[call]
+}
+
+func (c *stubClient) ListSessions() ([]openvpn.Session, error) {
+	return slices.Clone(c.sessions), nil
+}
+
+func (c *stubClient) GetSessionStats(string) (*openvpn.SessionStats, error) {
+	return &openvpn.SessionStats{}, nil
+}
+
+func (c *stubClient) Connect(configPath string, _ openvpn.AnswerFunc) error {
+	name := strings.TrimSuffix(configPath[strings.LastIndex(configPath, "/")+1:], ".ovpn")
+	if err := c.fail("connect " + name); err != nil {
+		return err
+	}
+	c.sessions = append(c.sessions, openvpn.Session{Path: "/s/" + name, ConfigName: name, Device: "tun0"})
+	return nil
+}
+
+func (c *stubClient) ConnectTo(configPath string, _ openvpn.Server, answer openvpn.AnswerFunc) error {
+	return c.Connect(configPath, answer)
+}
+
+func (c *stubClient) Disconnect(path string) error {
+	if err := c.fail("disconnect " + path); err != nil {
+		return err
+	}
+	c.sessions = slices.DeleteFunc(c.sessions, func(s openvpn.Session) bool { return s.Path == path })
+	return nil
+}
+
+func (c *stubClient) WaitClosed([]string, time.Duration) error {
+	c.calls = append(c.calls, "wait")
+	return c.waitFailed
+}
+
+func TestConnectExclusive(t *testing.T) {
+	errFailed := errors.New("failed")
+	tests := []struct {
+		name      string
+		connected []string // Sibling config names with a session
+		failOn    []string
+		waitFail  bool
+		calls     []string
+		sessions  []string // Config names connected afterwards
+		err       string
+	}{
+		{
+			name:      "switch",
+			connected: []string{"us"},
+			calls:     []string{"disconnect /s/us", "wait", "connect eu"},
+			sessions:  []string{"eu"},
+		},
+		{
+			name:      "disconnect fails",
+			connected: []string{"us"},
+			failOn:    []string{"disconnect /s/us"},
+			calls:     []string{"disconnect /s/us", "wait"},
+			sessions:  []string{"us"},
+			err:       "disconnecting US: failed",
+		},
+		{
+			name:      "second sibling fails to disconnect",
+			connected: []string{"us", "apac"},
+			failOn:    []string{"disconnect /s/apac"},
+			calls:     []string{"disconnect /s/us", "disconnect /s/apac", "wait", "connect us"},
+			sessions:  []string{"apac", "us"},
+			err:       "disconnecting APAC: failed; reconnected US",
+		},
+		{
+			name:      "wait times out",
+			connected: []string{"us"},
+			waitFail:  true,
+			calls:     []string{"disconnect /s/us", "wait", "connect us"},
+			sessions:  []string{"us"},
+			err:       "waiting for US to disconnect: failed; reconnected US",
+		},
+		{
+			name:      "connect fails",
+			connected: []string{"us"},
+			failOn:    []string{"connect eu"},
+			calls:     []string{"disconnect /s/us", "wait", "connect eu", "connect us"},
+			sessions:  []string{"us"},
+			err:       "failed; reconnected US",
+		},
+		{
+			name:      "connect and rollback fail",
+			connected: []string{"us"},
+			failOn:    []string{"connect eu", "connect us"},
+			calls:     []string{"disconnect /s/us", "wait", "connect eu", "connect us"},
+			err:       "failed; reconnecting US failed: failed",
+		},
+	}
+
+	for _, tt := range tests {
+		client := &stubClient{failOn: make(map[string]error)}
+		for _, name := range tt.connected {
+			client.sessions = append(client.sessions, openvpn.Session{Path: "/s/" + name, ConfigName: name, Device: "tun1"})
+		}
+		for _, call := range tt.failOn {
+			client.failOn[call] = errFailed
+		}
+		if tt.waitFail {
+			client.waitFailed = errFailed
+		}
+		m := Model{
+			config: &config.Config{
+				Profiles: []config.Profile{
+					{Name: "EU", Path: "/vpn/eu.ovpn"},
+					{Name: "US", Path: "/vpn/us.ovpn"},
+					{Name: "APAC", Path: "/vpn/apac.ovpn"},
+				},
+				Exclusive: []config.ExclusiveGroup{{Name: "region", Profiles: []string{"EU", "US", "APAC"}}},
+			},
+			client:     client,
+			owned:      &ownedSessions{},
+			killSwitch: newKillSwitch(nil),
+		}
+
+		msg := m.connectExclusive(m.config.Profiles[0])
+		switch {
+		case tt.err == "" && (msg.err != nil || msg.switched != "US"):
+			t.Errorf("%s: error %v after switching from %q", tt.name, msg.err, msg.switched)
+		case tt.err != "" && (msg.err == nil || msg.err.Error() != tt.err):
+			t.Errorf("%s: error %v, want %q", tt.name, msg.err, tt.err)
+		}
+		if !slices.Equal(client.calls, tt.calls) {
+			t.Errorf("%s: calls %q, want %q", tt.name, client.calls, tt.calls)
+		}
+		var connected []string
+		for _, s := range client.sessions {
+			connected = append(connected, s.ConfigName)
+		}
+		if !slices.Equal(connected, tt.sessions) {
+			t.Errorf("%s: connected %q, want %q", tt.name, connected, tt.sessions)
+		}
+	}
+}
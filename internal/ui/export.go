@@ -0,0 +1,85 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"openvpn3-tui/internal/bundle"
+	"openvpn3-tui/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// exportTarget returns the profiles to export: the folder or #tag group when
+// one is selected, otherwise the profile under the cursor
+func (m Model) exportTarget() ([]int, string) {
+	if indices, label := m.groupTarget(); len(indices) > 0 {
+		if row, ok := m.selectedRow(); !ok || row.isFolder() || strings.HasPrefix(label, "#") {
+			return indices, label
+		}
+	}
+	if index, ok := m.selectedProfile(); ok {
+		return []int{index}, m.config.Profiles[index].Name
+	}
+	return nil, ""
+}
+
+// startExport prompts for the bundle file to export profiles to
+func (m Model) startExport() (tea.Model, tea.Cmd) {
+	m.clearMessages()
+	indices, label := m.exportTarget()
+	if len(indices) == 0 {
+		m.errorMsg = "Select a profile, folder or #tag to export"
+		return m, nil
+	}
+
+	m.exportIndices = indices
+	m.exportRedact = true
+	m.inputMode = InputExportPath
+	m.textInput.SetValue(fmt.Sprintf("~/%s.tar.gz", config.SafeFileName(strings.TrimPrefix(label, "#"))))
+	m.textInput.CursorEnd()
+	m.textInput.Placeholder = "Bundle file to write"
+	m.textInput.Focus()
+	return m, nil
+}
+
+// finishExport writes the bundle
+func (m Model) finishExport(path string) (tea.Model, tea.Cmd) {
+	profiles := make([]config.Profile, 0, len(m.exportIndices))
+	for _, i := range m.exportIndices {
+		profiles = append(profiles, m.config.Profiles[i])
+	}
+
+	if err := bundle.ExportFile(path, profiles, m.exportRedact); err != nil {
+		// Stay in the prompt so another path can be tried
+		if os.IsExist(err) {
+			m.errorMsg = fmt.Sprintf("%s already exists", CompactPath(path))
+		} else {
+			m.errorMsg = fmt.Sprintf("Export failed: %v", err)
+		}
+		return m, nil
+	}
+
+	m.clearMessages()
+	m.inputMode = InputNone
+	m.exportIndices = nil
+	m.statusMsg = fmt.Sprintf("Exported %d profile(s) to %s", len(profiles), CompactPath(path))
+	return m, nil
+}
+
+// renderExportOptions lists the profiles being exported and whether keys are stripped
+func (m Model) renderExportOptions() string {
+	var b strings.Builder
+	for _, i := range m.exportIndices {
+		b.WriteString(m.styles.Muted.Render("  "+m.config.Profiles[i].Name) + "\n")
+	}
+	b.WriteString("\n")
+	if m.exportRedact {
+		b.WriteString(m.styles.Success.Render("Private keys and credential files are stripped"))
+	} else {
+		b.WriteString(m.styles.Error.Render("Private keys and credential files are included"))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
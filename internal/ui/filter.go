@@ -0,0 +1,283 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Scores used to rank fuzzy matches
+const (
+	scoreMatch       = 1
+	scoreConsecutive = 4
+	scoreWordStart   = 6
+	scoreFirstChar   = 8
+)
+
+// listMatch is an item of a filtered list
+type listMatch struct {
+	index     int   // Index into the unfiltered list
+	score     int   // Higher is better
+	positions []int // Matched rune positions in the displayed name
+}
+
+// fuzzyMatch reports whether all runes of pattern appear in text in order,
+// case-insensitively. It returns a score favouring consecutive runes and
+// word starts, and the rune positions that matched.
+func fuzzyMatch(pattern, text string) (int, []int, bool) {
+	if pattern == "" {
+		return 0, nil, true
+	}
+
+	p := []rune(strings.ToLower(pattern))
+	t := []rune(text)
+	positions := make([]int, 0, len(p))
+	score := 0
+	prev := -2
+
+	pi := 0
+	for ti := 0; ti < len(t) && pi < len(p); ti++ {
+		if unicode.ToLower(t[ti]) != p[pi] {
+			continue
+		}
+
+		score += scoreMatch
+		switch {
+		case ti == 0:
+			score += scoreFirstChar
+		case isWordBoundary(t[ti-1], t[ti]):
+			score += scoreWordStart
+		}
+		if ti == prev+1 {
+			score += scoreConsecutive
+		}
+
+		positions = append(positions, ti)
+		prev = ti
+		pi++
+	}
+
+	if pi < len(p) {
+		return 0, nil, false
+	}
+	return score, positions, true
+}
+
+// isWordBoundary reports whether cur starts a new word after prev
+func isWordBoundary(prev, cur rune) bool {
+	if !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+		return true
+	}
+	return unicode.IsLower(prev) && unicode.IsUpper(cur)
+}
+
+// matchFields matches pattern against the display name and any extra fields.
+// Only matches in the name are highlighted.
+func matchFields(pattern, name string, extra ...string) (int, []int, bool) {
+	best, positions, ok := fuzzyMatch(pattern, name)
+	for _, field := range extra {
+		if score, _, matched := fuzzyMatch(pattern, field); matched && (!ok || score > best) {
+			// Matches in secondary fields rank below equally good name matches
+			best, positions, ok = score-1, nil, true
+		}
+	}
+	return best, positions, ok
+}
+
+// sortMatches orders matches by score, keeping list order for ties
+func sortMatches(matches []listMatch) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+}
+
+// profileMatches returns the profiles with all tags that fuzzy match text
+func (m Model) profileMatches(text string, tags []string) []listMatch {
+	matches := make([]listMatch, 0, len(m.config.Profiles))
+	for i, p := range m.config.Profiles {
+		if !hasTags(p, tags) {
+			continue
+		}
+		extra := append([]string{p.Path, p.Folder}, p.Tags...)
+		if score, positions, ok := matchFields(text, p.Name, extra...); ok {
+			matches = append(matches, listMatch{index: i, score: score, positions: positions})
+		}
+	}
+	sortMatches(matches)
+	return matches
+}
+
+// sessionMatches returns the sessions that match the session filter
+func (m Model) sessionMatches() []listMatch {
+	query := m.filters[ViewSessions]
+	matches := make([]listMatch, 0, len(m.sessions))
+	for i, s := range m.sessions {
+		if score, positions, ok := matchFields(query, s.ConfigName, s.Device); ok {
+			matches = append(matches, listMatch{index: i, score: score, positions: positions})
+		}
+	}
+	if query != "" {
+		sortMatches(matches)
+	}
+	return matches
+}
+
+// selectedProfile returns the config index of the profile under the cursor.
+// It returns false when the cursor is on a folder.
+func (m Model) selectedProfile() (int, bool) {
+	row, ok := m.selectedRow()
+	if !ok || row.isFolder() {
+		return 0, false
+	}
+	return row.index, true
+}
+
+// selectProfile moves the cursor to the profile at the given config index,
+// expanding its folders if they are collapsed
+func (m *Model) selectProfile(index int) {
+	if index < 0 || index >= len(m.config.Profiles) {
+		return
+	}
+	folder := m.config.Profiles[index].Folder
+	for folder != "" {
+		delete(m.collapsed, folder)
+		folder = folder[:max(0, strings.LastIndex(folder, "/"))]
+	}
+
+	view := m.currentView
+	m.currentView = ViewProfiles
+	for i, row := range m.profileRows() {
+		if row.index == index {
+			m.moveCursorTo(i)
+			break
+		}
+	}
+	m.currentView = view
+}
+
+// selectedSession returns the session under the cursor
+func (m Model) selectedSession() (int, bool) {
+	matches := m.sessionMatches()
+	if m.sessionCursor < 0 || m.sessionCursor >= len(matches) {
+		return 0, false
+	}
+	return matches[m.sessionCursor].index, true
+}
+
+// newFilterInput creates the text input used in filter mode
+func newFilterInput() textinput.Model {
+	fi := textinput.New()
+	fi.Prompt = "/"
+	fi.Placeholder = "filter"
+	fi.CharLimit = 64
+	return fi
+}
+
+// startFilter enters filter mode for the current view
+func (m Model) startFilter() (tea.Model, tea.Cmd) {
+	m.filtering = true
+	m.filterInput.SetValue(m.filters[m.currentView])
+	m.filterInput.CursorEnd()
+	m.filterInput.Focus()
+	m.clearMessages()
+	return m, textinput.Blink
+}
+
+// handleFilterMode handles key events while typing a filter
+func (m Model) handleFilterMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		// Drop the filter entirely
+		m.filtering = false
+		m.filterInput.Blur()
+		m.filters[m.currentView] = ""
+		m.moveCursorTo(0)
+		return m, nil
+
+	case "enter":
+		// Keep the filter and act on the selected result
+		m.filtering = false
+		m.filterInput.Blur()
+		return m.handleEnter()
+
+	case "up", "ctrl+p":
+		m.moveCursorBy(-1)
+		return m, nil
+
+	case "down", "ctrl+n":
+		m.moveCursorBy(1)
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.filterInput, cmd = m.filterInput.Update(msg)
+	if value := m.filterInput.Value(); value != m.filters[m.currentView] {
+		m.filters[m.currentView] = value
+		m.moveCursorTo(0)
+	}
+	return m, cmd
+}
+
+// filterActive reports whether the current view shows a filter line
+func (m Model) filterActive() bool {
+	return m.filtering || m.filters[m.currentView] != ""
+}
+
+// renderFilter renders the filter line above the list
+func (m Model) renderFilter(shown, total int) string {
+	if !m.filterActive() {
+		return ""
+	}
+
+	var line string
+	if m.filtering {
+		line = m.filterInput.View()
+	} else {
+		line = m.styles.Muted.Render("/" + m.filters[m.currentView])
+	}
+	return line + m.styles.Muted.Render(fmt.Sprintf("  (%d/%d)", shown, total)) + "\n"
+}
+
+// highlight renders text with the runes at positions in the match style.
+// The base style must not have padding since it is applied per segment.
+func highlight(text string, positions []int, base, match lipgloss.Style) string {
+	if len(positions) == 0 {
+		return base.Render(text)
+	}
+
+	matched := make(map[int]bool, len(positions))
+	for _, p := range positions {
+		matched[p] = true
+	}
+
+	var b strings.Builder
+	var run []rune
+	runMatched := false
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		if runMatched {
+			b.WriteString(match.Render(string(run)))
+		} else {
+			b.WriteString(base.Render(string(run)))
+		}
+		run = run[:0]
+	}
+
+	for i, r := range []rune(text) {
+		if matched[i] != runMatched {
+			flush()
+			runMatched = matched[i]
+		}
+		run = append(run, r)
+	}
+	flush()
+
+	return b.String()
+}
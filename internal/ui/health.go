@@ -0,0 +1,185 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"openvpn3-tui/internal/config"
+	"openvpn3-tui/internal/health"
+	"openvpn3-tui/internal/hooks"
+	"openvpn3-tui/internal/openvpn"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// healthTickInterval is how often profiles are looked at for due checks
+const healthTickInterval = 5 * time.Second
+
+// healthTickMsg starts the checks that are due
+type healthTickMsg time.Time
+
+// healthTick schedules the next look at due checks
+func healthTick() tea.Cmd {
+	return tea.Tick(healthTickInterval, func(t time.Time) tea.Msg {
+		return healthTickMsg(t)
+	})
+}
+
+// healthMsg carries a round of checks
+type healthMsg struct {
+	checked  []config.Profile           // Profiles that were due
+	reports  map[string]health.Report   // By profile name, for those still connected
+	sessions map[string]openvpn.Session // The session each report is for
+}
+
+// healthHookMsg is sent after a health-failed hook ran
+type healthHookMsg struct {
+	err error
+}
+
+// hasHealthChecks reports whether any profile has health checks
+func (m Model) hasHealthChecks() bool {
+	for _, p := range m.config.Profiles {
+		if p.Health != nil && len(p.Health.Checks) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// handleHealthTick checks the connected profiles whose interval has passed.
+// Only one round runs at a time.
+func (m Model) handleHealthTick(now time.Time) (tea.Model, tea.Cmd) {
+	if m.healthRunning {
+		return m, healthTick()
+	}
+	var due []config.Profile
+	for _, p := range m.config.Profiles {
+		if p.Health == nil || len(p.Health.Checks) == 0 {
+			continue
+		}
+		if !m.isProfileConnected(p.Path) {
+			// A new session starts with fresh checks
+			delete(m.health, p.Name)
+			continue
+		}
+		if last, ok := m.health[p.Name]; ok && now.Sub(last.Time) < p.Health.IntervalDuration() {
+			continue
+		}
+		due = append(due, p)
+	}
+	if len(due) == 0 {
+		return m, healthTick()
+	}
+	m.healthRunning = true
+	return m, tea.Batch(healthTick(), m.checkHealth(due))
+}
+
+// checkHealth runs the checks of profiles that still have a session
+func (m Model) checkHealth(profiles []config.Profile) tea.Cmd {
+	return func() tea.Msg {
+		msg := healthMsg{
+			checked:  profiles,
+			reports:  make(map[string]health.Report),
+			sessions: make(map[string]openvpn.Session),
+		}
+		sessions, err := m.client.ListSessions()
+		if err != nil {
+			// Keep the previous reports until the next round
+			msg.checked = nil
+			return msg
+		}
+		for _, p := range profiles {
+			matches := sessionsFor(sessions, p.Path)
+			if len(matches) == 0 {
+				continue
+			}
+			msg.sessions[p.Name] = matches[0]
+			msg.reports[p.Name] = health.Run(p.Health)
+		}
+		return msg
+	}
+}
+
+// handleHealth stores a round of checks. A profile turning unhealthy is
+// reported and runs its health-failed hook; one recovering is reported too.
+func (m Model) handleHealth(msg healthMsg) (tea.Model, tea.Cmd) {
+	m.healthRunning = false
+	var cmds []tea.Cmd
+	for _, p := range msg.checked {
+		report, ok := msg.reports[p.Name]
+		if !ok {
+			delete(m.health, p.Name)
+			continue
+		}
+		previous, seen := m.health[p.Name]
+		m.health[p.Name] = report
+		switch {
+		case !report.Healthy() && (!seen || previous.Healthy()):
+			m.errorMsg = fmt.Sprintf("%s is unhealthy: %v", p.Name, report.Error())
+			cmds = append(cmds, m.healthFailed(p, msg.sessions[p.Name], report))
+		case report.Healthy() && seen && !previous.Healthy():
+			m.statusMsg = fmt.Sprintf("%s is healthy again", p.Name)
+		}
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// healthFailed runs the health-failed hook of a profile, if it has one
+func (m Model) healthFailed(profile config.Profile, s openvpn.Session, report health.Report) tea.Cmd {
+	if hooks.Command(profile.Hooks, hooks.HealthFailed) == "" {
+		return nil
+	}
+	return func() tea.Msg {
+		session := hooks.Session{
+			Profile:  profile,
+			Path:     s.Path,
+			Device:   s.Device,
+			TunnelIP: hooks.TunnelIP(s.Device),
+			Health:   report.Error().Error(),
+		}
+		return healthHookMsg{err: m.runHook(hooks.HealthFailed, session)}
+	}
+}
+
+// healthBadge summarizes the latest checks of a session's profile, e.g. [12ms]
+func (m Model) healthBadge(s openvpn.Session) string {
+	profile, ok := m.sessionProfile(s)
+	if !ok || profile.Health == nil || len(profile.Health.Checks) == 0 {
+		return ""
+	}
+	report, ok := m.health[profile.Name]
+	switch {
+	case !ok:
+		return m.styles.Muted.Render("[checking]")
+	case !report.Healthy():
+		return m.styles.Error.Render(fmt.Sprintf("[%s]", health.Summary(report)))
+	}
+	return m.styles.Connected.Render(fmt.Sprintf("[%s]", health.Summary(report)))
+}
+
+// renderHealthDetail lists the latest result of each check of a session's profile
+func (m Model) renderHealthDetail(s openvpn.Session) string {
+	profile, ok := m.sessionProfile(s)
+	if !ok || profile.Health == nil || len(profile.Health.Checks) == 0 {
+		return ""
+	}
+	report, ok := m.health[profile.Name]
+	if !ok {
+		return detailRow("Health", m.styles.Muted.Render("not checked yet"))
+	}
+
+	var rows []string
+	for _, r := range report.Results {
+		if r.Err != nil {
+			rows = append(rows, fmt.Sprintf("%s %s", r.Check, m.styles.Error.Render(r.Err.Error())))
+		} else {
+			rows = append(rows, fmt.Sprintf("%s %s", r.Check, m.styles.Connected.Render(health.FormatLatency(r.Latency))))
+		}
+	}
+	out := detailRow("Health", rows[0]+m.styles.Muted.Render(" • "+report.Time.Format("15:04:05")))
+	for _, row := range rows[1:] {
+		out += detailRow("", row)
+	}
+	return out
+}
@@ -0,0 +1,187 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"openvpn3-tui/internal/config"
+	"openvpn3-tui/internal/hooks"
+	"openvpn3-tui/internal/openvpn"
+)
+
+// runHook runs a hook of the session's profile and records it in the hook log
+func (m Model) runHook(event hooks.Event, s hooks.Session) error {
+	res, ok := hooks.Run(event, s)
+	if !ok {
+		return nil
+	}
+	m.hookLog.Add(res)
+	return res.Err
+}
+
+// connectWithHooks connects a profile between its pre-connect and
+// post-connect hooks and records the session as started by this instance. A
+// failing pre-connect hook only stops the connection when the profile asks
+// for it.
+func (m Model) connectWithHooks(profile config.Profile) connectMsg {
+	msg := connectMsg{profile: profile.Name}
+	if err := m.runHook(hooks.PreConnect, hooks.Session{Profile: profile}); err != nil {
+		if profile.Hooks.AbortOnFailure {
+			msg.err = err
+			return msg
+		}
+		msg.hookErr = err
+	}
+
+	// Only the servers and LAN exceptions are reachable during the handshake
+	var restore func()
+	if profile.KillSwitch != nil {
+		var err error
+		if restore, err = m.blockForConnect(profile); err != nil {
+			msg.err = fmt.Errorf("kill switch: %w", err)
+			return msg
+		}
+	}
+
+	var storeErr error
+	if server, ok := serverOverride(profile); ok {
+		msg.err = m.client.ConnectTo(profile.Path, server, m.answerer(profile, &storeErr))
+	} else {
+		msg.err = m.client.Connect(profile.Path, m.answerer(profile, &storeErr))
+	}
+	if storeErr != nil {
+		msg.err = storeErr
+	}
+	if msg.err != nil {
+		if restore != nil {
+			restore()
+		}
+		return msg
+	}
+
+	// Remember the new session and describe it to the post-connect hook
+	session := hooks.Session{Profile: profile}
+	if sessions, err := m.client.ListSessions(); err == nil {
+		if matches := sessionsFor(sessions, profile.Path); len(matches) > 0 {
+			s := matches[len(matches)-1]
+			m.owned.add(s.Path)
+			session.Path = s.Path
+			session.Device = s.Device
+			session.TunnelIP = hooks.TunnelIP(s.Device)
+		}
+	}
+	if restore != nil {
+		if err := m.killSwitch.attach(profile.Name, session.Device); err != nil {
+			// The tunnel is blocked without its device, so the session goes
+			if session.Path != "" {
+				m.client.Disconnect(session.Path)
+			}
+			restore()
+			msg.err = fmt.Errorf("kill switch: %w (disconnected)", err)
+			return msg
+		}
+	}
+	if hooks.Command(profile.Hooks, hooks.PostConnect) == "" {
+		return msg
+	}
+	if err := m.runHook(hooks.PostConnect, session); err != nil {
+		msg.hookErr = err
+	}
+	return msg
+}
+
+// disconnectWithHooks disconnects a session between the pre-disconnect and
+// post-disconnect hooks of its profile
+func (m Model) disconnectWithHooks(s openvpn.Session) disconnectMsg {
+	var msg disconnectMsg
+	profile, ok := m.sessionProfile(s)
+	session := hooks.Session{Profile: profile, Path: s.Path, Device: s.Device, TunnelIP: hooks.TunnelIP(s.Device)}
+	msg.profile = profile.Name
+
+	if ok {
+		msg.hookErr = m.runHook(hooks.PreDisconnect, session)
+	}
+	if msg.err = m.client.Disconnect(s.Path); msg.err != nil {
+		return msg
+	}
+	if ok {
+		if err := m.disarmKillSwitch(profile.Name); err != nil {
+			msg.err = fmt.Errorf("disconnected, but the kill switch still blocks traffic: %w", err)
+			return msg
+		}
+		if err := m.runHook(hooks.PostDisconnect, session); err != nil {
+			msg.hookErr = err
+		}
+	}
+	return msg
+}
+
+// hookError describes a failed hook for the status line
+func (m Model) hookError(err error) string {
+	if err == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v (press %s for the hook log)", err, m.keys.HookLog.Help().Key)
+}
+
+// hookNames lists the events a profile has hooks for
+func hookNames(h *config.Hooks) []string {
+	var names []string
+	for _, event := range []hooks.Event{hooks.PreConnect, hooks.PostConnect, hooks.PreDisconnect, hooks.PostDisconnect, hooks.HealthFailed} {
+		if hooks.Command(h, event) != "" {
+			names = append(names, string(event))
+		}
+	}
+	return names
+}
+
+// hookOutputLines is how many trailing output lines the log shows per run
+const hookOutputLines = 4
+
+// renderHookLog renders the hook log overlay, newest runs first
+func (m Model) renderHookLog() string {
+	var b strings.Builder
+	b.WriteString(m.styles.Subtitle.Render("Hook Log"))
+	b.WriteString("\n\n")
+
+	entries := m.hookLog.Entries()
+	if len(entries) == 0 {
+		b.WriteString(m.styles.Muted.Render("No hooks have run yet"))
+		b.WriteString("\n")
+	}
+
+	// Each run takes its heading, command and output lines
+	lines := 0
+	budget := max(m.height-headerHeight-footerHeight-4, 10)
+	for _, r := range entries {
+		output := strings.Split(strings.TrimRight(r.Output, "\n"), "\n")
+		if len(output) > hookOutputLines {
+			output = output[len(output)-hookOutputLines:]
+		}
+		if r.Output == "" {
+			output = nil
+		}
+		if lines > 0 && lines+2+len(output) > budget {
+			break
+		}
+		lines += 2 + len(output)
+
+		status := m.styles.Connected.Render("ok")
+		if r.Err != nil {
+			status = m.styles.Error.Render(strings.TrimPrefix(r.Err.Error(), string(r.Event)+" hook failed: "))
+		}
+		b.WriteString(fmt.Sprintf("%s  %s  %s  %s  %s\n",
+			m.styles.Muted.Render(r.Started.Format("15:04:05")),
+			m.styles.DetailTitle.Render(r.Profile), r.Event, status,
+			m.styles.Muted.Render(r.Duration.Round(10*time.Millisecond).String())))
+		b.WriteString(m.styles.Muted.Render("  $ "+r.Command) + "\n")
+		for _, line := range output {
+			b.WriteString("    " + line + "\n")
+		}
+	}
+
+	b.WriteString("\n")
+	b.WriteString(m.styles.Help.Render("press any key to close"))
+	return m.styles.Box.Render(b.String())
+}
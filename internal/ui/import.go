@@ -0,0 +1,243 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"openvpn3-tui/internal/config"
+	"openvpn3-tui/internal/importer"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// importState holds the profiles found by an import scan awaiting review
+type importState struct {
+	source *importer.Source
+	cursor int
+	offset int
+	copy   bool // Copy files into the managed profiles directory
+}
+
+// startImport prompts for a directory or archive to import profiles from
+func (m Model) startImport() (tea.Model, tea.Cmd) {
+	m.clearMessages()
+	m.inputMode = InputImportPath
+	m.textInput.SetValue("")
+	m.textInput.Placeholder = "Directory, .zip or .tar.gz to import (start with ~ or /)"
+	m.textInput.Focus()
+	m.completer.SetExtensions(".zip", ".tar.gz", ".tgz")
+	m.completer.Clear()
+	return m, nil
+}
+
+// scanImport scans the entered path and opens the import preview
+func (m Model) scanImport(path string) (tea.Model, tea.Cmd) {
+	src, err := importer.Scan(path)
+	if err != nil {
+		// Stay in the path prompt so the user can correct it
+		m.errorMsg = err.Error()
+		return m, nil
+	}
+	src.MarkDuplicates(m.config)
+
+	m.clearMessages()
+	m.inputMode = InputNone
+	m.completer.SetExtensions(".ovpn")
+	m.completer.Clear()
+	m.importing = &importState{source: src, copy: src.Archive}
+	return m, nil
+}
+
+// handleImportMode handles key events in the import preview
+func (m Model) handleImportMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	imp := m.importing
+	candidates := imp.source.Candidates
+
+	switch {
+	case key.Matches(msg, m.keys.Close):
+		m.importing = nil
+		m.statusMsg = "Import cancelled"
+		return m, nil
+
+	case key.Matches(msg, m.keys.Select):
+		return m.finishImport()
+
+	case key.Matches(msg, m.keys.Toggle):
+		if len(candidates) > 0 {
+			candidates[imp.cursor].Selected = !candidates[imp.cursor].Selected
+		}
+
+	case key.Matches(msg, m.keys.ToggleAll):
+		// Select all, or clear the selection when everything is selected
+		all := len(imp.source.Selected()) == len(candidates)
+		for i := range candidates {
+			candidates[i].Selected = !all
+		}
+
+	case key.Matches(msg, m.keys.CopyFiles):
+		if imp.source.Archive {
+			m.errorMsg = "Profiles from archives are always copied"
+		} else {
+			imp.copy = !imp.copy
+		}
+
+	case key.Matches(msg, m.keys.Up):
+		imp.cursor = clamp(imp.cursor-1, 0, len(candidates)-1)
+
+	case key.Matches(msg, m.keys.Down):
+		imp.cursor = clamp(imp.cursor+1, 0, len(candidates)-1)
+
+	case key.Matches(msg, m.keys.PageUp):
+		imp.cursor = clamp(imp.cursor-m.importHeight(), 0, len(candidates)-1)
+
+	case key.Matches(msg, m.keys.PageDown):
+		imp.cursor = clamp(imp.cursor+m.importHeight(), 0, len(candidates)-1)
+	}
+
+	imp.offset = scrollOffset(imp.cursor, imp.offset, len(candidates), m.importHeight())
+	return m, nil
+}
+
+// finishImport adds the selected candidates as profiles
+func (m Model) finishImport() (tea.Model, tea.Cmd) {
+	imp := m.importing
+	if len(imp.source.Selected()) == 0 {
+		m.errorMsg = "Select at least one profile to import"
+		return m, nil
+	}
+
+	destDir, err := config.ProfilesDir()
+	if err != nil {
+		m.errorMsg = err.Error()
+		return m, nil
+	}
+
+	notes := imp.source.ResolveDependencies()
+	first := len(m.config.Profiles)
+	var errs []string
+	for _, c := range imp.source.Selected() {
+		path, err := imp.source.Install(c, destDir, imp.copy)
+		if err == nil {
+			c.Name = m.config.UniqueName(c.Name)
+			err = m.config.AddProfileEntry(c.Profile(path))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", c.Path, err))
+		}
+	}
+
+	// A bundle edited by hand may still contain a cycle, which would keep
+	// the config from loading
+	for i := first; i < len(m.config.Profiles); i++ {
+		p := &m.config.Profiles[i]
+		if _, err := m.config.DependencyOrder(p.Name); errors.Is(err, config.ErrDependencyCycle) {
+			p.DependsOn = nil
+			notes = append(notes, fmt.Sprintf("%s: dependencies were dropped, %v", p.Name, err))
+		}
+	}
+	added := len(m.config.Profiles) - first
+
+	m.importing = nil
+	m.clearMessages()
+	if added > 0 {
+		if err := m.config.Save(); err != nil {
+			m.errorMsg = fmt.Sprintf("Failed to save config: %v", err)
+			return m, nil
+		}
+		m.statusMsg = fmt.Sprintf("Imported %d profile(s)", added)
+		if len(notes) > 0 {
+			m.statusMsg += "; " + strings.Join(notes, "; ")
+		}
+		m.validateProfiles()
+		m.selectProfile(first)
+	}
+	if len(errs) > 0 {
+		m.errorMsg = fmt.Sprintf("%d failed: %s", len(errs), errs[0])
+	}
+	return m, nil
+}
+
+// importHeight returns how many candidates fit in the import preview
+func (m Model) importHeight() int {
+	if m.height == 0 {
+		return 0
+	}
+	// Title, tabs, box frame, heading, option line and help
+	return max(minListHeight, m.height-headerHeight-footerHeight-4)
+}
+
+// renderImport renders the import preview
+func (m Model) renderImport() string {
+	imp := m.importing
+	candidates := imp.source.Candidates
+	var b strings.Builder
+
+	b.WriteString(m.styles.Subtitle.Render(fmt.Sprintf("Import from %s", CompactPath(imp.source.Path))))
+	b.WriteString("\n\n")
+
+	start, end := visibleRange(imp.offset, len(candidates), m.importHeight())
+	for i := start; i < end; i++ {
+		c := candidates[i]
+		cursor := "  "
+		if i == imp.cursor {
+			cursor = "> "
+		}
+		check := "[ ] "
+		if c.Selected {
+			check = "[x] "
+		}
+
+		line := cursor + check + c.Name
+		if i == imp.cursor {
+			line = m.styles.SuggestionSelected.Render(line)
+		}
+		line += " " + m.styles.Muted.Render(c.Path)
+		if c.Remote != "" {
+			line += " " + m.styles.Muted.Render("→ "+c.Remote)
+		}
+		if c.Duplicate != "" {
+			line += " " + m.styles.Paused.Render(fmt.Sprintf("[duplicate of %s]", c.Duplicate))
+		}
+		if len(c.Notes) > 0 {
+			line += " " + m.styles.Paused.Render(fmt.Sprintf("[%d %s]", len(c.Notes), plural(len(c.Notes), "note")))
+		}
+		b.WriteString(line + "\n")
+	}
+
+	// Explain what a converted connection loses or what needs attention
+	if len(candidates) > 0 {
+		c := candidates[imp.cursor]
+		if c.Username != "" {
+			b.WriteString("\n" + m.styles.Muted.Render(fmt.Sprintf("Username %s is asked for when connecting", c.Username)) + "\n")
+		}
+		if len(c.Notes) > 0 {
+			b.WriteString("\n" + m.styles.Muted.Render("Notes:") + "\n")
+			for _, note := range c.Notes {
+				b.WriteString(m.styles.Paused.Render("  ⚠ "+note) + "\n")
+			}
+		}
+	}
+
+	dest := "use files in place"
+	if imp.copy {
+		dest = "copy to the managed profiles directory"
+	}
+	b.WriteString("\n")
+	b.WriteString(fmt.Sprintf("%d of %d selected • %s\n", len(imp.source.Selected()), len(candidates), dest))
+
+	if m.errorMsg != "" {
+		b.WriteString("\n")
+		b.WriteString(m.styles.Error.Render(m.errorMsg))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(m.styles.Help.Render(strings.Join([]string{
+		hint(m.keys.Toggle, "toggle"), hint(m.keys.ToggleAll, "all/none"), hint(m.keys.CopyFiles, "copy files"),
+		hint(m.keys.Select, "import"), hint(m.keys.Close, "cancel"),
+	}, " • ")))
+
+	return m.styles.Box.Render(b.String())
+}
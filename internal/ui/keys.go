@@ -0,0 +1,215 @@
+package ui
+
+import (
+	"strings"
+
+	"openvpn3-tui/internal/config"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// KeyMap holds the key bindings for every action
+type KeyMap struct {
+	Quit            key.Binding
+	SwitchView      key.Binding
+	Up              key.Binding
+	Down            key.Binding
+	PageUp          key.Binding
+	PageDown        key.Binding
+	Home            key.Binding
+	End             key.Binding
+	Select          key.Binding
+	Servers         key.Binding
+	Add             key.Binding
+	Import          key.Binding
+	Export          key.Binding
+	Edit            key.Binding
+	MoveUp          key.Binding
+	MoveDown        key.Binding
+	Duplicate       key.Binding
+	Inline          key.Binding
+	Folder          key.Binding
+	Tags            key.Binding
+	ConnectGroup    key.Binding
+	DisconnectGroup key.Binding
+	Delete          key.Binding
+	Refresh         key.Binding
+	Stats           key.Binding
+	Certs           key.Binding
+	Credentials     key.Binding
+	Forget          key.Binding
+	TOTP            key.Binding
+	HookLog         key.Binding
+	Help            key.Binding
+	Filter          key.Binding
+	Toggle          key.Binding
+	ToggleAll       key.Binding
+	CopyFiles       key.Binding
+	ConfigOrder     key.Binding
+	Close           key.Binding
+	Confirm         key.Binding
+	Cancel          key.Binding
+}
+
+// NewKeyMap builds the key bindings from the keymap section of the config
+func NewKeyMap(km config.Keymap) KeyMap {
+	keys, err := km.Resolve()
+	if err != nil {
+		// Config loading already rejects invalid keymaps, so fall back to the defaults
+		keys, _ = config.Keymap{}.Resolve()
+	}
+
+	bind := func(action, desc string) key.Binding {
+		return key.NewBinding(
+			key.WithKeys(keys[action]...),
+			key.WithHelp(helpKey(keys[action]), desc),
+		)
+	}
+
+	return KeyMap{
+		Quit:            bind(config.ActionQuit, "quit"),
+		SwitchView:      bind(config.ActionSwitchView, "switch view"),
+		Up:              bind(config.ActionUp, "up"),
+		Down:            bind(config.ActionDown, "down"),
+		PageUp:          bind(config.ActionPageUp, "page up"),
+		PageDown:        bind(config.ActionPageDown, "page down"),
+		Home:            bind(config.ActionHome, "first"),
+		End:             bind(config.ActionEnd, "last / [count] go to"),
+		Select:          bind(config.ActionSelect, "connect"),
+		Servers:         bind(config.ActionServers, "connect via server"),
+		Add:             bind(config.ActionAdd, "add"),
+		Import:          bind(config.ActionImport, "import"),
+		Export:          bind(config.ActionExport, "export bundle"),
+		Edit:            bind(config.ActionEdit, "edit"),
+		MoveUp:          bind(config.ActionMoveUp, "move up"),
+		MoveDown:        bind(config.ActionMoveDown, "move down"),
+		Duplicate:       bind(config.ActionDuplicate, "duplicate"),
+		Inline:          bind(config.ActionInline, "make self-contained"),
+		Folder:          bind(config.ActionFolder, "move to folder"),
+		Tags:            bind(config.ActionTags, "edit tags"),
+		ConnectGroup:    bind(config.ActionConnectGroup, "connect folder/#tag"),
+		DisconnectGroup: bind(config.ActionDisconnectGroup, "disconnect folder/#tag"),
+		Delete:          bind(config.ActionDelete, "delete"),
+		Refresh:         bind(config.ActionRefresh, "refresh"),
+		Stats:           bind(config.ActionStats, "stats"),
+		Certs:           bind(config.ActionCerts, "certificates"),
+		Credentials:     bind(config.ActionCredentials, "save credentials"),
+		Forget:          bind(config.ActionForget, "forget credentials"),
+		TOTP:            bind(config.ActionTOTP, "TOTP secret"),
+		HookLog:         bind(config.ActionHookLog, "hook log"),
+		Help:            bind(config.ActionHelp, "help"),
+		Filter:          bind(config.ActionFilter, "filter"),
+		Toggle:          bind(config.ActionToggle, "toggle import"),
+		ToggleAll:       bind(config.ActionToggleAll, "import all/none"),
+		CopyFiles:       bind(config.ActionCopyFiles, "copy imported files"),
+		ConfigOrder:     bind(config.ActionConfigOrder, "servers in config order"),
+		Close:           bind(config.ActionClose, "close import/servers"),
+		Confirm:         bind(config.ActionConfirm, "confirm"),
+		Cancel:          bind(config.ActionCancel, "cancel"),
+	}
+}
+
+// helpKey formats the first keys of a binding for the help bar
+func helpKey(keys []string) string {
+	if len(keys) > 2 {
+		keys = keys[:2]
+	}
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		switch k {
+		case "up":
+			names[i] = "↑"
+		case "down":
+			names[i] = "↓"
+		case "pgup":
+			names[i] = "PgUp"
+		case "pgdown":
+			names[i] = "PgDn"
+		case " ":
+			names[i] = "space"
+		default:
+			names[i] = k
+		}
+	}
+	return strings.Join(names, "/")
+}
+
+// hint formats a binding for the key hints below an overlay, e.g. "esc: cancel"
+func hint(b key.Binding, desc string) string {
+	return b.Help().Key + ": " + desc
+}
+
+// viewKeyMap adapts the key bindings to the help bubble for a given view
+type viewKeyMap struct {
+	keys KeyMap
+	view View
+}
+
+// bindings returns the key bindings with view specific help text
+func (v viewKeyMap) bindings() KeyMap {
+	k := v.keys
+	if v.view == ViewSessions {
+		k.Select.SetHelp(k.Select.Help().Key, "stats")
+		k.Delete.SetHelp(k.Delete.Help().Key, "disconnect")
+		for _, b := range []*key.Binding{
+			&k.Add, &k.Import, &k.Export, &k.Edit, &k.MoveUp, &k.MoveDown, &k.Duplicate,
+			&k.Inline, &k.Folder, &k.Tags, &k.ConnectGroup, &k.DisconnectGroup, &k.Certs,
+			&k.Credentials, &k.Forget, &k.TOTP, &k.Servers, &k.Toggle, &k.ToggleAll,
+			&k.CopyFiles, &k.ConfigOrder, &k.Close,
+		} {
+			b.SetEnabled(false)
+		}
+	} else {
+		k.Stats.SetEnabled(false)
+	}
+	return k
+}
+
+// ShortHelp returns the bindings shown in the help bar
+func (v viewKeyMap) ShortHelp() []key.Binding {
+	k := v.bindings()
+	var navKeys, firstKeys []string
+	for _, b := range []key.Binding{k.Up, k.Down} {
+		navKeys = append(navKeys, b.Keys()...)
+		if keys := b.Keys(); len(keys) > 0 {
+			firstKeys = append(firstKeys, keys[0])
+		}
+	}
+	navigate := key.NewBinding(
+		key.WithKeys(navKeys...),
+		key.WithHelp(helpKey(firstKeys), "navigate"),
+	)
+
+	if v.view == ViewSessions {
+		return []key.Binding{k.SwitchView, navigate, k.Select, k.Delete, k.Filter, k.Refresh, k.Help, k.Quit}
+	}
+	return []key.Binding{k.SwitchView, navigate, k.Select, k.Add, k.Edit, k.Delete, k.Filter, k.Refresh, k.Help, k.Quit}
+}
+
+// FullHelp returns the bindings shown in the help overlay, grouped in columns
+func (v viewKeyMap) FullHelp() [][]key.Binding {
+	k := v.bindings()
+	return [][]key.Binding{
+		{k.Up, k.Down, k.PageUp, k.PageDown, k.Home, k.End, k.SwitchView},
+		{k.Select, k.Servers, k.Stats, k.Certs, k.HookLog, k.Refresh, k.Filter},
+		{k.Add, k.Import, k.Export, k.Edit, k.Duplicate, k.Inline, k.MoveUp, k.MoveDown, k.Delete},
+		{k.Folder, k.Tags, k.ConnectGroup, k.DisconnectGroup, k.Credentials, k.Forget, k.TOTP},
+		{k.Toggle, k.ToggleAll, k.CopyFiles, k.ConfigOrder, k.Close},
+		{k.Confirm, k.Cancel},
+		{k.Help, k.Quit},
+	}
+}
+
+// newHelp creates a help bubble styled with the current theme
+func newHelp(s *Styles) help.Model {
+	h := help.New()
+	h.Styles.ShortKey = s.HelpKey
+	h.Styles.ShortDesc = s.HelpDesc
+	h.Styles.ShortSeparator = s.HelpDesc
+	h.Styles.Ellipsis = s.HelpDesc
+	h.Styles.FullKey = s.HelpKey
+	h.Styles.FullDesc = s.HelpDesc
+	h.Styles.FullSeparator = s.HelpDesc
+	return h
+}
@@ -0,0 +1,206 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"openvpn3-tui/internal/config"
+	"openvpn3-tui/internal/killswitch"
+	"openvpn3-tui/internal/openvpn"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// killSwitchTimeout bounds resolving the servers of a profile
+const killSwitchTimeout = 10 * time.Second
+
+// errNoDevice is returned when a session's tunnel device is not known, which
+// would leave the tunnel itself blocked
+var errNoDevice = errors.New("tunnel device of the session not found")
+
+// killSwitch keeps the nftables table in line with the profiles whose kill
+// switch is active. Commands share it, so it is locked.
+type killSwitch struct {
+	mu      sync.Mutex
+	runner  killswitch.Runner
+	guards  map[string]killswitch.Guard // By profile name
+	adopted map[string]bool             // Session paths already tried
+}
+
+// newKillSwitch creates a kill switch applying rules through runner
+func newKillSwitch(runner killswitch.Runner) *killSwitch {
+	return &killSwitch{
+		runner:  runner,
+		guards:  make(map[string]killswitch.Guard),
+		adopted: make(map[string]bool),
+	}
+}
+
+// set replaces the guard of a profile, or removes it when g is nil, and
+// applies the rules. The previous rules stay on failure.
+func (k *killSwitch) set(profile string, g *killswitch.Guard) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	next := maps.Clone(k.guards)
+	if g == nil {
+		delete(next, profile)
+	} else {
+		next[profile] = *g
+	}
+	names := slices.Sorted(maps.Keys(next))
+	guards := make([]killswitch.Guard, 0, len(names))
+	for _, name := range names {
+		guards = append(guards, next[name])
+	}
+	if err := k.runner.Apply(killswitch.Ruleset(guards)); err != nil {
+		return err
+	}
+	k.guards = next
+	return nil
+}
+
+// attach lets traffic through the tunnel device of a profile that connected
+func (k *killSwitch) attach(profile, device string) error {
+	if device == "" {
+		return errNoDevice
+	}
+	g, ok := k.guard(profile)
+	if !ok {
+		return fmt.Errorf("%s has no active kill switch", profile)
+	}
+	g.Device = device
+	return k.set(profile, &g)
+}
+
+// guard returns the active guard of a profile
+func (k *killSwitch) guard(profile string) (killswitch.Guard, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	g, ok := k.guards[profile]
+	return g, ok
+}
+
+// claim reports whether a session has not been looked at for adoption yet
+func (k *killSwitch) claim(path string) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.adopted[path] {
+		return false
+	}
+	k.adopted[path] = true
+	return true
+}
+
+// killSwitchMsg is sent after kill switches of running sessions were applied
+type killSwitchMsg struct {
+	errs []error
+}
+
+// guardFor resolves what the kill switch of a profile lets through
+func guardFor(profile config.Profile, device string) (killswitch.Guard, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), killSwitchTimeout)
+	defer cancel()
+	return killswitch.ForProfile(ctx, profile, device)
+}
+
+// blockForConnect activates the kill switch of a profile before it connects,
+// allowing only its servers and LAN exceptions. restore puts back the rules
+// the profile had before, e.g. when the connect fails.
+func (m Model) blockForConnect(profile config.Profile) (restore func(), err error) {
+	previous, had := m.killSwitch.guard(profile.Name)
+	g, err := guardFor(profile, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := m.killSwitch.set(profile.Name, &g); err != nil {
+		return nil, err
+	}
+	return func() {
+		if had {
+			m.killSwitch.set(profile.Name, &previous)
+		} else {
+			m.killSwitch.set(profile.Name, nil)
+		}
+	}, nil
+}
+
+// armKillSwitch activates the kill switch of a connected profile
+func (m Model) armKillSwitch(profile config.Profile, device string) error {
+	if device == "" {
+		return errNoDevice
+	}
+	g, err := guardFor(profile, device)
+	if err != nil {
+		return err
+	}
+	return m.killSwitch.set(profile.Name, &g)
+}
+
+// disarmKillSwitch removes the kill switch of a profile after it was
+// disconnected on purpose
+func (m Model) disarmKillSwitch(profile string) error {
+	if _, ok := m.killSwitch.guard(profile); !ok {
+		return nil
+	}
+	return m.killSwitch.set(profile, nil)
+}
+
+// adoptKillSwitches activates the kill switch of sessions this instance did
+// not connect, e.g. ones running when it started. Each session is tried once.
+func (m Model) adoptKillSwitches(sessions []openvpn.Session) tea.Cmd {
+	m.sessions = sessions
+	var profiles []config.Profile
+	var devices []string
+	for _, s := range sessions {
+		profile, ok := m.sessionProfile(s)
+		// Sessions connected by this instance were armed while connecting
+		if !ok || profile.KillSwitch == nil || !s.Connected() || m.owned.has(s.Path) {
+			continue
+		}
+		if g, ok := m.killSwitch.guard(profile.Name); ok && g.Device == s.Device {
+			continue
+		}
+		if !m.killSwitch.claim(s.Path) {
+			continue
+		}
+		profiles = append(profiles, profile)
+		devices = append(devices, s.Device)
+	}
+	if len(profiles) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		var msg killSwitchMsg
+		for i, p := range profiles {
+			if err := m.armKillSwitch(p, devices[i]); err != nil {
+				msg.errs = append(msg.errs, fmt.Errorf("kill switch of %s: %w", p.Name, err))
+			}
+		}
+		return msg
+	}
+}
+
+// renderKillSwitchSummary shows whether the kill switch of a profile blocks traffic
+func (m Model) renderKillSwitchSummary(profile config.Profile) string {
+	if profile.KillSwitch == nil {
+		return ""
+	}
+	g, ok := m.killSwitch.guard(profile.Name)
+	switch {
+	case !ok:
+		return detailRow("Kill switch", m.styles.Muted.Render("while connected"))
+	case !m.isProfileConnected(profile.Path):
+		return detailRow("Kill switch", m.styles.Error.Render("blocking all traffic, session dropped"))
+	}
+	value := m.styles.Connected.Render("only " + g.Device)
+	if len(profile.KillSwitch.Allow) > 0 {
+		value += m.styles.Muted.Render(" and " + strings.Join(profile.KillSwitch.Allow, ", "))
+	}
+	return detailRow("Kill switch", value)
+}
@@ -0,0 +1,310 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"openvpn3-tui/internal/ovpn"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	// splitMinWidth is the terminal width from which the detail pane is shown
+	splitMinWidth = 100
+	// minListWidth keeps the list readable in split mode
+	minListWidth = 32
+	// paneGap is the space between the list and the detail pane
+	paneGap = 2
+)
+
+// layout describes how the content area is divided between list and detail pane
+type layout struct {
+	split       bool
+	listWidth   int
+	detailWidth int
+}
+
+// computeLayout picks a split or stacked layout for the given terminal width.
+// A width of 0 means the size is not known yet and disables truncation.
+func computeLayout(width int) layout {
+	if width < splitMinWidth {
+		return layout{listWidth: width}
+	}
+
+	listWidth := max(minListWidth, width*2/5)
+	return layout{
+		split:       true,
+		listWidth:   listWidth,
+		detailWidth: width - listWidth - paneGap,
+	}
+}
+
+// truncate shortens a possibly styled line to fit the given width
+func truncate(s string, width int) string {
+	if width <= 0 {
+		return s
+	}
+	return ansi.Truncate(s, width, "…")
+}
+
+// joinPanes places the list and detail pane side by side
+func (m Model) joinPanes(l layout, list, detail string) string {
+	left := lipgloss.NewStyle().Width(l.listWidth).Render(list)
+	gap := strings.Repeat(" ", paneGap)
+	return lipgloss.JoinHorizontal(lipgloss.Top, left, gap, detail)
+}
+
+// renderDetail renders the detail pane for the item under the cursor
+func (m Model) renderDetail(l layout) string {
+	var content string
+	if m.currentView == ViewProfiles {
+		content = m.renderProfileDetail()
+	} else {
+		content = m.renderSessionDetail()
+	}
+
+	// Account for the border and padding of the box
+	box := m.styles.DetailBox
+	inner := l.detailWidth - box.GetHorizontalFrameSize()
+	content = wrapLines(strings.TrimRight(content, "\n"), inner)
+	return box.Width(l.detailWidth - box.GetHorizontalBorderSize()).Render(content)
+}
+
+// wrapLines wraps every line to the given width, breaking long paths if needed
+func wrapLines(s string, width int) string {
+	if width <= 0 {
+		return s
+	}
+	return ansi.Wrap(s, width, "/ ")
+}
+
+// renderProfileDetail summarizes the selected profile
+func (m Model) renderProfileDetail() string {
+	if row, ok := m.selectedRow(); ok && row.isFolder() {
+		return m.renderFolderDetail(row.folder)
+	}
+
+	index, ok := m.selectedProfile()
+	if !ok {
+		return m.styles.Subtitle.Render("No profile selected")
+	}
+	profile := m.config.Profiles[index]
+
+	var b strings.Builder
+	b.WriteString(m.styles.DetailTitle.Render(profile.Name))
+	b.WriteString("\n\n")
+	b.WriteString(detailRow("Path", CompactPath(profile.Path)))
+	if profile.Folder != "" {
+		b.WriteString(detailRow("Folder", profile.Folder))
+	}
+	if len(profile.Tags) > 0 {
+		b.WriteString(detailRow("Tags", m.styles.Tag.Render("#"+strings.Join(profile.Tags, " #"))))
+	}
+	if names := hookNames(profile.Hooks); len(names) > 0 {
+		b.WriteString(detailRow("Hooks", strings.Join(names, ", ")))
+	}
+	b.WriteString(m.renderScheduleSummary(profile))
+	b.WriteString(m.renderExclusiveSummary(profile))
+	b.WriteString(m.renderDependencySummary(profile))
+	b.WriteString(m.renderKillSwitchSummary(profile))
+
+	if !m.profileValid[index] {
+		b.WriteString(detailRow("File", m.styles.Error.Render("not found")))
+		return b.String()
+	}
+
+	status := m.styles.Muted.Render("disconnected")
+	if m.isProfileConnected(profile.Path) {
+		status = m.styles.Connected.Render("connected")
+	}
+	b.WriteString(detailRow("Status", status))
+
+	file := m.profileFiles[index]
+	if file.cfg == nil {
+		if file.err != nil {
+			b.WriteString(detailRow("File", m.styles.Error.Render(file.err.Error())))
+		}
+		return b.String()
+	}
+	cfg := file.cfg
+	b.WriteString(m.renderOvpnSummary(file))
+	if profile.Server != "" {
+		b.WriteString(detailRow("Server", profile.Server+m.styles.Muted.Render(fmt.Sprintf(" • chosen with %s", m.keys.Servers.Help().Key))))
+	}
+	b.WriteString(m.renderCredentialSummary(profile.Name, cfg))
+	b.WriteString(m.renderCertSummary(index))
+	b.WriteString(m.renderFindings(m.profileLint[index]))
+
+	return b.String()
+}
+
+// renderFindings lists lint findings, errors first
+func (m Model) renderFindings(findings []ovpn.Finding) string {
+	if len(findings) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n")
+	for _, sev := range []ovpn.Severity{ovpn.SeverityError, ovpn.SeverityWarning} {
+		for _, f := range findings {
+			if f.Severity != sev {
+				continue
+			}
+			icon := m.styles.Paused.Render("⚠ ")
+			if sev == ovpn.SeverityError {
+				icon = m.styles.Error.Render("✗ ")
+			}
+			line := f.Message
+			if f.Line > 0 {
+				line = fmt.Sprintf("%s (line %d)", line, f.Line)
+			}
+			b.WriteString(icon + line + "\n")
+		}
+	}
+	return b.String()
+}
+
+// renderOvpnSummary lists the connection settings of a parsed .ovpn file
+func (m Model) renderOvpnSummary(file profileFile) string {
+	var b strings.Builder
+	cfg := file.cfg
+
+	b.WriteString(detailRow("Protocol", cfg.Proto()))
+	b.WriteString(detailRow("Device", cfg.Dev()))
+	if ciphers := cfg.DataCiphers(); len(ciphers) > 0 {
+		b.WriteString(detailRow("Ciphers", strings.Join(ciphers, ", ")))
+	} else if cipher := cfg.Cipher(); cipher != "" {
+		b.WriteString(detailRow("Cipher", cipher))
+	}
+	if auth, file := cfg.AuthUserPass(); auth {
+		value := "prompt"
+		if file != "" {
+			value = "from " + CompactPath(cfg.Resolve(file))
+		}
+		b.WriteString(detailRow("Auth", value))
+	}
+
+	for i, remote := range cfg.Remotes() {
+		label := ""
+		if i == 0 {
+			label = "Remotes"
+		}
+		b.WriteString(detailRow(label, remote.String()))
+	}
+
+	var inline []string
+	for _, block := range cfg.Blocks() {
+		inline = append(inline, block.Name)
+	}
+	if len(inline) > 0 {
+		b.WriteString(detailRow("Inline", strings.Join(inline, ", ")))
+	}
+
+	for i, ref := range cfg.ExternalFiles() {
+		label := ""
+		if i == 0 {
+			label = "Files"
+		}
+		value := ref.Directive.Name + " " + CompactPath(ref.Path)
+		if file.missing[ref.Path] {
+			value = m.styles.Error.Render(value + " (missing)")
+		}
+		b.WriteString(detailRow(label, value))
+	}
+
+	return b.String()
+}
+
+// renderFolderDetail summarizes the profiles in a folder and its subfolders
+func (m Model) renderFolderDetail(folder string) string {
+	var b strings.Builder
+	b.WriteString(m.styles.DetailTitle.Render(folder + "/"))
+	b.WriteString("\n\n")
+
+	var names []string
+	connected := 0
+	tags := make(map[string]bool)
+	for _, p := range m.config.Profiles {
+		if !p.InFolder(folder) {
+			continue
+		}
+		name := p.Name
+		if m.isProfileConnected(p.Path) {
+			connected++
+			name += " " + m.styles.Connected.Render("●")
+		}
+		names = append(names, name)
+		for _, t := range p.Tags {
+			tags["#"+t] = true
+		}
+	}
+
+	b.WriteString(detailRow("Profiles", fmt.Sprintf("%d", len(names))))
+	b.WriteString(detailRow("Connected", fmt.Sprintf("%d", connected)))
+	if len(tags) > 0 {
+		var list []string
+		for t := range tags {
+			list = append(list, t)
+		}
+		sort.Strings(list)
+		b.WriteString(detailRow("Tags", m.styles.Tag.Render(strings.Join(list, " "))))
+	}
+	b.WriteString("\n")
+	for _, name := range names {
+		b.WriteString("  " + name + "\n")
+	}
+
+	return b.String()
+}
+
+// renderSessionDetail shows the selected session and its stats if loaded
+func (m Model) renderSessionDetail() string {
+	index, ok := m.selectedSession()
+	if !ok {
+		return m.styles.Subtitle.Render("No session selected")
+	}
+	session := m.sessions[index]
+
+	var b strings.Builder
+	b.WriteString(m.styles.DetailTitle.Render(session.ConfigName))
+	b.WriteString("\n\n")
+	b.WriteString(detailRow("Status", session.Status))
+	b.WriteString(detailRow("Device", session.Device))
+	b.WriteString(detailRow("Server", session.ConnectedTo))
+	b.WriteString(detailRow("Created", session.Created))
+	b.WriteString(detailRow("Owner", session.Owner))
+	b.WriteString(detailRow("Path", session.Path))
+	b.WriteString(m.renderHealthDetail(session))
+	b.WriteString(m.renderConflictDetail(session))
+
+	if m.selectedStats == nil {
+		b.WriteString("\n")
+		b.WriteString(m.styles.Muted.Render(fmt.Sprintf("Press %s for statistics", m.keys.Stats.Help().Key)))
+		return b.String()
+	}
+
+	stats := m.selectedStats
+	b.WriteString("\n")
+	b.WriteString(detailRow("Bytes In", stats.BytesIn))
+	b.WriteString(detailRow("Bytes Out", stats.BytesOut))
+	b.WriteString(detailRow("Packets In", stats.PacketsIn))
+	b.WriteString(detailRow("Packets Out", stats.PacketsOut))
+	b.WriteString(detailRow("Tunnel In", stats.TunnelIP))
+	if stats.TunnelIPv6 != "" {
+		b.WriteString(detailRow("Tunnel Out", stats.TunnelIPv6))
+	}
+
+	return b.String()
+}
+
+// detailRow formats a label/value pair with aligned values
+func detailRow(label, value string) string {
+	if value == "" {
+		value = "-"
+	}
+	return fmt.Sprintf("%-12s %s\n", label, value)
+}
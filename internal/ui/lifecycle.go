@@ -0,0 +1,137 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"openvpn3-tui/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// SignalMsg asks the TUI to exit because the process received a signal
+type SignalMsg struct {
+	Signal os.Signal
+}
+
+// quitDoneMsg is sent once the sessions to tear down on exit are gone
+type quitDoneMsg struct {
+	err error
+}
+
+// ownedSessions remembers the sessions started by this instance
+type ownedSessions struct {
+	mu    sync.Mutex
+	paths map[string]bool
+}
+
+// add records a session started by this instance
+func (o *ownedSessions) add(path string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.paths == nil {
+		o.paths = make(map[string]bool)
+	}
+	o.paths[path] = true
+}
+
+// has reports whether this instance started the session
+func (o *ownedSessions) has(path string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.paths[path]
+}
+
+// ConnectOnStart adds profiles to connect when the TUI starts, on top of
+// those with autoconnect set
+func (m *Model) ConnectOnStart(names []string) {
+	m.startup = append(m.startup, names...)
+}
+
+// startupProfiles returns the profiles to connect on start, each once
+func (m Model) startupProfiles() []config.Profile {
+	var profiles []config.Profile
+	seen := make(map[string]bool)
+	add := func(p config.Profile) {
+		if !seen[p.Name] {
+			seen[p.Name] = true
+			profiles = append(profiles, p)
+		}
+	}
+	for _, p := range m.config.Profiles {
+		if p.ConnectOnStart {
+			add(p)
+		}
+	}
+	for _, name := range m.startup {
+		if index, ok := m.config.FindProfile(name); ok {
+			add(m.config.Profiles[index])
+		}
+	}
+	return profiles
+}
+
+// QuitError reports sessions that could not be disconnected on exit
+func (m Model) QuitError() error {
+	return m.quitErr
+}
+
+// quit exits after disconnecting the sessions the on_quit policy asks for.
+// Quitting again while that runs exits right away.
+func (m Model) quit() (tea.Model, tea.Cmd) {
+	if m.quitting || m.config.QuitPolicy() == config.QuitLeave {
+		return m, tea.Quit
+	}
+	m.quitting = true
+	m.clearMessages()
+	m.loading = true
+	m.loadingMsg = "Disconnecting before exit, quit again to skip..."
+	return m, tea.Batch(m.spinner.Tick, m.disconnectOnQuit())
+}
+
+// disconnectOnQuit disconnects the sessions covered by the on_quit policy,
+// giving up after the quit timeout
+func (m Model) disconnectOnQuit() tea.Cmd {
+	policy := m.config.QuitPolicy()
+	timeout := m.config.QuitTimeoutDuration()
+	return func() tea.Msg {
+		done := make(chan error, 1)
+		go func() {
+			sessions, err := m.client.ListSessions()
+			if err != nil {
+				done <- err
+				return
+			}
+			// Hooks look up profiles in the current sessions
+			m.sessions = sessions
+
+			failed := 0
+			var first error
+			for _, s := range sessions {
+				if policy == config.QuitDisconnectOwn && !m.owned.has(s.Path) {
+					continue
+				}
+				if res := m.disconnectWithHooks(s); res.err != nil {
+					failed++
+					if first == nil {
+						first = fmt.Errorf("%s: %w", s.ConfigName, res.err)
+					}
+				}
+			}
+			if failed > 0 {
+				done <- fmt.Errorf("%d session(s) not disconnected: %w", failed, first)
+				return
+			}
+			done <- nil
+		}()
+
+		select {
+		case err := <-done:
+			return quitDoneMsg{err: err}
+		case <-time.After(timeout):
+			return quitDoneMsg{err: fmt.Errorf("disconnecting timed out after %s, sessions may still be running", timeout)}
+		}
+	}
+}
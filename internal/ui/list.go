@@ -0,0 +1,152 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// headerHeight covers the title and tab bar
+	headerHeight = 5
+	// footerHeight reserves room for the position indicator, messages and help
+	footerHeight = 7
+	// statsHeight is the height of the stats box shown below the session list
+	statsHeight = 10
+	// minListHeight is the smallest number of rows shown on tiny terminals
+	minListHeight = 3
+)
+
+// listHeight returns how many rows of the current list fit on screen.
+// Zero means the terminal size is unknown and every row is shown.
+func (m Model) listHeight() int {
+	if m.height == 0 {
+		return 0
+	}
+
+	height := m.height - headerHeight - footerHeight
+	if m.filterActive() {
+		height--
+	}
+	if m.currentView == ViewSessions && m.selectedStats != nil && !computeLayout(m.width).split {
+		height -= statsHeight
+	}
+	return max(minListHeight, height)
+}
+
+// listLen returns the number of items in the current, possibly filtered, list
+func (m Model) listLen() int {
+	if m.currentView == ViewProfiles {
+		return len(m.profileRows())
+	}
+	return len(m.sessionMatches())
+}
+
+// cursor returns pointers to the cursor and scroll offset of the current list
+func (m *Model) cursor() (cursor, offset *int) {
+	if m.currentView == ViewProfiles {
+		return &m.profileCursor, &m.profileOffset
+	}
+	return &m.sessionCursor, &m.sessionOffset
+}
+
+// moveCursorBy moves the cursor of the current list by delta, stopping at the ends
+func (m *Model) moveCursorBy(delta int) {
+	cursor, _ := m.cursor()
+	m.moveCursorTo(*cursor + delta)
+}
+
+// moveCursorTo places the cursor of the current list on index
+func (m *Model) moveCursorTo(index int) {
+	cursor, _ := m.cursor()
+	*cursor = clamp(index, 0, m.listLen()-1)
+	m.selectedStats = nil
+	m.ensureCursorVisible()
+}
+
+// pageSize returns how far page up/down moves the cursor
+func (m Model) pageSize() int {
+	if h := m.listHeight(); h > 0 {
+		return h
+	}
+	return max(1, m.listLen())
+}
+
+// takeCount returns the pending numeric prefix and resets it
+func (m *Model) takeCount() (int, bool) {
+	if m.countPrefix == "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(m.countPrefix)
+	m.countPrefix = ""
+	return n, err == nil
+}
+
+// ensureCursorVisible adjusts the scroll offset so the cursor stays on screen
+func (m *Model) ensureCursorVisible() {
+	cursor, offset := m.cursor()
+	*offset = scrollOffset(*cursor, *offset, m.listLen(), m.listHeight())
+}
+
+// clampCursors keeps both cursors and offsets valid after the lists change size
+func (m *Model) clampCursors() {
+	m.profileCursor = clamp(m.profileCursor, 0, len(m.profileRows())-1)
+	m.sessionCursor = clamp(m.sessionCursor, 0, len(m.sessionMatches())-1)
+
+	view := m.currentView
+	for _, v := range []View{ViewProfiles, ViewSessions} {
+		m.currentView = v
+		m.ensureCursorVisible()
+	}
+	m.currentView = view
+}
+
+// scrollOffset returns the first visible row so that cursor lies within the window
+func scrollOffset(cursor, offset, total, height int) int {
+	if height <= 0 || total <= height {
+		return 0
+	}
+	if cursor < offset {
+		offset = cursor
+	}
+	if cursor >= offset+height {
+		offset = cursor - height + 1
+	}
+	return clamp(offset, 0, total-height)
+}
+
+// visibleRange returns the half-open range of rows to render
+func visibleRange(offset, total, height int) (int, int) {
+	if height <= 0 || total <= height {
+		return 0, total
+	}
+	return offset, min(total, offset+height)
+}
+
+// renderPosition shows the cursor position when the list does not fit on screen
+func (m Model) renderPosition(cursor, offset, total int) string {
+	height := m.listHeight()
+	if height <= 0 || total <= height {
+		return ""
+	}
+
+	start, end := visibleRange(offset, total, height)
+	indicator := fmt.Sprintf("%d/%d", cursor+1, total)
+	if start > 0 {
+		indicator = "↑ " + indicator
+	}
+	if end < total {
+		indicator += " ↓"
+	}
+	return m.styles.Muted.Render("  "+indicator) + "\n"
+}
+
+// clamp limits v to [lo, hi], returning lo when the range is empty
+func clamp(v, lo, hi int) int {
+	if v > hi {
+		v = hi
+	}
+	if v < lo {
+		v = lo
+	}
+	return v
+}
@@ -0,0 +1,139 @@
+package ui
+
+import (
+	"fmt"
+	"testing"
+
+	"openvpn3-tui/internal/config"
+	"openvpn3-tui/internal/openvpn"
+)
+
+// listModel returns a model with the given number of profiles and sessions,
+// tall enough to show height rows of a list
+func listModel(profiles, sessions, height int) Model {
+	m := Model{config: &config.Config{}, collapsed: make(map[string]bool)}
+	for i := range profiles {
+		m.config.Profiles = append(m.config.Profiles, config.Profile{Name: fmt.Sprintf("profile %d", i)})
+	}
+	for i := range sessions {
+		m.sessions = append(m.sessions, openvpn.Session{Path: fmt.Sprintf("/session/%d", i)})
+	}
+	if height > 0 {
+		m.height = height + headerHeight + footerHeight
+	}
+	return m
+}
+
+func TestScrollOffset(t *testing.T) {
+	tests := []struct {
+		name                          string
+		cursor, offset, total, height int
+		want                          int
+	}{
+		{"unknown height", 30, 10, 50, 0, 0},
+		{"list fits", 4, 2, 5, 10, 0},
+		{"cursor in window", 12, 10, 50, 5, 10},
+		{"cursor above window", 3, 10, 50, 5, 3},
+		{"cursor below window", 20, 10, 50, 5, 16},
+		{"last row", 49, 0, 50, 5, 45},
+		{"offset past end", 49, 48, 50, 5, 45},
+		{"height smaller than list", 2, 0, 4, 3, 0},
+		{"height smaller than list at end", 3, 0, 4, 3, 1},
+	}
+	for _, tt := range tests {
+		if got := scrollOffset(tt.cursor, tt.offset, tt.total, tt.height); got != tt.want {
+			t.Errorf("%s: scrollOffset(%d, %d, %d, %d) = %d, want %d",
+				tt.name, tt.cursor, tt.offset, tt.total, tt.height, got, tt.want)
+		}
+	}
+}
+
+func TestVisibleRange(t *testing.T) {
+	tests := []struct {
+		name                  string
+		offset, total, height int
+		start, end            int
+	}{
+		{"unknown height", 5, 50, 0, 0, 50},
+		{"list fits", 3, 5, 10, 0, 5},
+		{"empty list", 0, 0, 5, 0, 0},
+		{"first page", 0, 50, 5, 0, 5},
+		{"middle", 10, 50, 5, 10, 15},
+		{"last page", 45, 50, 5, 45, 50},
+		{"offset near end", 48, 50, 5, 48, 50},
+	}
+	for _, tt := range tests {
+		start, end := visibleRange(tt.offset, tt.total, tt.height)
+		if start != tt.start || end != tt.end {
+			t.Errorf("%s: visibleRange(%d, %d, %d) = %d, %d, want %d, %d",
+				tt.name, tt.offset, tt.total, tt.height, start, end, tt.start, tt.end)
+		}
+	}
+}
+
+func TestClampCursors(t *testing.T) {
+	tests := []struct {
+		name                      string
+		height                    int
+		cursor, offset            int // Before the lists shrink
+		profiles, sessions        int // After the lists shrink
+		profileCursor, profileOff int
+		sessionCursor, sessionOff int
+	}{
+		{"shrink above cursor", 5, 15, 12, 20, 20, 15, 12, 15, 12},
+		{"shrink below cursor", 5, 15, 12, 8, 10, 7, 3, 9, 5},
+		{"shrink to fit", 5, 15, 12, 4, 3, 3, 0, 2, 0},
+		{"shrink to zero", 5, 15, 12, 0, 0, 0, 0, 0, 0},
+		{"unknown height", 0, 15, 12, 8, 10, 7, 0, 9, 0},
+	}
+	for _, tt := range tests {
+		m := listModel(tt.profiles, tt.sessions, tt.height)
+		m.profileCursor, m.profileOffset = tt.cursor, tt.offset
+		m.sessionCursor, m.sessionOffset = tt.cursor, tt.offset
+		m.clampCursors()
+
+		if m.profileCursor != tt.profileCursor || m.profileOffset != tt.profileOff {
+			t.Errorf("%s: profile cursor %d offset %d, want %d and %d",
+				tt.name, m.profileCursor, m.profileOffset, tt.profileCursor, tt.profileOff)
+		}
+		if m.sessionCursor != tt.sessionCursor || m.sessionOffset != tt.sessionOff {
+			t.Errorf("%s: session cursor %d offset %d, want %d and %d",
+				tt.name, m.sessionCursor, m.sessionOffset, tt.sessionCursor, tt.sessionOff)
+		}
+		if m.currentView != ViewProfiles {
+			t.Errorf("%s: clampCursors() switched to view %d", tt.name, m.currentView)
+		}
+	}
+}
+
+func TestPageMoves(t *testing.T) {
+	tests := []struct {
+		name           string
+		total, height  int
+		cursor, offset int
+		pages          int // Negative pages move up
+		wantCursor     int
+		wantOffset     int
+	}{
+		{"down from top", 20, 5, 0, 0, 1, 5, 1},
+		{"down at bottom", 20, 5, 19, 15, 1, 19, 15},
+		{"down past bottom", 20, 5, 17, 13, 1, 19, 15},
+		{"up at top", 20, 5, 0, 0, -1, 0, 0},
+		{"up past top", 20, 5, 3, 0, -1, 0, 0},
+		{"up from bottom", 20, 5, 19, 15, -1, 14, 14},
+		{"unknown height", 20, 0, 0, 0, 1, 19, 0},
+		{"height larger than list", 4, 10, 1, 0, 1, 3, 0},
+		{"empty list", 0, 5, 0, 0, 1, 0, 0},
+	}
+	for _, tt := range tests {
+		m := listModel(0, tt.total, tt.height)
+		m.currentView = ViewSessions
+		m.sessionCursor, m.sessionOffset = tt.cursor, tt.offset
+		m.moveCursorBy(tt.pages * m.pageSize())
+
+		if m.sessionCursor != tt.wantCursor || m.sessionOffset != tt.wantOffset {
+			t.Errorf("%s: cursor %d offset %d, want %d and %d",
+				tt.name, m.sessionCursor, m.sessionOffset, tt.wantCursor, tt.wantOffset)
+		}
+	}
+}
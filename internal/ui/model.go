@@ -4,13 +4,27 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
+	"openvpn3-tui/internal/autoconnect"
 	"openvpn3-tui/internal/config"
+	"openvpn3-tui/internal/conflict"
+	"openvpn3-tui/internal/credentials"
+	"openvpn3-tui/internal/health"
+	"openvpn3-tui/internal/hooks"
+	"openvpn3-tui/internal/killswitch"
+	"openvpn3-tui/internal/network"
 	"openvpn3-tui/internal/openvpn"
+	"openvpn3-tui/internal/ovpn"
+	"openvpn3-tui/internal/schedule"
 
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 )
 
 // View represents the current view/tab
@@ -28,6 +42,15 @@ const (
 	InputNone InputMode = iota
 	InputProfilePath
 	InputProfileName
+	InputProfileFolder
+	InputProfileTags
+	InputImportPath
+	InputExportPath
+	InputCredUsername
+	InputCredPassword
+	InputPassphrase
+	InputPassphraseConfirm
+	InputTOTPSecret
 )
 
 // ConfirmMode represents what confirmation we're requesting
@@ -36,36 +59,122 @@ type ConfirmMode int
 const (
 	ConfirmNone ConfirmMode = iota
 	ConfirmDeleteProfile
+	ConfirmInlineProfile
+	ConfirmForgetCredentials
+	ConfirmDisconnectDependencies
+	ConfirmConnectConflicts
 )
 
+// sessionClient manages openvpn3 sessions. It is implemented by
+// openvpn.Client and stubbed in tests.
+type sessionClient interface {
+	ListSessions() ([]openvpn.Session, error)
+	GetSessionStats(sessionPath string) (*openvpn.SessionStats, error)
+	Connect(configPath string, answer openvpn.AnswerFunc) error
+	ConnectTo(configPath string, server openvpn.Server, answer openvpn.AnswerFunc) error
+	Disconnect(sessionPath string) error
+	WaitClosed(paths []string, timeout time.Duration) error
+}
+
 // Model is the main application model
 type Model struct {
 	// Core state
 	config   *config.Config
-	client   *openvpn.Client
+	client   sessionClient
 	sessions []openvpn.Session
 
 	// UI state
-	currentView    View
-	profileCursor  int
-	sessionCursor  int
-	profileValid   map[int]bool
-	selectedStats  *openvpn.SessionStats
-	loading        bool
-	loadingMsg     string
-	spinner        spinner.Model
-	styles         *Styles
+	currentView   View
+	profileCursor int
+	sessionCursor int
+	profileOffset int
+	sessionOffset int
+	countPrefix   string // Pending numeric prefix for jumps, e.g. "12" before G
+	profileValid  map[int]bool
+	profileLint   map[int][]ovpn.Finding
+	profileCerts  map[int]certInfo
+	profileFiles  map[int]profileFile
+	certCache     *certCache // Certificates by config file, kept across reloads
+	selectedStats *openvpn.SessionStats
+	loading       bool
+	loadingMsg    string
+	spinner       spinner.Model
+	styles        *Styles
+	keys          KeyMap
+	help          help.Model
+	showHelp      bool
+	showCerts     bool
+	showHooks     bool
+	hookLog       *hooks.Log
+
+	// Filter state
+	filtering   bool
+	filterInput textinput.Model
+	filters     map[View]string // Active filter query per view
+	collapsed   map[string]bool // Collapsed folders in the Profiles view
 
 	// Input state
 	inputMode  InputMode
 	textInput  textinput.Model
 	newProfile config.Profile
+	editIndex  int // Index of the profile being edited, -1 when adding
 	completer  *PathCompleter
 
+	// Import state, nil unless previewing an import
+	importing *importState
+
+	// Server picker state, nil unless picking a server
+	servers *serverState
+
+	// Export state
+	exportIndices []int // Profiles to export
+	exportRedact  bool  // Strip private keys and credentials
+
+	// Credential state
+	creds       credentials.Store
+	credSaved   map[string]bool              // Profiles with saved credentials, nil while locked
+	credEntry   credentials.Credentials      // Credentials being entered
+	credConnect bool                         // Connect once the credentials are saved
+	otpSecrets  map[string]*credentials.TOTP // TOTP secrets read for the detail pane, nil when none
+	afterUnlock func(Model) (tea.Model, tea.Cmd)
+	passphrase  string // New passphrase waiting to be repeated
+
+	// Auto-connect state, netSource is nil without rules
+	netSource  network.Source
+	netKey     string // Network the rules last ran for
+	netMAC     string // Gateway hardware address when the rules last ran
+	netMatched bool   // A rule fired for the current network
+	netErr     string // Last error reading the network
+
+	// Lifecycle state
+	startup  []string       // Profiles to connect on start, from the command line
+	owned    *ownedSessions // Sessions started by this instance
+	ensureMu *sync.Mutex    // Serializes automatic connects
+	quitting bool           // Disconnecting sessions before exit
+	quitErr  error
+
+	// Schedule state
+	scheduleActive map[string]bool        // Whether each scheduled profile was inside a window
+	idle           map[string]idleCounter // Traffic of sessions with an idle timeout
+
+	// Health state
+	health        map[string]health.Report // Latest checks of each connected profile
+	healthRunning bool                     // A round of checks is in progress
+
+	// Kill switch state, shared with commands
+	killSwitch *killSwitch
+
+	// Conflict state
+	footprints *conflict.Store                // Routes and DNS of each profile's last session
+	conflicts  map[string][]conflict.Conflict // By session path
+
 	// Confirm state
 	confirmMode   ConfirmMode
-	confirmTarget string // Name of item being confirmed
-	confirmIndex  int    // Index of item being confirmed
+	confirmTarget string           // Name of item being confirmed
+	confirmIndex  int              // Index of item being confirmed
+	confirmDeps   []config.Profile // Dependencies offered for disconnecting
+
+	confirmConflicts []conflict.Conflict // What a confirmed connect would conflict with
 
 	// Messages
 	statusMsg string
@@ -90,12 +199,26 @@ type statsRefreshMsg struct {
 
 // connectMsg is sent after a connection attempt
 type connectMsg struct {
-	err error
+	profile      string
+	err          error
+	hookErr      error    // A failed hook that did not stop the connection
+	switched     string   // Exclusive siblings disconnected to make way
+	dependencies []string // Dependencies connected first
 }
 
 // disconnectMsg is sent after a disconnect attempt
 type disconnectMsg struct {
-	err error
+	profile string // Empty when the session matches no profile
+	err     error
+	hookErr error
+}
+
+// groupMsg is sent after connecting or disconnecting a group of profiles
+type groupMsg struct {
+	action   string // "connected" or "disconnected"
+	done     int
+	errs     []error
+	hookErrs []error
 }
 
 // NewModel creates a new application model
@@ -113,22 +236,82 @@ func NewModel(cfg *config.Config) Model {
 	s.Spinner = spinner.Dot
 	s.Style = styles.Spinner
 
-	return Model{
-		config:       cfg,
-		client:       openvpn.NewClient(),
-		profileValid: cfg.ValidateProfiles(),
-		textInput:    ti,
-		completer:    NewPathCompleter(),
-		spinner:      s,
-		styles:       styles,
-		loading:      true,
-		loadingMsg:   "Fetching sessions...",
+	m := Model{
+		config:         cfg,
+		client:         openvpn.NewClient(),
+		textInput:      ti,
+		editIndex:      -1,
+		completer:      NewPathCompleter(),
+		spinner:        s,
+		styles:         styles,
+		keys:           NewKeyMap(cfg.Keymap),
+		filterInput:    newFilterInput(),
+		filters:        make(map[View]string),
+		collapsed:      make(map[string]bool),
+		help:           newHelp(styles),
+		hookLog:        &hooks.Log{},
+		owned:          &ownedSessions{},
+		scheduleActive: make(map[string]bool),
+		idle:           make(map[string]idleCounter),
+		health:         make(map[string]health.Report),
+		killSwitch:     newKillSwitch(killswitch.NewRunner(cfg.KillSwitchRunner)),
+		ensureMu:       &sync.Mutex{},
+		certCache:      newCertCache(),
+		loading:        true,
+		loadingMsg:     "Fetching sessions...",
+	}
+	m.validateProfiles()
+
+	// Config errors come before warnings such as expiring certificates
+	var problems []string
+	problems = append(problems, schedule.Check(cfg)...)
+	problems = append(problems, health.Check(cfg)...)
+	problems = append(problems, killswitch.Check(cfg)...)
+	if err := m.openCredentials(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if err := m.openFootprints(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if warning := m.certWarning(); warning != "" {
+		problems = append(problems, warning)
+	}
+	m.errorMsg = summarizeProblems(problems)
+
+	if ac := cfg.AutoConnect; ac != nil && len(ac.Rules) > 0 {
+		m.netSource = autoconnect.NewSource(ac)
+	}
+	return m
+}
+
+// summarizeProblems shows the first of several problems and how many more there are
+func summarizeProblems(problems []string) string {
+	switch len(problems) {
+	case 0:
+		return ""
+	case 1:
+		return problems[0]
 	}
+	return fmt.Sprintf("%s (and %d more)", problems[0], len(problems)-1)
 }
 
 // Init initializes the model
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(m.spinner.Tick, m.refreshSessions(), WatchTheme())
+	cmds := []tea.Cmd{m.spinner.Tick, m.refreshSessions(), WatchTheme(), otpTick()}
+	if m.netSource != nil {
+		cmds = append(cmds, watchNetwork(m.netSource, true))
+	}
+	if profiles := m.startupProfiles(); len(profiles) > 0 {
+		cmds = append(cmds, m.ensureProfiles("connected", profiles, nil))
+	}
+	if m.hasAutomation() {
+		// Check right away instead of waiting for the first minute
+		cmds = append(cmds, func() tea.Msg { return scheduleTickMsg(time.Now()) })
+	}
+	if m.hasHealthChecks() {
+		cmds = append(cmds, healthTick())
+	}
+	return tea.Batch(cmds...)
 }
 
 // Update handles messages
@@ -142,6 +325,14 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, cmd
 
 	case tea.KeyMsg:
+		// Only quitting again is possible while sessions are torn down
+		if m.quitting {
+			if key.Matches(msg, m.keys.Quit) {
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+
 		// Handle input mode separately
 		if m.inputMode != InputNone {
 			return m.handleInputMode(msg)
@@ -152,11 +343,47 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.handleConfirmMode(msg)
 		}
 
-		switch msg.String() {
-		case "q", "ctrl+c":
-			return m, tea.Quit
+		// Handle the import preview separately
+		if m.importing != nil {
+			return m.handleImportMode(msg)
+		}
 
-		case "tab":
+		// Handle the server picker separately
+		if m.servers != nil {
+			return m.handleServerMode(msg)
+		}
+
+		// Handle filter mode separately
+		if m.filtering {
+			return m.handleFilterMode(msg)
+		}
+
+		// Any key closes the help, certificate and hook log overlays
+		if m.showHelp || m.showCerts || m.showHooks {
+			if key.Matches(msg, m.keys.Quit) {
+				return m.quit()
+			}
+			m.showHelp = false
+			m.showCerts = false
+			m.showHooks = false
+			return m, nil
+		}
+
+		// A numeric prefix applies to the next movement key only
+		prefix := m.countPrefix
+		count, hasCount := m.takeCount()
+		if !hasCount {
+			count = 1
+		}
+
+		switch {
+		case key.Matches(msg, m.keys.Quit):
+			return m.quit()
+
+		case key.Matches(msg, m.keys.Help):
+			m.showHelp = true
+
+		case key.Matches(msg, m.keys.SwitchView):
 			if m.currentView == ViewProfiles {
 				m.currentView = ViewSessions
 			} else {
@@ -164,40 +391,167 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			m.clearMessages()
 
-		case "up", "k":
-			m.moveCursorUp()
+		case key.Matches(msg, m.keys.Up):
+			m.moveCursorBy(-count)
 
-		case "down", "j":
-			m.moveCursorDown()
+		case key.Matches(msg, m.keys.Down):
+			m.moveCursorBy(count)
 
-		case "enter":
+		case key.Matches(msg, m.keys.PageUp):
+			m.moveCursorBy(-count * m.pageSize())
+
+		case key.Matches(msg, m.keys.PageDown):
+			m.moveCursorBy(count * m.pageSize())
+
+		case key.Matches(msg, m.keys.Home):
+			m.moveCursorTo(0)
+
+		case key.Matches(msg, m.keys.End):
+			if hasCount {
+				m.moveCursorTo(count - 1)
+			} else {
+				m.moveCursorTo(m.listLen() - 1)
+			}
+
+		case key.Matches(msg, m.keys.Select):
 			return m.handleEnter()
 
-		case "a":
+		case key.Matches(msg, m.keys.Servers):
+			if m.currentView == ViewProfiles {
+				m.clearMessages()
+				if index, ok := m.selectedProfile(); ok {
+					return m.startServers(index)
+				}
+				m.errorMsg = "Select a profile first"
+			}
+
+		case key.Matches(msg, m.keys.Add):
 			if m.currentView == ViewProfiles {
 				return m.startAddProfile()
 			}
 
-		case "d", "delete":
+		case key.Matches(msg, m.keys.Import):
+			if m.currentView == ViewProfiles {
+				return m.startImport()
+			}
+
+		case key.Matches(msg, m.keys.Export):
+			if m.currentView == ViewProfiles {
+				return m.startExport()
+			}
+
+		case key.Matches(msg, m.keys.Edit):
+			if m.currentView == ViewProfiles {
+				return m.startEditProfile()
+			}
+
+		case key.Matches(msg, m.keys.MoveUp):
+			if m.currentView == ViewProfiles {
+				m.moveProfile(-count)
+			}
+
+		case key.Matches(msg, m.keys.MoveDown):
+			if m.currentView == ViewProfiles {
+				m.moveProfile(count)
+			}
+
+		case key.Matches(msg, m.keys.Duplicate):
+			if m.currentView == ViewProfiles {
+				m.duplicateProfile()
+			}
+
+		case key.Matches(msg, m.keys.Inline):
+			if m.currentView == ViewProfiles {
+				m.confirmInline()
+			}
+
+		case key.Matches(msg, m.keys.Folder):
+			if m.currentView == ViewProfiles {
+				return m.startEditField(InputProfileFolder)
+			}
+
+		case key.Matches(msg, m.keys.Tags):
+			if m.currentView == ViewProfiles {
+				return m.startEditField(InputProfileTags)
+			}
+
+		case key.Matches(msg, m.keys.ConnectGroup):
+			if m.currentView == ViewProfiles {
+				return m.connectGroup()
+			}
+
+		case key.Matches(msg, m.keys.DisconnectGroup):
+			if m.currentView == ViewProfiles {
+				return m.disconnectGroup()
+			}
+
+		case key.Matches(msg, m.keys.Delete):
 			return m.handleDelete()
 
-		case "r":
+		case key.Matches(msg, m.keys.Refresh):
 			m.clearMessages()
 			m.loading = true
 			m.loadingMsg = "Refreshing sessions..."
 			return m, tea.Batch(m.spinner.Tick, m.refreshSessions())
 
-		case "s":
-			if m.currentView == ViewSessions && len(m.sessions) > 0 {
-				m.loading = true
-				m.loadingMsg = "Fetching stats..."
-				return m, tea.Batch(m.spinner.Tick, m.fetchStats(m.sessions[m.sessionCursor].Path))
+		case key.Matches(msg, m.keys.Stats):
+			if m.currentView == ViewSessions {
+				return m.handleEnter()
+			}
+
+		case key.Matches(msg, m.keys.Certs):
+			if m.currentView == ViewProfiles {
+				m.clearMessages()
+				if _, ok := m.selectedProfile(); ok {
+					m.showCerts = true
+				} else {
+					m.errorMsg = "Select a profile first"
+				}
+			}
+
+		case key.Matches(msg, m.keys.Credentials):
+			if m.currentView == ViewProfiles {
+				m.clearMessages()
+				if index, ok := m.selectedProfile(); ok {
+					return m.startCredentials(index, false)
+				}
+				m.errorMsg = "Select a profile first"
+			}
+
+		case key.Matches(msg, m.keys.Forget):
+			if m.currentView == ViewProfiles {
+				return m.confirmForget()
+			}
+
+		case key.Matches(msg, m.keys.TOTP):
+			if m.currentView == ViewProfiles {
+				m.clearMessages()
+				if index, ok := m.selectedProfile(); ok {
+					return m.startTOTP(index)
+				}
+				m.errorMsg = "Select a profile first"
+			}
+
+		case key.Matches(msg, m.keys.HookLog):
+			m.showHooks = true
+
+		case key.Matches(msg, m.keys.Filter):
+			return m.startFilter()
+
+		default:
+			// Collect digits for a count prefix
+			if r := msg.Runes; msg.Type == tea.KeyRunes && len(r) == 1 && r[0] >= '0' && r[0] <= '9' {
+				if prefix != "" || r[0] != '0' {
+					m.countPrefix = prefix + string(r)
+				}
 			}
 		}
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+		m.help.Width = msg.Width
+		m.clampCursors()
 
 	case sessionRefreshMsg:
 		m.loading = false
@@ -205,29 +559,46 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.errorMsg = fmt.Sprintf("Failed to fetch sessions: %v", msg.err)
 		} else {
 			m.sessions = msg.sessions
-			if m.sessionCursor >= len(m.sessions) {
-				m.sessionCursor = max(0, len(m.sessions)-1)
-			}
+			m.clampCursors()
+			cmds = append(cmds, m.analyzeConflicts(msg.sessions), m.adoptKillSwitches(msg.sessions))
+		}
+
+	case killSwitchMsg:
+		if len(msg.errs) > 0 {
+			m.errorMsg = msg.errs[0].Error()
 		}
 
+	case conflictsMsg:
+		return m.handleConflicts(msg)
+
+	case conflictCheckMsg:
+		return m.handleConflictCheck(msg)
+
 	case statsRefreshMsg:
 		m.loading = false
 		if msg.err != nil {
 			m.errorMsg = fmt.Sprintf("Failed to fetch stats: %v", msg.err)
 		} else {
 			m.selectedStats = msg.stats
+			m.ensureCursorVisible()
 		}
 
 	case connectMsg:
 		m.loading = false
 		if msg.err != nil {
-			m.errorMsg = fmt.Sprintf("Connection failed: %v", msg.err)
-		} else {
-			m.statusMsg = "Connected successfully!"
-			m.loading = true
-			m.loadingMsg = "Refreshing sessions..."
-			cmds = append(cmds, m.spinner.Tick, m.refreshSessions())
+			return m.handleConnectError(msg)
+		}
+		m.statusMsg = "Connected successfully!"
+		if msg.switched != "" {
+			m.statusMsg = fmt.Sprintf("Switched from %s to %s", msg.switched, msg.profile)
+		}
+		if len(msg.dependencies) > 0 {
+			m.statusMsg += fmt.Sprintf(" (connected %s first)", strings.Join(msg.dependencies, ", "))
 		}
+		m.errorMsg = m.hookError(msg.hookErr)
+		m.loading = true
+		m.loadingMsg = "Refreshing sessions..."
+		cmds = append(cmds, m.spinner.Tick, m.refreshSessions())
 
 	case disconnectMsg:
 		m.loading = false
@@ -235,17 +606,75 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.errorMsg = fmt.Sprintf("Disconnect failed: %v", msg.err)
 		} else {
 			m.statusMsg = "Disconnected successfully!"
+			m.errorMsg = m.hookError(msg.hookErr)
+			if index, ok := m.config.FindProfile(msg.profile); ok {
+				if deps := m.unneededDependencies(m.config.Profiles[index]); len(deps) > 0 {
+					m.confirmMode = ConfirmDisconnectDependencies
+					m.confirmTarget = msg.profile
+					m.confirmDeps = deps
+				}
+			}
 			m.selectedStats = nil
 			m.loading = true
 			m.loadingMsg = "Refreshing sessions..."
 			cmds = append(cmds, m.spinner.Tick, m.refreshSessions())
 		}
 
+	case groupMsg:
+		m.loading = false
+		if len(msg.errs) > 0 {
+			m.errorMsg = fmt.Sprintf("%d of %d failed: %v", len(msg.errs), msg.done+len(msg.errs), msg.errs[0])
+		} else {
+			m.statusMsg = fmt.Sprintf("%s %d profile(s)", strings.ToUpper(msg.action[:1])+msg.action[1:], msg.done)
+			if len(msg.hookErrs) > 0 {
+				m.errorMsg = m.hookError(msg.hookErrs[0])
+			}
+		}
+		m.selectedStats = nil
+		m.loading = true
+		m.loadingMsg = "Refreshing sessions..."
+		cmds = append(cmds, m.spinner.Tick, m.refreshSessions())
+
+	case SignalMsg:
+		return m.quit()
+
+	case quitDoneMsg:
+		m.quitErr = msg.err
+		return m, tea.Quit
+
+	case scheduleTickMsg:
+		return m.handleScheduleTick(time.Time(msg))
+
+	case idleMsg:
+		return m.handleIdle(msg)
+
+	case probeMsg:
+		return m.handleProbe(msg)
+
+	case healthTickMsg:
+		return m.handleHealthTick(time.Time(msg))
+
+	case healthMsg:
+		return m.handleHealth(msg)
+
+	case healthHookMsg:
+		if msg.err != nil {
+			m.errorMsg = m.hookError(msg.err)
+		}
+
+	case networkMsg:
+		return m.handleNetwork(msg)
+
+	case otpTickMsg:
+		m.loadSelectedOTP()
+		cmds = append(cmds, otpTick())
+
 	case ThemeChangedMsg:
 		// Reload theme and recreate styles
 		theme := LoadTheme()
 		m.styles = NewStyles(theme)
 		m.spinner.Style = m.styles.Spinner
+		m.help.Styles = newHelp(m.styles).Styles
 		// Restart the theme watcher
 		cmds = append(cmds, WatchTheme())
 	}
@@ -257,14 +686,22 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m Model) handleInputMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "esc":
-		m.inputMode = InputNone
-		m.newProfile = config.Profile{}
+		m = m.endCredentialInput()
+		m.completer.SetExtensions(".ovpn")
 		m.completer.Clear()
+		m.exportIndices = nil
+		m.clearMessages()
+		return m, nil
+
+	case "ctrl+r":
+		if m.inputMode == InputExportPath {
+			m.exportRedact = !m.exportRedact
+		}
 		return m, nil
 
 	case "tab":
 		// Tab completion - only in path input mode
-		if m.inputMode == InputProfilePath && m.completer.HasSuggestions() {
+		if m.completesPath() && m.completer.HasSuggestions() {
 			m.completer.SelectNext()
 			if selected := m.completer.GetSelected(); selected != "" {
 				m.textInput.SetValue(selected)
@@ -277,7 +714,7 @@ func (m Model) handleInputMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	case "shift+tab":
 		// Reverse tab completion
-		if m.inputMode == InputProfilePath && m.completer.HasSuggestions() {
+		if m.completesPath() && m.completer.HasSuggestions() {
 			m.completer.SelectPrev()
 			if selected := m.completer.GetSelected(); selected != "" {
 				m.textInput.SetValue(selected)
@@ -289,21 +726,43 @@ func (m Model) handleInputMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	case "enter":
 		value := strings.TrimSpace(m.textInput.Value())
+
+		// Secrets are taken verbatim
+		switch m.inputMode {
+		case InputPassphrase, InputPassphraseConfirm:
+			return m.unlockCredentials(m.textInput.Value())
+		case InputCredPassword:
+			return m.saveCredentials(m.textInput.Value())
+		case InputTOTPSecret:
+			return m.saveTOTP(value)
+		}
+
+		// Folder and tags may be cleared by submitting an empty value
+		if m.inputMode == InputProfileFolder || m.inputMode == InputProfileTags {
+			return m.saveField(value)
+		}
+
 		if value == "" {
 			return m, nil
 		}
 
+		if m.inputMode == InputImportPath {
+			return m.scanImport(expandHome(value))
+		}
+
+		if m.inputMode == InputExportPath {
+			return m.finishExport(expandHome(value))
+		}
+
+		if m.inputMode == InputCredUsername {
+			return m.startPassword(value)
+		}
+
 		if m.inputMode == InputProfilePath {
-			// Expand ~ before saving
-			expandedPath := value
-			if strings.HasPrefix(value, "~") {
-				if home, err := os.UserHomeDir(); err == nil {
-					expandedPath = home + value[1:]
-				}
-			}
-			m.newProfile.Path = expandedPath
+			m.newProfile.Path = expandHome(value)
 			m.inputMode = InputProfileName
-			m.textInput.SetValue("")
+			m.textInput.SetValue(m.newProfile.Name)
+			m.textInput.CursorEnd()
 			m.textInput.Placeholder = "Enter a friendly name"
 			m.completer.Clear()
 			return m, nil
@@ -311,16 +770,38 @@ func (m Model) handleInputMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 		if m.inputMode == InputProfileName {
 			m.newProfile.Name = value
-			m.config.AddProfile(m.newProfile.Name, m.newProfile.Path)
+
+			var err error
+			var oldName string
+			index := m.editIndex
+			if index >= 0 {
+				oldName = m.config.Profiles[index].Name
+				err = m.config.UpdateProfile(index, m.newProfile.Name, m.newProfile.Path)
+			} else {
+				err = m.config.AddProfile(m.newProfile.Name, m.newProfile.Path)
+				index = len(m.config.Profiles) - 1
+			}
+			if err != nil {
+				// Stay in the name prompt so the user can pick another name
+				m.errorMsg = err.Error()
+				return m, nil
+			}
+
+			m.clearMessages()
 			if err := m.config.Save(); err != nil {
 				m.errorMsg = fmt.Sprintf("Failed to save config: %v", err)
+			} else if m.editIndex >= 0 {
+				m.statusMsg = fmt.Sprintf("Updated profile: %s", m.newProfile.Name)
+				m.renameCredentials(oldName, m.newProfile.Name)
 			} else {
 				m.statusMsg = fmt.Sprintf("Added profile: %s", m.newProfile.Name)
 			}
-			m.profileValid = m.config.ValidateProfiles()
+			m.validateProfiles()
 			m.inputMode = InputNone
 			m.newProfile = config.Profile{}
+			m.editIndex = -1
 			m.completer.Clear()
+			m.selectProfile(index)
 			return m, nil
 		}
 	}
@@ -329,41 +810,74 @@ func (m Model) handleInputMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	m.textInput, cmd = m.textInput.Update(msg)
 
 	// Update path suggestions after each keystroke (only in path mode)
-	if m.inputMode == InputProfilePath {
+	if m.completesPath() {
 		m.completer.Update(m.textInput.Value())
 	}
 
 	return m, cmd
 }
 
+// completesPath reports whether the current input offers path completion
+func (m Model) completesPath() bool {
+	return m.inputMode == InputProfilePath || m.inputMode == InputImportPath
+}
+
+// expandHome expands a leading ~ to the home directory
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return home + path[1:]
+		}
+	}
+	return path
+}
+
 // handleConfirmMode handles key events during confirm mode
 func (m Model) handleConfirmMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "y", "Y", "enter":
+	var cmd tea.Cmd
+	switch {
+	case key.Matches(msg, m.keys.Confirm):
 		// Perform the confirmed action
-		if m.confirmMode == ConfirmDeleteProfile {
+		switch m.confirmMode {
+		case ConfirmDeleteProfile:
 			name := m.confirmTarget
 			m.config.RemoveProfile(m.confirmIndex)
 			if err := m.config.Save(); err != nil {
 				m.errorMsg = fmt.Sprintf("Failed to save config: %v", err)
 			} else {
 				m.statusMsg = fmt.Sprintf("Removed profile: %s", name)
+				if m.credSaved[name] {
+					m.forgetCredentials(name)
+				}
 			}
-			m.profileValid = m.config.ValidateProfiles()
-			if m.profileCursor >= len(m.config.Profiles) {
-				m.profileCursor = max(0, len(m.config.Profiles)-1)
-			}
+			m.validateProfiles()
+			m.clampCursors()
+		case ConfirmInlineProfile:
+			m.inlineProfile(m.confirmIndex)
+		case ConfirmForgetCredentials:
+			m.forgetCredentials(m.confirmTarget)
+		case ConfirmDisconnectDependencies:
+			m.statusMsg = fmt.Sprintf("Disconnecting %s...", profileNames(m.confirmDeps))
+			cmd = m.ensureProfiles("disconnected", nil, m.confirmDeps)
+		case ConfirmConnectConflicts:
+			var next tea.Model
+			next, cmd = m.startConnect(m.confirmIndex)
+			m = next.(Model)
 		}
 		m.confirmMode = ConfirmNone
 		m.confirmTarget = ""
 		m.confirmIndex = 0
-		return m, nil
+		m.confirmDeps = nil
+		m.confirmConflicts = nil
+		return m, cmd
 
-	case "n", "N", "esc":
+	case key.Matches(msg, m.keys.Cancel):
 		// Cancel the action
 		m.confirmMode = ConfirmNone
 		m.confirmTarget = ""
 		m.confirmIndex = 0
+		m.confirmDeps = nil
+		m.confirmConflicts = nil
 		return m, nil
 	}
 
@@ -373,6 +887,8 @@ func (m Model) handleConfirmMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 // startAddProfile enters input mode for adding a profile
 func (m Model) startAddProfile() (tea.Model, tea.Cmd) {
 	m.inputMode = InputProfilePath
+	m.editIndex = -1
+	m.newProfile = config.Profile{}
 	m.textInput.SetValue("")
 	m.textInput.Placeholder = "Enter path to .ovpn file"
 	m.textInput.Focus()
@@ -380,59 +896,227 @@ func (m Model) startAddProfile() (tea.Model, tea.Cmd) {
 	return m, textinput.Blink
 }
 
+// startEditProfile enters input mode for editing the selected profile,
+// starting with its current path
+func (m Model) startEditProfile() (tea.Model, tea.Cmd) {
+	index, ok := m.selectedProfile()
+	if !ok {
+		return m, nil
+	}
+
+	m.inputMode = InputProfilePath
+	m.editIndex = index
+	m.newProfile = m.config.Profiles[index]
+	m.textInput.SetValue(CompactPath(m.newProfile.Path))
+	m.textInput.CursorEnd()
+	m.textInput.Placeholder = "Enter path to .ovpn file"
+	m.textInput.Focus()
+	m.completer.Update(m.textInput.Value())
+	m.clearMessages()
+	return m, textinput.Blink
+}
+
+// startEditField enters input mode for the folder or tags of the selected profile
+func (m Model) startEditField(mode InputMode) (tea.Model, tea.Cmd) {
+	m.clearMessages()
+	index, ok := m.selectedProfile()
+	if !ok {
+		m.errorMsg = "Select a profile first"
+		return m, nil
+	}
+
+	profile := m.config.Profiles[index]
+	m.inputMode = mode
+	m.editIndex = index
+	m.newProfile = profile
+	if mode == InputProfileFolder {
+		m.textInput.SetValue(profile.Folder)
+		m.textInput.Placeholder = "Folder, e.g. Clients/Acme (empty for top level)"
+	} else {
+		m.textInput.SetValue(strings.Join(profile.Tags, ", "))
+		m.textInput.Placeholder = "Comma separated tags"
+	}
+	m.textInput.CursorEnd()
+	m.textInput.Focus()
+	return m, textinput.Blink
+}
+
+// saveField stores the folder or tags entered for the profile being edited
+func (m Model) saveField(value string) (tea.Model, tea.Cmd) {
+	var err error
+	if m.inputMode == InputProfileFolder {
+		err = m.config.SetFolder(m.editIndex, value)
+	} else {
+		err = m.config.SetTags(m.editIndex, config.ParseTags(value))
+	}
+	if err == nil {
+		err = m.config.Save()
+	}
+
+	if err != nil {
+		m.errorMsg = fmt.Sprintf("Failed to save config: %v", err)
+	} else {
+		m.statusMsg = fmt.Sprintf("Updated profile: %s", m.newProfile.Name)
+	}
+
+	index := m.editIndex
+	m.inputMode = InputNone
+	m.newProfile = config.Profile{}
+	m.editIndex = -1
+	m.clampCursors()
+	m.selectProfile(index)
+	return m, nil
+}
+
+// moveProfile moves the selected profile up or down in the list
+func (m *Model) moveProfile(delta int) {
+	m.clearMessages()
+	if m.filterActive() {
+		m.errorMsg = "Clear the filter to reorder profiles"
+		return
+	}
+
+	index, ok := m.selectedProfile()
+	if !ok {
+		return
+	}
+	// Reorder within the folder so the profile never jumps to another group
+	newIndex := m.config.MoveProfile(index, m.siblingIndex(index, delta)-index)
+	if newIndex == index {
+		return
+	}
+	if err := m.config.Save(); err != nil {
+		m.errorMsg = fmt.Sprintf("Failed to save config: %v", err)
+	}
+	m.validateProfiles()
+	m.selectProfile(newIndex)
+}
+
+// duplicateProfile inserts a copy of the selected profile below it
+func (m *Model) duplicateProfile() {
+	m.clearMessages()
+	index, ok := m.selectedProfile()
+	if !ok {
+		return
+	}
+
+	newIndex, err := m.config.DuplicateProfile(index)
+	if err != nil {
+		m.errorMsg = err.Error()
+		return
+	}
+	if err := m.config.Save(); err != nil {
+		m.errorMsg = fmt.Sprintf("Failed to save config: %v", err)
+	} else {
+		// Both use the same file until one is edited, so they share sessions
+		m.statusMsg = fmt.Sprintf("Duplicated profile as: %s (press %s to give it its own config file)",
+			m.config.Profiles[newIndex].Name, m.keys.Edit.Help().Key)
+	}
+	m.validateProfiles()
+	m.selectProfile(newIndex)
+}
+
 // handleEnter handles the enter key based on current view
 func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 	m.clearMessages()
 
 	if m.currentView == ViewProfiles {
-		if len(m.config.Profiles) == 0 {
+		if row, ok := m.selectedRow(); ok && row.isFolder() {
+			m.toggleFolder(row.folder)
 			return m, nil
 		}
-		if !m.profileValid[m.profileCursor] {
-			m.errorMsg = "Config file not found"
+
+		index, ok := m.selectedProfile()
+		if !ok {
 			return m, nil
 		}
-		profile := m.config.Profiles[m.profileCursor]
-
-		// Check if already connected
-		if m.isProfileConnected(profile.Path) {
-			m.errorMsg = fmt.Sprintf("'%s' is already connected", profile.Name)
+		if !m.profileValid[index] {
+			m.errorMsg = "Config file not found"
 			return m, nil
 		}
-
-		m.statusMsg = fmt.Sprintf("Connecting to %s...", profile.Name)
-		m.loading = true
-		m.loadingMsg = "Connecting..."
-		return m, tea.Batch(m.spinner.Tick, m.connect(profile.Path))
+		return m.connectProfile(index)
 	}
 
 	if m.currentView == ViewSessions {
-		if len(m.sessions) > 0 {
+		if index, ok := m.selectedSession(); ok {
 			m.loading = true
 			m.loadingMsg = "Fetching stats..."
-			return m, tea.Batch(m.spinner.Tick, m.fetchStats(m.sessions[m.sessionCursor].Path))
+			return m, tea.Batch(m.spinner.Tick, m.fetchStats(m.sessions[index].Path))
 		}
 	}
 
 	return m, nil
 }
 
-// isProfileConnected checks if a profile is already connected
-func (m Model) isProfileConnected(profilePath string) bool {
-	// Extract filename without extension from profile path
-	profileName := profilePath
-	if lastSlash := strings.LastIndex(profilePath, "/"); lastSlash != -1 {
-		profileName = profilePath[lastSlash+1:]
+// connectProfile starts a session for a profile unless it is already connected
+func (m Model) connectProfile(index int) (tea.Model, tea.Cmd) {
+	profile := m.config.Profiles[index]
+	if m.isProfileConnected(profile.Path) {
+		m.errorMsg = fmt.Sprintf("'%s' is already connected", profile.Name)
+		return m, nil
 	}
-	profileName = strings.TrimSuffix(profileName, ".ovpn")
 
-	// Check against active sessions
-	for _, session := range m.sessions {
-		if session.ConfigName == profileName {
-			return true
-		}
+	m.statusMsg = fmt.Sprintf("Checking routes and DNS of %s...", profile.Name)
+	m.loading = true
+	m.loadingMsg = "Checking conflicts..."
+	return m, tea.Batch(m.spinner.Tick, m.checkConflicts(profile))
+}
+
+// startConnect connects a profile once it was checked for conflicts
+func (m Model) startConnect(index int) (tea.Model, tea.Cmd) {
+	profile := m.config.Profiles[index]
+	m.statusMsg = fmt.Sprintf("Connecting to %s...", profile.Name)
+	m.loading = true
+	m.loadingMsg = "Connecting..."
+	return m, tea.Batch(m.spinner.Tick, m.connect(profile))
+}
+
+// confirmInline asks before converting the selected profile to a self-contained file
+func (m *Model) confirmInline() {
+	m.clearMessages()
+	index, ok := m.selectedProfile()
+	if !ok {
+		m.errorMsg = "Select a profile first"
+		return
+	}
+	if !m.profileValid[index] {
+		m.errorMsg = "Config file not found"
+		return
+	}
+	m.confirmMode = ConfirmInlineProfile
+	m.confirmTarget = m.config.Profiles[index].Name
+	m.confirmIndex = index
+}
+
+// inlineProfile rewrites a profile with inline certificates and keys into the
+// managed profiles directory and points the profile at the new file
+func (m *Model) inlineProfile(index int) {
+	profile := m.config.Profiles[index]
+	dst, err := config.ManagedProfilePath(profile.Name)
+	if err != nil {
+		m.errorMsg = err.Error()
+		return
 	}
-	return false
+	inlined, err := ovpn.InlineFile(profile.Path, dst)
+	if err != nil {
+		m.errorMsg = fmt.Sprintf("Failed to inline %s: %v", profile.Name, err)
+		return
+	}
+	if err := m.config.UpdateProfile(index, profile.Name, dst); err != nil {
+		m.errorMsg = err.Error()
+		return
+	}
+	if err := m.config.Save(); err != nil {
+		m.errorMsg = fmt.Sprintf("Failed to save config: %v", err)
+		return
+	}
+	m.statusMsg = fmt.Sprintf("Inlined %s into %s", strings.Join(inlined, ", "), CompactPath(dst))
+	m.validateProfiles()
+}
+
+// isProfileConnected checks if a profile is already connected
+func (m Model) isProfileConnected(profilePath string) bool {
+	return len(m.profileSessions(profilePath)) > 0
 }
 
 // handleDelete handles deletion based on current view
@@ -440,52 +1124,74 @@ func (m Model) handleDelete() (tea.Model, tea.Cmd) {
 	m.clearMessages()
 
 	if m.currentView == ViewProfiles {
-		if len(m.config.Profiles) > 0 {
+		if row, ok := m.selectedRow(); ok && row.isFolder() {
+			m.errorMsg = "Folders are removed when their last profile is moved or deleted"
+			return m, nil
+		}
+		if index, ok := m.selectedProfile(); ok {
 			// Enter confirm mode instead of immediate deletion
 			m.confirmMode = ConfirmDeleteProfile
-			m.confirmTarget = m.config.Profiles[m.profileCursor].Name
-			m.confirmIndex = m.profileCursor
+			m.confirmTarget = m.config.Profiles[index].Name
+			m.confirmIndex = index
 		}
 		return m, nil
 	}
 
 	if m.currentView == ViewSessions {
-		if len(m.sessions) > 0 {
-			session := m.sessions[m.sessionCursor]
+		if index, ok := m.selectedSession(); ok {
+			session := m.sessions[index]
 			m.statusMsg = "Disconnecting..."
-			return m, m.disconnect(session.Path)
+			return m, m.disconnect(session)
 		}
 	}
 
 	return m, nil
 }
 
-// moveCursorUp moves the cursor up in the current list
-func (m *Model) moveCursorUp() {
-	if m.currentView == ViewProfiles {
-		if m.profileCursor > 0 {
-			m.profileCursor--
-		}
-	} else {
-		if m.sessionCursor > 0 {
-			m.sessionCursor--
+// profileFile is what the detail pane shows of a profile's config file, read
+// once instead of on every render
+type profileFile struct {
+	cfg     *ovpn.Config
+	err     error
+	missing map[string]bool // Referenced files that do not exist, by path
+}
+
+// loadProfileFile parses a profile's config file and checks the files it references
+func loadProfileFile(path string) profileFile {
+	cfg, err := ovpn.ParseFile(path)
+	if err != nil {
+		return profileFile{err: err}
+	}
+	f := profileFile{cfg: cfg, missing: make(map[string]bool)}
+	for _, ref := range cfg.ExternalFiles() {
+		if _, err := os.Stat(ref.Path); err != nil {
+			f.missing[ref.Path] = true
 		}
 	}
-	m.selectedStats = nil
+	return f
 }
 
-// moveCursorDown moves the cursor down in the current list
-func (m *Model) moveCursorDown() {
-	if m.currentView == ViewProfiles {
-		if m.profileCursor < len(m.config.Profiles)-1 {
-			m.profileCursor++
+// validateProfiles checks that profile files exist, parses and lints their
+// contents and reads their certificates. Certificates come from the cache
+// unless the profile's files changed.
+func (m *Model) validateProfiles() {
+	m.profileValid = m.config.ValidateProfiles()
+	m.profileLint = make(map[int][]ovpn.Finding)
+	m.profileCerts = make(map[int]certInfo)
+	m.profileFiles = make(map[int]profileFile)
+	for i, p := range m.config.Profiles {
+		if !m.profileValid[i] {
+			continue
 		}
-	} else {
-		if m.sessionCursor < len(m.sessions)-1 {
-			m.sessionCursor++
+		f := loadProfileFile(p.Path)
+		m.profileFiles[i] = f
+		if f.cfg == nil {
+			m.profileCerts[i] = certInfo{err: f.err}
+			continue
 		}
+		m.profileLint[i] = ovpn.Lint(f.cfg)
+		m.profileCerts[i] = m.certCache.load(p.Path, f.cfg)
 	}
-	m.selectedStats = nil
 }
 
 // clearMessages clears status and error messages
@@ -510,17 +1216,104 @@ func (m Model) fetchStats(path string) tea.Cmd {
 	}
 }
 
-func (m Model) connect(configPath string) tea.Cmd {
+func (m Model) connect(profile config.Profile) tea.Cmd {
+	return func() tea.Msg {
+		return m.connectWithDependencies(profile)
+	}
+}
+
+func (m Model) disconnect(session openvpn.Session) tea.Cmd {
+	return func() tea.Msg {
+		return m.disconnectWithHooks(session)
+	}
+}
+
+func (m Model) connectAll(profiles []config.Profile) tea.Cmd {
 	return func() tea.Msg {
-		err := m.client.Connect(configPath)
-		return connectMsg{err: err}
+		msg := groupMsg{action: "connected"}
+		for _, p := range profiles {
+			res := m.connectWithDependencies(p)
+			if res.hookErr != nil {
+				msg.hookErrs = append(msg.hookErrs, fmt.Errorf("%s: %w", p.Name, res.hookErr))
+			}
+			if res.err != nil {
+				msg.errs = append(msg.errs, fmt.Errorf("%s: %w", p.Name, res.err))
+			} else {
+				msg.done++
+			}
+		}
+		return msg
 	}
 }
 
-func (m Model) disconnect(sessionPath string) tea.Cmd {
+func (m Model) disconnectAll(sessions []openvpn.Session) tea.Cmd {
 	return func() tea.Msg {
-		err := m.client.Disconnect(sessionPath)
-		return disconnectMsg{err: err}
+		msg := groupMsg{action: "disconnected"}
+		for _, s := range sessions {
+			res := m.disconnectWithHooks(s)
+			if res.hookErr != nil {
+				msg.hookErrs = append(msg.hookErrs, res.hookErr)
+			}
+			if res.err != nil {
+				msg.errs = append(msg.errs, res.err)
+			} else {
+				msg.done++
+			}
+		}
+		return msg
+	}
+}
+
+// ensureProfiles disconnects and then connects profiles, skipping those
+// already in the wanted state. Nothing is reported when no profile had to
+// change. Runs are serialized so that two of them never start the same
+// profile twice.
+func (m Model) ensureProfiles(action string, connect, disconnect []config.Profile) tea.Cmd {
+	return func() tea.Msg {
+		m.ensureMu.Lock()
+		defer m.ensureMu.Unlock()
+
+		msg := groupMsg{action: action}
+		sessions, err := m.client.ListSessions()
+		if err != nil {
+			msg.errs = append(msg.errs, err)
+			return msg
+		}
+		// Hooks look up profiles in the current sessions
+		m.sessions = sessions
+
+		for _, p := range disconnect {
+			for _, s := range sessionsFor(sessions, p.Path) {
+				res := m.disconnectWithHooks(s)
+				if res.hookErr != nil {
+					msg.hookErrs = append(msg.hookErrs, res.hookErr)
+				}
+				if res.err != nil {
+					msg.errs = append(msg.errs, fmt.Errorf("%s: %w", p.Name, res.err))
+				} else {
+					msg.done++
+				}
+			}
+		}
+		for _, p := range connect {
+			if len(sessionsFor(sessions, p.Path)) > 0 {
+				continue
+			}
+			res := m.connectWithDependencies(p)
+			if res.hookErr != nil {
+				msg.hookErrs = append(msg.hookErrs, fmt.Errorf("%s: %w", p.Name, res.hookErr))
+			}
+			if res.err != nil {
+				msg.errs = append(msg.errs, fmt.Errorf("%s: %w", p.Name, res.err))
+			} else {
+				msg.done++
+			}
+		}
+
+		if msg.done == 0 && len(msg.errs) == 0 {
+			return nil
+		}
+		return msg
 	}
 }
 
@@ -548,11 +1341,49 @@ func (m Model) View() string {
 		return b.String()
 	}
 
-	// Main content based on current view
+	// Import preview
+	if m.importing != nil {
+		b.WriteString(m.renderImport())
+		return b.String()
+	}
+
+	// Server picker
+	if m.servers != nil {
+		b.WriteString(m.renderServers())
+		return b.String()
+	}
+
+	// Help overlay
+	if m.showHelp {
+		b.WriteString(m.renderFullHelp())
+		return b.String()
+	}
+
+	// Certificate overlay
+	if m.showCerts {
+		b.WriteString(m.renderCertDetail())
+		return b.String()
+	}
+
+	// Hook log overlay
+	if m.showHooks {
+		b.WriteString(m.renderHookLog())
+		return b.String()
+	}
+
+	// Main content based on current view, with a detail pane on wide terminals
+	l := computeLayout(m.width)
+	var list string
 	if m.currentView == ViewProfiles {
-		b.WriteString(m.renderProfiles())
+		list = m.renderProfiles(l)
+	} else {
+		list = m.renderSessions(l)
+	}
+	if l.split {
+		b.WriteString(m.joinPanes(l, list, m.renderDetail(l)))
+		b.WriteString("\n")
 	} else {
-		b.WriteString(m.renderSessions())
+		b.WriteString(list)
 	}
 
 	// Loading indicator
@@ -595,18 +1426,47 @@ func (m Model) renderTabs() string {
 func (m Model) renderInputMode() string {
 	var b strings.Builder
 
-	title := "Add Profile - Enter Path"
-	if m.inputMode == InputProfileName {
-		title = "Add Profile - Enter Name"
+	action := "Add Profile"
+	if m.editIndex >= 0 {
+		action = "Edit Profile"
+	}
+	title := action + " - Enter Path"
+	switch m.inputMode {
+	case InputProfileName:
+		title = action + " - Enter Name"
+	case InputProfileFolder:
+		title = fmt.Sprintf("Move '%s' to Folder", m.newProfile.Name)
+	case InputProfileTags:
+		title = fmt.Sprintf("Tags for '%s'", m.newProfile.Name)
+	case InputImportPath:
+		title = "Import Profiles - Enter Directory or Archive"
+	case InputExportPath:
+		title = fmt.Sprintf("Export %d Profile(s) - Enter Bundle Path", len(m.exportIndices))
+	case InputCredUsername:
+		title = fmt.Sprintf("Credentials for '%s' - Username", m.newProfile.Name)
+	case InputCredPassword:
+		title = fmt.Sprintf("Credentials for '%s' - Password", m.newProfile.Name)
+	case InputTOTPSecret:
+		title = fmt.Sprintf("TOTP Secret for '%s'", m.newProfile.Name)
+	case InputPassphrase:
+		title = "Unlock Credential File"
+		if !m.credentialFileExists() {
+			title = "Choose a Passphrase for the Credential File"
+		}
+	case InputPassphraseConfirm:
+		title = "Repeat the Passphrase for the Credential File"
 	}
 
 	b.WriteString(m.styles.Subtitle.Render(title))
 	b.WriteString("\n")
 	b.WriteString(m.textInput.View())
 	b.WriteString("\n")
+	if m.inputMode == InputPassphrase || m.inputMode == InputPassphraseConfirm {
+		b.WriteString("\n" + m.renderPassphraseHint())
+	}
 
 	// Show path suggestions
-	if m.inputMode == InputProfilePath && m.completer.HasSuggestions() {
+	if m.completesPath() && m.completer.HasSuggestions() {
 		b.WriteString("\n")
 		suggestions := m.completer.Suggestions()
 		selectedIdx := m.completer.SelectedIndex()
@@ -622,8 +1482,21 @@ func (m Model) renderInputMode() string {
 		}
 	}
 
+	if m.errorMsg != "" {
+		b.WriteString("\n")
+		b.WriteString(m.styles.Error.Render(m.errorMsg))
+		b.WriteString("\n")
+	}
+
+	if m.inputMode == InputExportPath {
+		b.WriteString("\n")
+		b.WriteString(m.renderExportOptions())
+	}
+
 	b.WriteString("\n")
-	if m.inputMode == InputProfilePath {
+	if m.inputMode == InputExportPath {
+		b.WriteString(m.styles.Help.Render("ctrl+r: toggle key stripping • enter: export • esc: cancel"))
+	} else if m.completesPath() {
 		b.WriteString(m.styles.Help.Render("tab: complete • enter: confirm • esc: cancel"))
 	} else {
 		b.WriteString(m.styles.Help.Render("enter: confirm • esc: cancel"))
@@ -635,55 +1508,84 @@ func (m Model) renderInputMode() string {
 func (m Model) renderConfirmMode() string {
 	var b strings.Builder
 
-	b.WriteString(m.styles.Subtitle.Render("Confirm Delete"))
-	b.WriteString("\n\n")
-	b.WriteString(fmt.Sprintf("Delete profile '%s'?\n\n", m.confirmTarget))
-	b.WriteString(m.styles.Help.Render("y/enter: confirm • n/esc: cancel"))
+	switch m.confirmMode {
+	case ConfirmForgetCredentials:
+		b.WriteString(m.styles.Subtitle.Render("Forget Credentials"))
+		b.WriteString("\n\n")
+		b.WriteString(fmt.Sprintf("Remove the saved username and password of '%s'?\n\n", m.confirmTarget))
+	case ConfirmInlineProfile:
+		b.WriteString(m.styles.Subtitle.Render("Make Self-Contained"))
+		b.WriteString("\n\n")
+		b.WriteString(fmt.Sprintf("Inline the certificates and keys of '%s'?\n", m.confirmTarget))
+		if dst, err := config.ManagedProfilePath(m.confirmTarget); err == nil {
+			b.WriteString(m.styles.Muted.Render("Saved as "+CompactPath(dst)) + "\n")
+		}
+		b.WriteString("\n")
+	case ConfirmDisconnectDependencies:
+		b.WriteString(m.styles.Subtitle.Render("Disconnect Dependencies"))
+		b.WriteString("\n\n")
+		b.WriteString(fmt.Sprintf("'%s' is disconnected. No other session needs %s.\n", m.confirmTarget, profileNames(m.confirmDeps)))
+		b.WriteString("Disconnect them too?\n\n")
+	case ConfirmConnectConflicts:
+		b.WriteString(m.styles.Subtitle.Render("Route and DNS Conflicts"))
+		b.WriteString("\n\n")
+		b.WriteString(fmt.Sprintf("Connecting '%s' would conflict:\n", m.confirmTarget))
+		b.WriteString(m.styles.Paused.Render(renderConflictList(m.confirmConflicts, 8)))
+		b.WriteString("\nConnect anyway?\n\n")
+	default:
+		b.WriteString(m.styles.Subtitle.Render("Confirm Delete"))
+		b.WriteString("\n\n")
+		b.WriteString(fmt.Sprintf("Delete profile '%s'?\n\n", m.confirmTarget))
+	}
+	b.WriteString(m.help.ShortHelpView([]key.Binding{m.keys.Confirm, m.keys.Cancel}))
 
 	return m.styles.Box.Render(b.String())
 }
 
-func (m Model) renderProfiles() string {
+func (m Model) renderProfiles(l layout) string {
 	var b strings.Builder
 
 	if len(m.config.Profiles) == 0 {
 		b.WriteString(m.styles.Subtitle.Render("No profiles configured"))
 		b.WriteString("\n")
-		b.WriteString("Press 'a' to add a profile")
+		b.WriteString(hint(m.keys.Add, "add a profile"))
 		return b.String()
 	}
 
-	for i, profile := range m.config.Profiles {
+	rows := m.profileRows()
+	total := len(rows)
+	shown := 0
+	for _, row := range rows {
+		if !row.isFolder() {
+			shown++
+		}
+	}
+	b.WriteString(m.renderFilter(shown, len(m.config.Profiles)))
+
+	start, end := visibleRange(m.profileOffset, total, m.listHeight())
+	for i := start; i < end; i++ {
+		row := rows[i]
 		cursor := "  "
 		if i == m.profileCursor {
 			cursor = "> "
 		}
+		indent := strings.Repeat("  ", row.depth)
 
-		isConnected := m.isProfileConnected(profile.Path)
-		line := fmt.Sprintf("%s%s", cursor, profile.Name)
-
-		if i == m.profileCursor {
-			b.WriteString(m.styles.Selected.Render(line))
-			if isConnected {
-				b.WriteString(" ")
-				b.WriteString(m.styles.Connected.Render("[connected]"))
-			}
-		} else if !m.profileValid[i] {
-			b.WriteString(m.styles.Invalid.Render(line + " (file not found)"))
-		} else if isConnected {
-			b.WriteString(m.styles.Normal.Render(line))
-			b.WriteString(" ")
-			b.WriteString(m.styles.Connected.Render("[connected]"))
+		var line string
+		if row.isFolder() {
+			line = m.renderFolderRow(row, cursor, i == m.profileCursor)
 		} else {
-			b.WriteString(m.styles.Normal.Render(line))
+			line = m.renderProfileRow(row, cursor, i == m.profileCursor)
 		}
+		b.WriteString(truncate(indent+line, l.listWidth))
 		b.WriteString("\n")
 	}
+	b.WriteString(m.renderPosition(m.profileCursor, m.profileOffset, total))
 
 	return b.String()
 }
 
-func (m Model) renderSessions() string {
+func (m Model) renderSessions(l layout) string {
 	var b strings.Builder
 
 	if len(m.sessions) == 0 {
@@ -691,7 +1593,14 @@ func (m Model) renderSessions() string {
 		return b.String()
 	}
 
-	for i, session := range m.sessions {
+	matches := m.sessionMatches()
+	total := len(matches)
+	b.WriteString(m.renderFilter(total, len(m.sessions)))
+
+	start, end := visibleRange(m.sessionOffset, total, m.listHeight())
+	for i := start; i < end; i++ {
+		match := matches[i]
+		session := m.sessions[match.index]
 		cursor := "  "
 		if i == m.sessionCursor {
 			cursor = "> "
@@ -708,25 +1617,128 @@ func (m Model) renderSessions() string {
 			statusStyled = m.styles.Disconnected.Render(status)
 		}
 
-		line := fmt.Sprintf("%s%s [%s]", cursor, session.ConfigName, statusStyled)
-
+		var row string
 		if i == m.sessionCursor {
-			b.WriteString(m.styles.Selected.Render(fmt.Sprintf("%s%s", cursor, session.ConfigName)))
-			b.WriteString(fmt.Sprintf(" [%s]", statusStyled))
+			row = m.renderItem(cursor, session.ConfigName, "", match.positions, m.styles.Selected)
 		} else {
-			b.WriteString(m.styles.Normal.Render(line))
+			row = m.renderItem(cursor, session.ConfigName, "", match.positions, m.styles.Normal)
 		}
+		row += fmt.Sprintf(" [%s]", statusStyled)
+		if badge := m.healthBadge(session); badge != "" {
+			row += " " + badge
+		}
+		if badge := m.conflictBadge(session); badge != "" {
+			row += " " + badge
+		}
+		b.WriteString(truncate(row, l.listWidth))
 		b.WriteString("\n")
 	}
+	b.WriteString(m.renderPosition(m.sessionCursor, m.sessionOffset, total))
 
-	// Show stats if available
-	if m.selectedStats != nil && m.sessionCursor < len(m.sessions) {
+	// Show stats below the list unless the detail pane displays them
+	if !l.split && m.selectedStats != nil {
 		b.WriteString(m.renderStats())
 	}
 
 	return b.String()
 }
 
+// renderProfileRow renders a profile with its status, tags and filter matches
+func (m Model) renderProfileRow(row profileRow, cursor string, selected bool) string {
+	profile := m.config.Profiles[row.index]
+	isConnected := m.isProfileConnected(profile.Path)
+
+	var line string
+	if selected {
+		line = m.renderItem(cursor, profile.Name, "", row.positions, m.styles.Selected)
+	} else if !m.profileValid[row.index] {
+		line = m.renderItem(cursor, profile.Name, " (file not found)", row.positions, m.styles.Invalid)
+	} else {
+		line = m.renderItem(cursor, profile.Name, "", row.positions, m.styles.Normal)
+	}
+	if isConnected {
+		line += " " + m.styles.Connected.Render("[connected]")
+	}
+	if badge := m.lintBadge(row.index); badge != "" {
+		line += " " + badge
+	}
+	if badge := m.certBadge(row.index); badge != "" {
+		line += " " + badge
+	}
+	if len(profile.Tags) > 0 {
+		line += " " + m.styles.Tag.Render("#"+strings.Join(profile.Tags, " #"))
+	}
+
+	// Matches in a flat, filtered list lose their tree context, so show the folder
+	if row.depth == 0 && profile.Folder != "" && m.filters[ViewProfiles] != "" {
+		line += " " + m.styles.Muted.Render(profile.Folder+"/")
+	}
+	return line
+}
+
+// lintBadge summarizes the lint findings of a profile, e.g. [2 errors]
+func (m Model) lintBadge(index int) string {
+	errors, warnings := ovpn.Count(m.profileLint[index])
+	switch {
+	case errors > 0:
+		return m.styles.Error.Render(fmt.Sprintf("[%d %s]", errors, plural(errors, "error")))
+	case warnings > 0:
+		return m.styles.Paused.Render(fmt.Sprintf("[%d %s]", warnings, plural(warnings, "warning")))
+	}
+	return ""
+}
+
+// plural appends an s to word unless n is one
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+// renderFolderRow renders a folder header with its collapse state and counts
+func (m Model) renderFolderRow(row profileRow, cursor string, selected bool) string {
+	icon := "▾ "
+	if m.collapsed[row.folder] {
+		icon = "▸ "
+	}
+
+	count, connected := 0, 0
+	for _, p := range m.config.Profiles {
+		if p.InFolder(row.folder) {
+			count++
+			if m.isProfileConnected(p.Path) {
+				connected++
+			}
+		}
+	}
+
+	style := m.styles.Folder
+	if selected {
+		style = m.styles.Selected
+	}
+	line := m.renderItem(cursor, icon+folderName(row.folder), "", nil, style)
+	line += " " + m.styles.Muted.Render(fmt.Sprintf("(%d)", count))
+	if connected > 0 {
+		line += " " + m.styles.Connected.Render(fmt.Sprintf("[%d connected]", connected))
+	}
+	return line
+}
+
+// renderItem renders a list item in the given style with filter matches highlighted
+func (m Model) renderItem(cursor, name, suffix string, positions []int, style lipgloss.Style) string {
+	base := style.UnsetPadding()
+	match := m.styles.Match.Inherit(base)
+	if style.GetBackground() != (lipgloss.NoColor{}) {
+		// Keep the selection background and mark matches by underlining
+		match = base.Underline(true)
+	}
+
+	left := strings.Repeat(" ", style.GetPaddingLeft())
+	right := strings.Repeat(" ", style.GetPaddingRight())
+	return base.Render(left+cursor) + highlight(name, positions, base, match) + base.Render(suffix+right)
+}
+
 func (m Model) renderStats() string {
 	stats := m.selectedStats
 	var sb strings.Builder
@@ -747,11 +1759,22 @@ func (m Model) renderStats() string {
 }
 
 func (m Model) renderHelp() string {
-	var help string
-	if m.currentView == ViewProfiles {
-		help = "tab: switch view • j/k: navigate • enter: connect • a: add • d: delete • r: refresh • q: quit"
-	} else {
-		help = "tab: switch view • j/k: navigate • enter/s: stats • d: disconnect • r: refresh • q: quit"
-	}
-	return m.styles.Help.Render(help)
+	return m.styles.Help.Render(m.help.ShortHelpView(m.helpKeys().ShortHelp()))
+}
+
+func (m Model) renderFullHelp() string {
+	var b strings.Builder
+
+	b.WriteString(m.styles.Subtitle.Render("Keybindings"))
+	b.WriteString("\n")
+	b.WriteString(m.help.FullHelpView(m.helpKeys().FullHelp()))
+	b.WriteString("\n\n")
+	b.WriteString(m.styles.Help.Render("press any key to close"))
+
+	return m.styles.Box.Render(b.String())
+}
+
+// helpKeys returns the key bindings with help text for the current view
+func (m Model) helpKeys() viewKeyMap {
+	return viewKeyMap{keys: m.keys, view: m.currentView}
 }
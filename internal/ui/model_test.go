@@ -0,0 +1,22 @@
+package ui
+
+import "testing"
+
+func TestSummarizeProblems(t *testing.T) {
+	tests := []struct {
+		problems []string
+		want     string
+	}{
+		{nil, ""},
+		{[]string{"schedule of Acme: invalid start"}, "schedule of Acme: invalid start"},
+		{
+			[]string{"schedule of Acme: invalid start", "kill switch of Lab: invalid allow", "Certificates expiring: Acme (3 days)"},
+			"schedule of Acme: invalid start (and 2 more)",
+		},
+	}
+	for _, tt := range tests {
+		if got := summarizeProblems(tt.problems); got != tt.want {
+			t.Errorf("summarizeProblems(%q) = %q, want %q", tt.problems, got, tt.want)
+		}
+	}
+}
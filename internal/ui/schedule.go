@@ -0,0 +1,167 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"openvpn3-tui/internal/config"
+	"openvpn3-tui/internal/openvpn"
+	"openvpn3-tui/internal/schedule"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// scheduleTickMsg checks schedules and idle sessions once a minute
+type scheduleTickMsg time.Time
+
+// scheduleTick schedules the next check at the start of the next minute
+func scheduleTick() tea.Cmd {
+	return tea.Every(time.Minute, func(t time.Time) tea.Msg {
+		return scheduleTickMsg(t)
+	})
+}
+
+// idleCounter is the traffic of a session when it last changed
+type idleCounter struct {
+	bytes int64
+	since time.Time
+}
+
+// idleMsg carries the traffic counters of sessions with an idle timeout
+type idleMsg struct {
+	now      time.Time
+	sessions []openvpn.Session
+	bytes    map[string]int64 // By session path
+	limits   map[string]time.Duration
+}
+
+// hasAutomation reports whether any profile has a schedule or idle timeout
+func (m Model) hasAutomation() bool {
+	for _, p := range m.config.Profiles {
+		if len(p.Schedule) > 0 || p.IdleMinutes > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// handleScheduleTick connects profiles whose window opened and disconnects
+// those whose window closed. A profile inside its window when the TUI starts
+// is connected; one outside it is left alone.
+func (m Model) handleScheduleTick(now time.Time) (tea.Model, tea.Cmd) {
+	cmds := []tea.Cmd{scheduleTick(), m.checkIdle(now)}
+
+	var connect, disconnect []config.Profile
+	for _, p := range m.config.Profiles {
+		if len(p.Schedule) == 0 {
+			continue
+		}
+		active := schedule.Active(p.Schedule, now)
+		was, seen := m.scheduleActive[p.Name]
+		m.scheduleActive[p.Name] = active
+		switch {
+		case active && (!seen || !was):
+			connect = append(connect, p)
+		case !active && seen && was:
+			disconnect = append(disconnect, p)
+		}
+	}
+	if len(connect) > 0 || len(disconnect) > 0 {
+		cmds = append(cmds, m.ensureProfiles("schedule changed", connect, disconnect))
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// checkIdle reads the traffic counters of sessions whose profile has an
+// idle timeout
+func (m Model) checkIdle(now time.Time) tea.Cmd {
+	var profiles []config.Profile
+	for _, p := range m.config.Profiles {
+		if p.IdleMinutes > 0 {
+			profiles = append(profiles, p)
+		}
+	}
+	if len(profiles) == 0 {
+		return nil
+	}
+
+	return func() tea.Msg {
+		all, err := m.client.ListSessions()
+		if err != nil {
+			return nil
+		}
+		msg := idleMsg{now: now, bytes: make(map[string]int64), limits: make(map[string]time.Duration)}
+		for _, p := range profiles {
+			for _, s := range sessionsFor(all, p.Path) {
+				stats, err := m.client.GetSessionStats(s.Path)
+				if err != nil {
+					continue
+				}
+				msg.sessions = append(msg.sessions, s)
+				msg.bytes[s.Path] = stats.RawBytesIn + stats.RawBytesOut
+				msg.limits[s.Path] = time.Duration(p.IdleMinutes) * time.Minute
+			}
+		}
+		return msg
+	}
+}
+
+// handleIdle disconnects sessions whose traffic has not changed for their
+// idle timeout. Counting starts when a session is first seen.
+func (m Model) handleIdle(msg idleMsg) (tea.Model, tea.Cmd) {
+	var idle []openvpn.Session
+	var names []string
+	seen := make(map[string]bool)
+	for _, s := range msg.sessions {
+		seen[s.Path] = true
+		bytes := msg.bytes[s.Path]
+		last, ok := m.idle[s.Path]
+		if !ok || last.bytes != bytes {
+			m.idle[s.Path] = idleCounter{bytes: bytes, since: msg.now}
+			continue
+		}
+		if msg.now.Sub(last.since) >= msg.limits[s.Path] {
+			idle = append(idle, s)
+			names = append(names, s.ConfigName)
+			delete(m.idle, s.Path)
+		}
+	}
+	// Forget sessions that are gone
+	for path := range m.idle {
+		if !seen[path] {
+			delete(m.idle, path)
+		}
+	}
+
+	if len(idle) == 0 {
+		return m, nil
+	}
+	m.statusMsg = fmt.Sprintf("Disconnecting idle %s", strings.Join(names, ", "))
+	return m, m.disconnectAll(idle)
+}
+
+// renderScheduleSummary describes the schedule and idle timeout of a profile
+func (m Model) renderScheduleSummary(profile config.Profile) string {
+	var b strings.Builder
+	if len(profile.Schedule) > 0 {
+		var windows []string
+		for _, w := range profile.Schedule {
+			windows = append(windows, schedule.Describe(w))
+		}
+		value := strings.Join(windows, ", ")
+		now := time.Now()
+		if next, ok := schedule.Next(profile.Schedule, now); ok {
+			change := "opens"
+			if schedule.Active(profile.Schedule, now) {
+				change = "closes"
+			}
+			value += m.styles.Muted.Render(fmt.Sprintf(" • %s %s", change, next.Format("Mon 15:04")))
+		}
+		b.WriteString(detailRow("Schedule", value))
+	}
+	if profile.IdleMinutes > 0 {
+		b.WriteString(detailRow("Idle", fmt.Sprintf("disconnect after %d min without traffic", profile.IdleMinutes)))
+	}
+	return b.String()
+}
@@ -0,0 +1,220 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"openvpn3-tui/internal/config"
+	"openvpn3-tui/internal/health"
+	"openvpn3-tui/internal/openvpn"
+	"openvpn3-tui/internal/ovpn"
+	"openvpn3-tui/internal/probe"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// probeTimeout bounds how long a server may take to answer a probe
+const probeTimeout = 3 * time.Second
+
+// serverState holds the server picker of a profile
+type serverState struct {
+	profile string
+	results []probe.Result // Unmeasured while probing
+	probing bool
+	cursor  int
+	offset  int
+}
+
+// probeMsg carries the latencies of a profile's servers
+type probeMsg struct {
+	profile string
+	results []probe.Result
+}
+
+// startServers opens the server picker of a profile and probes its servers
+func (m Model) startServers(index int) (tea.Model, tea.Cmd) {
+	profile := m.config.Profiles[index]
+	if !m.profileValid[index] {
+		m.errorMsg = "Config file not found"
+		return m, nil
+	}
+	cfg, err := ovpn.ParseFile(profile.Path)
+	if err != nil {
+		m.errorMsg = err.Error()
+		return m, nil
+	}
+	remotes := cfg.Remotes()
+	if len(remotes) == 0 {
+		m.errorMsg = fmt.Sprintf("'%s' lists no remote servers", profile.Name)
+		return m, nil
+	}
+
+	servers := &serverState{profile: profile.Name, probing: true}
+	for _, r := range remotes {
+		servers.results = append(servers.results, probe.Result{Remote: r})
+	}
+	servers.cursor = chosenIndex(servers.results, profile.Server)
+	m.servers = servers
+	return m, tea.Batch(m.spinner.Tick, probeServers(profile.Name, cfg))
+}
+
+// probeServers measures the latency of every server of a profile
+func probeServers(profile string, cfg *ovpn.Config) tea.Cmd {
+	return func() tea.Msg {
+		return probeMsg{profile: profile, results: probe.Remotes(cfg, probeTimeout)}
+	}
+}
+
+// handleProbe shows the sorted servers, keeping the remembered one selected
+func (m Model) handleProbe(msg probeMsg) (tea.Model, tea.Cmd) {
+	if m.servers == nil || m.servers.profile != msg.profile {
+		return m, nil
+	}
+	m.servers.results = msg.results
+	m.servers.probing = false
+	if index, ok := m.config.FindProfile(msg.profile); ok {
+		m.servers.cursor = chosenIndex(msg.results, m.config.Profiles[index].Server)
+	}
+	m.servers.offset = scrollOffset(m.servers.cursor, 0, len(msg.results), m.importHeight())
+	return m, nil
+}
+
+// chosenIndex finds the remembered server among results, defaulting to the first
+func chosenIndex(results []probe.Result, server string) int {
+	for i, r := range results {
+		if r.Remote.String() == server {
+			return i
+		}
+	}
+	return 0
+}
+
+// handleServerMode handles key events in the server picker
+func (m Model) handleServerMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	servers := m.servers
+	index, ok := m.config.FindProfile(servers.profile)
+	if !ok {
+		m.servers = nil
+		return m, nil
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.Close):
+		m.servers = nil
+		return m, nil
+
+	case key.Matches(msg, m.keys.Select):
+		return m.connectVia(index, servers.results[servers.cursor].Remote.String())
+
+	case key.Matches(msg, m.keys.ConfigOrder):
+		// Forget the choice and let OpenVPN try the servers in order
+		return m.connectVia(index, "")
+
+	case key.Matches(msg, m.keys.Refresh):
+		if !servers.probing {
+			return m.startServers(index)
+		}
+
+	case key.Matches(msg, m.keys.Up):
+		servers.cursor = clamp(servers.cursor-1, 0, len(servers.results)-1)
+
+	case key.Matches(msg, m.keys.Down):
+		servers.cursor = clamp(servers.cursor+1, 0, len(servers.results)-1)
+
+	case key.Matches(msg, m.keys.Home):
+		servers.cursor = 0
+
+	case key.Matches(msg, m.keys.End):
+		servers.cursor = len(servers.results) - 1
+	}
+
+	servers.offset = scrollOffset(servers.cursor, servers.offset, len(servers.results), m.importHeight())
+	return m, nil
+}
+
+// connectVia remembers the server of a profile and connects it. An empty
+// server restores the order of the config.
+func (m Model) connectVia(index int, server string) (tea.Model, tea.Cmd) {
+	m.servers = nil
+	m.clearMessages()
+	if m.config.Profiles[index].Server != server {
+		m.config.Profiles[index].Server = server
+		if err := m.config.Save(); err != nil {
+			m.errorMsg = fmt.Sprintf("Failed to save config: %v", err)
+			return m, nil
+		}
+	}
+	return m.connectProfile(index)
+}
+
+// serverOverride returns the remembered server of a profile while the config
+// still lists it
+func serverOverride(profile config.Profile) (openvpn.Server, bool) {
+	if profile.Server == "" {
+		return openvpn.Server{}, false
+	}
+	cfg, err := ovpn.ParseFile(profile.Path)
+	if err != nil {
+		return openvpn.Server{}, false
+	}
+	for _, r := range cfg.Remotes() {
+		if r.String() == profile.Server {
+			proto := "udp"
+			if strings.HasPrefix(probe.Network(r.Proto), "tcp") {
+				proto = "tcp"
+			}
+			return openvpn.Server{Host: r.Host, Port: r.Port, Proto: proto}, true
+		}
+	}
+	return openvpn.Server{}, false
+}
+
+// renderServers renders the server picker
+func (m Model) renderServers() string {
+	servers := m.servers
+	var b strings.Builder
+
+	b.WriteString(m.styles.Subtitle.Render(fmt.Sprintf("Servers of %s", servers.profile)))
+	b.WriteString("\n\n")
+
+	var chosen string
+	if index, ok := m.config.FindProfile(servers.profile); ok {
+		chosen = m.config.Profiles[index].Server
+	}
+
+	start, end := visibleRange(servers.offset, len(servers.results), m.importHeight())
+	for i := start; i < end; i++ {
+		r := servers.results[i]
+		cursor := "  "
+		if i == servers.cursor {
+			cursor = "> "
+		}
+		line := cursor + r.Remote.String()
+		if i == servers.cursor {
+			line = m.styles.SuggestionSelected.Render(line)
+		}
+
+		switch {
+		case servers.probing:
+			line += " " + m.styles.Muted.Render(m.spinner.View())
+		case r.Err != nil:
+			line += " " + m.styles.Error.Render(r.Err.Error())
+		default:
+			line += " " + m.styles.Connected.Render(health.FormatLatency(r.Latency))
+		}
+		if r.Remote.String() == chosen {
+			line += " " + m.styles.Muted.Render("[last used]")
+		}
+		b.WriteString(line + "\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(m.styles.Help.Render(strings.Join([]string{
+		hint(m.keys.Select, "connect"), hint(m.keys.ConfigOrder, "connect in config order"),
+		hint(m.keys.Refresh, "probe again"), hint(m.keys.Close, "cancel"),
+	}, " • ")))
+
+	return m.styles.Box.Render(b.String())
+}
@@ -4,24 +4,32 @@ import "github.com/charmbracelet/lipgloss"
 
 // Styles holds all the application styles
 type Styles struct {
-	Title               lipgloss.Style
-	Subtitle            lipgloss.Style
-	Selected            lipgloss.Style
-	Normal              lipgloss.Style
-	Connected           lipgloss.Style
-	Disconnected        lipgloss.Style
-	Paused              lipgloss.Style
-	Box                 lipgloss.Style
-	StatsBox            lipgloss.Style
-	Help                lipgloss.Style
-	Error               lipgloss.Style
-	Success             lipgloss.Style
-	Invalid             lipgloss.Style
-	ActiveTab           lipgloss.Style
-	InactiveTab         lipgloss.Style
-	Suggestion          lipgloss.Style
-	SuggestionSelected  lipgloss.Style
-	Spinner             lipgloss.Style
+	Title              lipgloss.Style
+	Subtitle           lipgloss.Style
+	Selected           lipgloss.Style
+	Normal             lipgloss.Style
+	Connected          lipgloss.Style
+	Disconnected       lipgloss.Style
+	Paused             lipgloss.Style
+	Box                lipgloss.Style
+	StatsBox           lipgloss.Style
+	DetailBox          lipgloss.Style
+	DetailTitle        lipgloss.Style
+	Muted              lipgloss.Style
+	Help               lipgloss.Style
+	HelpKey            lipgloss.Style
+	HelpDesc           lipgloss.Style
+	Error              lipgloss.Style
+	Success            lipgloss.Style
+	Invalid            lipgloss.Style
+	ActiveTab          lipgloss.Style
+	InactiveTab        lipgloss.Style
+	Match              lipgloss.Style
+	Folder             lipgloss.Style
+	Tag                lipgloss.Style
+	Suggestion         lipgloss.Style
+	SuggestionSelected lipgloss.Style
+	Spinner            lipgloss.Style
 }
 
 // NewStyles creates styles from a theme
@@ -66,10 +74,28 @@ func NewStyles(t *Theme) *Styles {
 			Padding(1, 2).
 			MarginTop(1),
 
+		DetailBox: lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(t.Muted).
+			Padding(0, 1),
+
+		DetailTitle: lipgloss.NewStyle().
+			Bold(true).
+			Foreground(t.Accent),
+
+		Muted: lipgloss.NewStyle().
+			Foreground(t.Muted),
+
 		Help: lipgloss.NewStyle().
 			Foreground(t.Muted).
 			MarginTop(1),
 
+		HelpKey: lipgloss.NewStyle().
+			Foreground(t.Foreground),
+
+		HelpDesc: lipgloss.NewStyle().
+			Foreground(t.Muted),
+
 		Error: lipgloss.NewStyle().
 			Foreground(t.Error).
 			Bold(true),
@@ -93,6 +119,17 @@ func NewStyles(t *Theme) *Styles {
 			Foreground(t.Muted).
 			Padding(0, 2),
 
+		Match: lipgloss.NewStyle().
+			Foreground(t.Accent).
+			Bold(true),
+
+		Folder: lipgloss.NewStyle().
+			Foreground(t.Accent).
+			Padding(0, 1),
+
+		Tag: lipgloss.NewStyle().
+			Foreground(t.Warning),
+
 		Suggestion: lipgloss.NewStyle().
 			Foreground(t.Muted),
 
@@ -0,0 +1,284 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"openvpn3-tui/internal/config"
+	"openvpn3-tui/internal/openvpn"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// profileRow is a line of the Profiles view: either a folder header or a profile
+type profileRow struct {
+	folder    string // Full folder path for header rows, empty for profiles
+	depth     int    // Indentation level
+	index     int    // Index into config.Profiles, -1 for folder rows
+	positions []int  // Matched rune positions in the profile name
+}
+
+// isFolder reports whether the row is a folder header
+func (r profileRow) isFolder() bool {
+	return r.index < 0
+}
+
+// parseQuery splits a filter query into fuzzy text and #tag terms
+func parseQuery(query string) (string, []string) {
+	var text, tags []string
+	for _, field := range strings.Fields(query) {
+		if strings.HasPrefix(field, "#") {
+			if tag := strings.TrimPrefix(field, "#"); tag != "" {
+				tags = append(tags, tag)
+			}
+			continue
+		}
+		text = append(text, field)
+	}
+	return strings.Join(text, " "), tags
+}
+
+// hasTags reports whether a profile carries all the given tags
+func hasTags(p config.Profile, tags []string) bool {
+	for _, tag := range tags {
+		if !p.HasTag(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// profileRows builds the rows of the Profiles view. Without a text filter the
+// profiles are shown as a tree grouped by folder; with one, as a flat list of
+// matches ordered by score.
+func (m Model) profileRows() []profileRow {
+	text, tags := parseQuery(m.filters[ViewProfiles])
+
+	if text != "" {
+		var rows []profileRow
+		for _, match := range m.profileMatches(text, tags) {
+			rows = append(rows, profileRow{index: match.index, positions: match.positions})
+		}
+		return rows
+	}
+
+	var included []int
+	for i, p := range m.config.Profiles {
+		if hasTags(p, tags) {
+			included = append(included, i)
+		}
+	}
+	return m.buildTree(included)
+}
+
+// buildTree arranges the given profiles under their folders. Subfolders come
+// first in alphabetical order, followed by the folder's profiles in config order.
+func (m Model) buildTree(included []int) []profileRow {
+	children := make(map[string][]string)
+	profiles := make(map[string][]int)
+	seen := map[string]bool{"": true}
+
+	for _, i := range included {
+		folder := m.config.Profiles[i].Folder
+		profiles[folder] = append(profiles[folder], i)
+
+		// Register the folder and all its ancestors
+		for folder != "" && !seen[folder] {
+			seen[folder] = true
+			parent := ""
+			if slash := strings.LastIndex(folder, "/"); slash != -1 {
+				parent = folder[:slash]
+			}
+			children[parent] = append(children[parent], folder)
+			folder = parent
+		}
+	}
+
+	var rows []profileRow
+	var walk func(folder string, depth int)
+	walk = func(folder string, depth int) {
+		subfolders := children[folder]
+		sort.Slice(subfolders, func(i, j int) bool {
+			return strings.ToLower(subfolders[i]) < strings.ToLower(subfolders[j])
+		})
+		for _, sub := range subfolders {
+			rows = append(rows, profileRow{folder: sub, depth: depth, index: -1})
+			if !m.collapsed[sub] {
+				walk(sub, depth+1)
+			}
+		}
+		for _, i := range profiles[folder] {
+			rows = append(rows, profileRow{depth: depth, index: i})
+		}
+	}
+	walk("", 0)
+
+	return rows
+}
+
+// selectedRow returns the row under the cursor in the Profiles view
+func (m Model) selectedRow() (profileRow, bool) {
+	rows := m.profileRows()
+	if m.profileCursor < 0 || m.profileCursor >= len(rows) {
+		return profileRow{}, false
+	}
+	return rows[m.profileCursor], true
+}
+
+// folderName returns the last element of a folder path
+func folderName(folder string) string {
+	return folder[strings.LastIndex(folder, "/")+1:]
+}
+
+// toggleFolder collapses or expands a folder
+func (m *Model) toggleFolder(folder string) {
+	m.collapsed[folder] = !m.collapsed[folder]
+	m.clampCursors()
+}
+
+// groupTarget returns the profiles affected by a group action and a label for
+// them. An active tag filter selects every profile with those tags; otherwise
+// the folder under the cursor, or the folder of the selected profile, is used.
+func (m Model) groupTarget() ([]int, string) {
+	text, tags := parseQuery(m.filters[ViewProfiles])
+	if len(tags) > 0 {
+		var indices []int
+		for i, p := range m.config.Profiles {
+			if hasTags(p, tags) && (text == "" || m.matchesProfile(text, p)) {
+				indices = append(indices, i)
+			}
+		}
+		return indices, "#" + strings.Join(tags, " #")
+	}
+
+	row, ok := m.selectedRow()
+	if !ok {
+		return nil, ""
+	}
+	folder := row.folder
+	if !row.isFolder() {
+		folder = m.config.Profiles[row.index].Folder
+	}
+	if folder == "" {
+		return nil, ""
+	}
+
+	var indices []int
+	for i, p := range m.config.Profiles {
+		if p.InFolder(folder) {
+			indices = append(indices, i)
+		}
+	}
+	return indices, folder
+}
+
+// matchesProfile reports whether a profile matches the fuzzy text filter
+func (m Model) matchesProfile(text string, p config.Profile) bool {
+	_, _, ok := matchFields(text, p.Name, append([]string{p.Path, p.Folder}, p.Tags...)...)
+	return ok
+}
+
+// siblingIndex returns the config index of the profile delta rows away from
+// index within the same folder, so reordering stays inside the folder
+func (m Model) siblingIndex(index, delta int) int {
+	folder := m.config.Profiles[index].Folder
+	var siblings []int
+	pos := 0
+	for i, p := range m.config.Profiles {
+		if p.Folder != folder {
+			continue
+		}
+		if i == index {
+			pos = len(siblings)
+		}
+		siblings = append(siblings, i)
+	}
+	return siblings[clamp(pos+delta, 0, len(siblings)-1)]
+}
+
+// connectGroup connects every profile of the group that is not yet connected
+func (m Model) connectGroup() (tea.Model, tea.Cmd) {
+	m.clearMessages()
+	indices, label := m.groupTarget()
+	if len(indices) == 0 {
+		m.errorMsg = "Select a folder or filter by #tag to connect a group"
+		return m, nil
+	}
+
+	var profiles []config.Profile
+	for _, i := range indices {
+		p := m.config.Profiles[i]
+		if m.profileValid[i] && !m.isProfileConnected(p.Path) {
+			profiles = append(profiles, p)
+		}
+	}
+	if len(profiles) == 0 {
+		m.statusMsg = fmt.Sprintf("All profiles in %s are already connected", label)
+		return m, nil
+	}
+
+	m.statusMsg = fmt.Sprintf("Connecting %d profile(s) in %s...", len(profiles), label)
+	m.loading = true
+	m.loadingMsg = "Connecting..."
+	return m, tea.Batch(m.spinner.Tick, m.connectAll(profiles))
+}
+
+// disconnectGroup disconnects the sessions of every connected profile of the group
+func (m Model) disconnectGroup() (tea.Model, tea.Cmd) {
+	m.clearMessages()
+	indices, label := m.groupTarget()
+	if len(indices) == 0 {
+		m.errorMsg = "Select a folder or filter by #tag to disconnect a group"
+		return m, nil
+	}
+
+	var sessions []openvpn.Session
+	for _, i := range indices {
+		sessions = append(sessions, m.profileSessions(m.config.Profiles[i].Path)...)
+	}
+	if len(sessions) == 0 {
+		m.statusMsg = fmt.Sprintf("No profiles in %s are connected", label)
+		return m, nil
+	}
+
+	m.statusMsg = fmt.Sprintf("Disconnecting %d session(s) in %s...", len(sessions), label)
+	m.loading = true
+	m.loadingMsg = "Disconnecting..."
+	return m, tea.Batch(m.spinner.Tick, m.disconnectAll(sessions))
+}
+
+// profileSessions returns the active sessions started from a profile
+func (m Model) profileSessions(profilePath string) []openvpn.Session {
+	return sessionsFor(m.sessions, profilePath)
+}
+
+// sessionsFor returns the sessions started from the config file at profilePath
+func sessionsFor(sessions []openvpn.Session, profilePath string) []openvpn.Session {
+	// Sessions report the config file name without its extension
+	profileName := profilePath
+	if lastSlash := strings.LastIndex(profilePath, "/"); lastSlash != -1 {
+		profileName = profilePath[lastSlash+1:]
+	}
+	profileName = strings.TrimSuffix(profileName, ".ovpn")
+
+	var matches []openvpn.Session
+	for _, session := range sessions {
+		if session.ConfigName == profileName {
+			matches = append(matches, session)
+		}
+	}
+	return matches
+}
+
+// sessionProfile returns the profile a session was started from
+func (m Model) sessionProfile(session openvpn.Session) (config.Profile, bool) {
+	for _, p := range m.config.Profiles {
+		for _, s := range m.profileSessions(p.Path) {
+			if s.Path == session.Path {
+				return p, true
+			}
+		}
+	}
+	return config.Profile{}, false
+}
@@ -1,8 +1,13 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"openvpn3-tui/internal/config"
 	"openvpn3-tui/internal/ui"
@@ -17,11 +22,52 @@ func main() {
 		os.Exit(1)
 	}
 
+	fs := flag.NewFlagSet("openvpn3-tui", flag.ContinueOnError)
+	fs.Usage = printUsage
+	var connect []string
+	fs.Func("connect", "connect `profile` on start, repeatable or comma separated", func(s string) error {
+		for _, name := range strings.Split(s, ",") {
+			name = strings.TrimSpace(name)
+			if _, ok := cfg.FindProfile(name); !ok {
+				return fmt.Errorf("no such profile %q", name)
+			}
+			connect = append(connect, name)
+		}
+		return nil
+	})
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		os.Exit(2)
+	}
+
+	if fs.NArg() > 0 {
+		os.Exit(runCommand(cfg, fs.Arg(0), fs.Args()[1:]))
+	}
+
 	model := ui.NewModel(cfg)
-	p := tea.NewProgram(model, tea.WithAltScreen())
+	model.ConnectOnStart(connect)
+	// Signals go through the model so that the on_quit policy applies
+	p := tea.NewProgram(model, tea.WithAltScreen(), tea.WithoutSignalHandler())
+	go forwardSignals(p)
 
-	if _, err := p.Run(); err != nil {
+	final, err := p.Run()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error running program: %v\n", err)
 		os.Exit(1)
 	}
+	if m, ok := final.(ui.Model); ok && m.QuitError() != nil {
+		fmt.Fprintf(os.Stderr, "Error disconnecting on exit: %v\n", m.QuitError())
+		os.Exit(1)
+	}
+}
+
+// forwardSignals hands termination signals to the program
+func forwardSignals(p *tea.Program) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	for s := range sig {
+		p.Send(ui.SignalMsg{Signal: s})
+	}
 }